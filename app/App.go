@@ -22,11 +22,14 @@ type App struct {
 	sync.Mutex
 	Name       string
 	Router     *mux.Router
+	RootRouter *mux.Router
 	DB         *gorm.DB
 	Log        log.Logger
 	Server     *http.Server
 	WG         *sync.WaitGroup
 	Repository repository.Repository
+	Jobs       []Job
+	quit       chan struct{}
 }
 
 type Controller interface {
@@ -37,6 +40,11 @@ type ModuleConfig interface {
 	MigrateTables()
 }
 
+// Job is a background task that runs until quit is closed.
+type Job interface {
+	Run(quit <-chan struct{})
+}
+
 func NewApp(name string, db *gorm.DB, log log.Logger,
 	wg *sync.WaitGroup, repo repository.Repository) *App {
 	return &App{
@@ -45,6 +53,7 @@ func NewApp(name string, db *gorm.DB, log log.Logger,
 		Log:        log,
 		WG:         wg,
 		Repository: repo,
+		quit:       make(chan struct{}),
 	}
 }
 
@@ -70,14 +79,14 @@ func getConnectionString() string {
 	return conn
 }
 
-func NewDBConnection(log log.Logger) *gorm.DB {
+func NewDBConnection(logger log.Logger) *gorm.DB {
 	// const url = "root:12345@tcp(127.0.0.1:3306)/contact_app_db?charset=utf8&parseTime=True&loc=Local"
 
 	url := getConnectionString()
 
 	db, err := gorm.Open("mysql", url)
 	if err != nil {
-		log.Print(err.Error())
+		logger.Print(err.Error())
 		return nil
 	}
 
@@ -86,6 +95,7 @@ func NewDBConnection(log log.Logger) *gorm.DB {
 	sqlDB.SetMaxOpenConns(500)
 	sqlDB.SetConnMaxLifetime(3 * time.Minute)
 
+	db.SetLogger(log.SQLLogger())
 	db.LogMode(true)
 
 	return db
@@ -98,8 +108,8 @@ func (a *App) Init() {
 
 func (a *App) initializeRouter() {
 	a.Log.Print("Initializing " + a.Name + " Route")
-	a.Router = mux.NewRouter().StrictSlash(true)
-	a.Router = a.Router.PathPrefix("/api/v1/banking-app").Subrouter()
+	a.RootRouter = mux.NewRouter().StrictSlash(true)
+	a.Router = a.RootRouter.PathPrefix("/api/v1/banking-app").Subrouter()
 }
 
 func (a *App) initializeServer() {
@@ -112,6 +122,9 @@ func (a *App) initializeServer() {
 	methodsOk := handlers.AllowedMethods([]string{
 		http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions,
 	})
+	exposedOk := handlers.ExposedHeaders([]string{
+		"X-Request-ID", "X-Impersonated-By",
+	})
 	credentialsOk := handlers.AllowCredentials()
 	apiPort := a.getPort()
 	a.Server = &http.Server{
@@ -120,7 +133,7 @@ func (a *App) initializeServer() {
 		ReadTimeout:  time.Second * 60,
 		WriteTimeout: time.Second * 60,
 		IdleTimeout:  time.Second * 60,
-		Handler:      handlers.CORS(originsOk, methodsOk, headersOk, credentialsOk)(a.Router),
+		Handler:      handlers.CORS(originsOk, methodsOk, headersOk, exposedOk, credentialsOk)(a.RootRouter),
 	}
 	a.Log.Printf("Server Exposed On %s", apiPort)
 }
@@ -149,6 +162,27 @@ func (a *App) RegisterControllerRoutes(controllers []Controller) {
 
 }
 
+// RegisterMiddlewares runs middlewares ahead of every API route.
+func (a *App) RegisterMiddlewares(middlewares ...mux.MiddlewareFunc) {
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.Router.Use(middlewares...)
+}
+
+// RegisterRootControllerRoutes registers routes outside the API prefix, such as well-known URLs.
+func (a *App) RegisterRootControllerRoutes(controllers []Controller) {
+
+	a.Lock()
+	defer a.Unlock()
+
+	for _, controller := range controllers {
+		controller.RegisterRoutes(a.RootRouter.NewRoute().Subrouter())
+	}
+
+}
+
 func (a *App) MigrateModuleTables(moduleConfigs []ModuleConfig) {
 
 	a.Lock()
@@ -160,8 +194,30 @@ func (a *App) MigrateModuleTables(moduleConfigs []ModuleConfig) {
 
 }
 
+func (a *App) RegisterJobs(jobs []Job) {
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.Jobs = append(a.Jobs, jobs...)
+}
+
+// StartJobs starts every registered job. It should be called once the tables are migrated.
+func (a *App) StartJobs() {
+
+	a.Lock()
+	defer a.Unlock()
+
+	for _, job := range a.Jobs {
+		go job.Run(a.quit)
+	}
+	a.Log.Printf("Started %d background jobs", len(a.Jobs))
+}
+
 func (app *App) Stop() {
 
+	close(app.quit)
+
 	context, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
 	defer cancel()
 
@@ -0,0 +1,110 @@
+// Command jwtkeys manages the keys access tokens are signed with.
+//
+//	jwtkeys [-dir DIR] list
+//	jwtkeys [-dir DIR] generate [-alg RS256|EdDSA]
+//	jwtkeys [-dir DIR] rotate [-alg RS256|EdDSA]
+//	jwtkeys [-dir DIR] activate KID
+//	jwtkeys [-dir DIR] retire KID
+//	jwtkeys [-dir DIR] remove KID
+//
+// The directory and algorithm default to JWT_KEYS_DIR and JWT_ALGORITHM. A running server picks
+// up changes within JWT_KEY_RELOAD_SECONDS. Remove a retired key only once the tokens it signed
+// have expired.
+package main
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/security"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	config.InitializeGlobalConfig(config.Local)
+
+	flags := flag.NewFlagSet("jwtkeys", flag.ExitOnError)
+	dir := flags.String("dir", security.KeysDir(), "keys directory")
+	flags.Usage = usage
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := run(*dir, flags.Arg(0), flags.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "jwtkeys:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, command string, args []string) error {
+	switch command {
+	case "list":
+		return list(dir)
+	case "generate", "rotate":
+		commandFlags := flag.NewFlagSet(command, flag.ExitOnError)
+		algorithm := commandFlags.String("alg", security.KeyAlgorithm(), "RS256 or EdDSA")
+		commandFlags.Parse(args)
+
+		var kid string
+		var err error
+		if command == "generate" {
+			kid, err = security.GenerateKey(dir, *algorithm)
+		} else {
+			kid, err = security.RotateKeys(dir, *algorithm)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(kid)
+		return nil
+	case "activate", "retire", "remove":
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes one key id", command)
+		}
+		switch command {
+		case "activate":
+			return security.ActivateKey(dir, args[0])
+		case "retire":
+			return security.RetireKey(dir, args[0])
+		default:
+			return security.RemoveKey(dir, args[0])
+		}
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func list(dir string) error {
+	keySet, err := security.LoadKeySet(dir)
+	if err != nil {
+		return err
+	}
+	for _, kid := range keySet.IDs() {
+		key := keySet.Keys[kid]
+		state := "retired"
+		switch {
+		case key == keySet.Active:
+			state = "active"
+		case key.Private != nil:
+			state = "standby"
+		}
+		fmt.Printf("%s\t%s\t%s\n", kid, key.Method.Alg(), state)
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: jwtkeys [-dir DIR] COMMAND
+
+commands:
+  list                  list the keys and which one signs
+  generate [-alg ALG]   add a key without activating it
+  rotate [-alg ALG]     add a key, activate it and retire the active one
+  activate KID          sign new tokens with KID
+  retire KID            keep KID for verification only
+  remove KID            delete KID, refusing the tokens it signed`)
+}
@@ -0,0 +1,155 @@
+// Command piikeys manages the keys personal data is encrypted with.
+//
+//	piikeys [-file FILE] list
+//	piikeys [-file FILE] rotate
+//	piikeys [-file FILE] reencrypt [-batch N]
+//	piikeys [-file FILE] remove VERSION
+//
+// The keyfile defaults to PII_KEY_FILE. After a rotation, restart the servers so they encrypt
+// with the new key, then re-encrypt the stored values. Remove the old key only once no value is
+// encrypted with it; remove refuses otherwise.
+package main
+
+import (
+	"banking-app-be/app"
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+func main() {
+	config.InitializeGlobalConfig(config.Local)
+
+	flags := flag.NewFlagSet("piikeys", flag.ExitOnError)
+	file := flags.String("file", pii.KeyFile(), "keyfile")
+	flags.Usage = usage
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := run(*file, flags.Arg(0), flags.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "piikeys:", err)
+		os.Exit(1)
+	}
+}
+
+func run(file, command string, args []string) error {
+	switch command {
+	case "list":
+		return list(file)
+	case "rotate":
+		version, err := pii.RotateKey(file)
+		if err != nil {
+			return err
+		}
+		fmt.Println(version)
+		return nil
+	case "reencrypt":
+		commandFlags := flag.NewFlagSet(command, flag.ExitOnError)
+		batchSize := commandFlags.Int("batch", pii.DefaultBatchSize, "rows per batch")
+		commandFlags.Parse(args)
+		if *batchSize <= 0 {
+			return fmt.Errorf("batch must be positive")
+		}
+		return reencrypt(file, *batchSize)
+	case "remove":
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes one key version", command)
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("%q is not a key version", args[0])
+		}
+		return remove(file, version)
+	default:
+		usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func list(file string) error {
+	ring, err := pii.LoadKeyring(file)
+	if err != nil {
+		return err
+	}
+	for _, version := range ring.Versions() {
+		state := "decrypt only"
+		if version == ring.Active {
+			state = "active"
+		}
+		fmt.Printf("%d\t%s\n", version, state)
+	}
+	return nil
+}
+
+func reencrypt(file string, batchSize int) error {
+	db, err := open(file)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, column := range pii.Columns {
+		rewritten, err := pii.Reencrypt(db, column, batchSize)
+		fmt.Printf("%s.%s\t%d\n", column.Table, column.Name, rewritten)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func remove(file string, version int) error {
+	db, err := open(file)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, column := range pii.Columns {
+		count, err := pii.CountEncryptedWith(db, column, version)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%d values of %s.%s are still encrypted with key %d; run reencrypt first",
+				count, column.Table, column.Name, version)
+		}
+	}
+	return pii.RemoveKey(file, version)
+}
+
+// open loads the keyfile and connects to the database.
+func open(file string) (*gorm.DB, error) {
+	ring, err := pii.LoadKeyring(file)
+	if err != nil {
+		return nil, err
+	}
+	pii.UseKeyring(ring)
+
+	db := app.NewDBConnection(log.GetLogger())
+	if db == nil {
+		return nil, fmt.Errorf("db connection failed")
+	}
+	db.LogMode(false)
+	return db, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: piikeys [-file FILE] COMMAND
+
+commands:
+  list                  list the keys and which one encrypts
+  rotate                add a key and encrypt new values with it
+  reencrypt [-batch N]  encrypt every stored value with the active key
+  remove VERSION        delete a key no stored value is encrypted with`)
+}
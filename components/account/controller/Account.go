@@ -6,23 +6,30 @@ import (
 	"banking-app-be/components/security"
 	"banking-app-be/components/web"
 	"banking-app-be/model/account"
+	"banking-app-be/model/payment"
+	"banking-app-be/model/role"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 
 	accountService "banking-app-be/components/account/service"
+	paymentService "banking-app-be/components/payment/service"
 )
 
 type AccountController struct {
 	log            log.Logger
 	AccountService *accountService.AccountService
+	PaymentService *paymentService.PaymentService
 }
 
-func NewAccountController(accountService *accountService.AccountService, log log.Logger) *AccountController {
+func NewAccountController(accountService *accountService.AccountService, paymentService *paymentService.PaymentService, log log.Logger) *AccountController {
 	return &AccountController{
 		log:            log,
 		AccountService: accountService,
+		PaymentService: paymentService,
 	}
 }
 
@@ -31,31 +38,37 @@ func (Controller *AccountController) RegisterRoutes(router *mux.Router) {
 	// http://localhost:8001/api/v1/banking-app/
 	accountRouter := router.PathPrefix("/account").Subrouter()
 	guardedRouter := accountRouter.PathPrefix("/").Subrouter()
+	adminRouter := accountRouter.PathPrefix("/").Subrouter()
 
 	//Post
-	guardedRouter.HandleFunc("/bank/{bankId}", Controller.createAccount).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/bank/{bankId}", security.Authorize(Controller.createAccount, role.AccountManageOwn)).Methods(http.MethodPost)
 
 	//Get
-	guardedRouter.HandleFunc("/", Controller.getAllUserAccounts).Methods(http.MethodGet)
-	guardedRouter.HandleFunc("/{id}", Controller.getAccountByAccountID).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/", security.Authorize(Controller.getAllUserAccounts, role.AccountManageOwn)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/queued-transfer", security.Authorize(Controller.getQueuedTransfers, role.PaymentReadOwn)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeAccount(Controller.getAccountByAccountID, "id", role.AccountManageOwn, role.AccountReadAll)).Methods(http.MethodGet)
 
 	//Update
-	guardedRouter.HandleFunc("/{id}", Controller.updateAccountByAccountID).Methods(http.MethodPut)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeAccount(Controller.updateAccountByAccountID, "id", role.AccountManageOwn, "")).Methods(http.MethodPut)
 
 	//Delete
-	guardedRouter.HandleFunc("/{id}", Controller.deleteAccountByAccountID).Methods(http.MethodDelete)
-	guardedRouter.Use(security.MiddlewareUser)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeAccount(Controller.deleteAccountByAccountID, "id", role.AccountManageOwn, "")).Methods(http.MethodDelete)
 
 	//Withdraw
-	guardedRouter.HandleFunc("/{id}/withdraw", Controller.withdrawFromAccount).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/withdraw", security.AuthorizeAccount(Controller.withdrawFromAccount, "id", role.AccountTransact, "")).Methods(http.MethodPost)
 
 	//Deposite
-	guardedRouter.HandleFunc("/{id}/deposite", Controller.depositetToAccount).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/deposite", security.AuthorizeAccount(Controller.depositetToAccount, "id", role.AccountTransact, "")).Methods(http.MethodPost)
 
 	//Transfer
-	guardedRouter.HandleFunc("/{id}/transfer", Controller.transfer).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/transfer", security.AuthorizeAccount(Controller.transfer, "id", role.AccountTransact, "")).Methods(http.MethodPost)
 
-	guardedRouter.Use(security.MiddlewareUser)
+	guardedRouter.Use(security.MiddlewareActive)
+
+	//===========================
+
+	adminRouter.HandleFunc("/queued-transfer/release", security.Authorize(Controller.releaseQueuedTransfers, role.PaymentProcess)).Methods(http.MethodPost)
+	adminRouter.Use(security.MiddlewareActive)
 }
 
 func (controller *AccountController) createAccount(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +76,7 @@ func (controller *AccountController) createAccount(w http.ResponseWriter, r *htt
 	newAccount := account.Account{}
 	parser := web.NewParser(r)
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -79,7 +92,18 @@ func (controller *AccountController) createAccount(w http.ResponseWriter, r *htt
 	}
 	newAccount.BankID = bankID
 
-	err = controller.AccountService.CreateAccount(&newAccount)
+	// The home branch is optional in the body for banks that have no branches.
+	var requestData struct {
+		BranchCode string `json:"branchCode" example:"SBIN0000300"`
+	}
+	if r.ContentLength != 0 {
+		if err := web.UnmarshalJSON(r, &requestData); err != nil {
+			web.RespondError(w, errors.NewHTTPError("Unable to parse requested data", http.StatusBadRequest))
+			return
+		}
+	}
+
+	err = controller.AccountService.CreateAccount(r.Context(), &newAccount, requestData.BranchCode)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -107,7 +131,7 @@ func (controller *AccountController) getAllUserAccounts(w http.ResponseWriter, r
 		offset = 0
 	}
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
 		return
@@ -119,6 +143,11 @@ func (controller *AccountController) getAllUserAccounts(w http.ResponseWriter, r
 		return
 	}
 
+	if web.MaskAccountNumbers(r) {
+		for i := range allAccounts {
+			allAccounts[i].MaskAccountNumbers()
+		}
+	}
 	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allAccounts)
 }
 
@@ -128,9 +157,10 @@ func (controller *AccountController) getAccountByAccountID(w http.ResponseWriter
 
 	parser := web.NewParser(r)
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	// Staff may read accounts they do not hold, so the account is looked up under its holder.
+	holderID, err := security.CurrentAccountHolder(r)
 	if err != nil {
-		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		web.RespondError(w, err)
 		return
 	}
 
@@ -140,7 +170,7 @@ func (controller *AccountController) getAccountByAccountID(w http.ResponseWriter
 		return
 	}
 
-	accountToGet.UserID = userID
+	accountToGet.UserID = holderID
 	accountToGet.ID = accountIDFromURL
 
 	err = controller.AccountService.GetAccountByAccountID(&accountToGet)
@@ -157,9 +187,14 @@ func (controller *AccountController) updateAccountByAccountID(w http.ResponseWri
 	accountToUpdate := account.Account{}
 	parser := web.NewParser(r)
 
-	var err error
+	err := web.UnmarshalJSON(r, &accountToUpdate)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
 
-	accountToUpdate.UpdatedBy, err = security.ExtractUserIDFromToken(r)
+	// The account and its holder come from the verified route, never from the body.
+	accountToUpdate.UpdatedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
 		return
@@ -172,13 +207,7 @@ func (controller *AccountController) updateAccountByAccountID(w http.ResponseWri
 	}
 	accountToUpdate.UserID = accountToUpdate.UpdatedBy
 
-	err = web.UnmarshalJSON(r, &accountToUpdate)
-	if err != nil {
-		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
-		return
-	}
-
-	err = controller.AccountService.UpdateAccountById(&accountToUpdate)
+	err = controller.AccountService.UpdateAccountById(r.Context(), &accountToUpdate)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -193,7 +222,7 @@ func (controller *AccountController) deleteAccountByAccountID(w http.ResponseWri
 	accountToDelete := account.Account{}
 	parser := web.NewParser(r)
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
 		return
@@ -209,7 +238,7 @@ func (controller *AccountController) deleteAccountByAccountID(w http.ResponseWri
 	accountToDelete.ID = accountIDFromURL
 	accountToDelete.DeletedBy = userID
 
-	err = controller.AccountService.DeleteAccountById(&accountToDelete)
+	err = controller.AccountService.DeleteAccountById(r.Context(), &accountToDelete)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -227,6 +256,7 @@ func (controller *AccountController) withdrawFromAccount(w http.ResponseWriter,
 	var requestData struct {
 		// AccountNo string  `json:"accountNo"`
 		Amount float32 `json:"amount"`
+		security.StepUp
 	}
 
 	err := web.UnmarshalJSON(r, &requestData)
@@ -244,7 +274,7 @@ func (controller *AccountController) withdrawFromAccount(w http.ResponseWriter,
 
 	// accountToUpdate.AccountNo = requestData.AccountNo
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
@@ -253,7 +283,7 @@ func (controller *AccountController) withdrawFromAccount(w http.ResponseWriter,
 	accountToUpdate.UserID = userID
 	accountToUpdate.UpdatedBy = userID
 
-	err = controller.AccountService.Withdraw(accountToUpdate, requestData.Amount)
+	err = controller.AccountService.Withdraw(r.Context(), accountToUpdate, requestData.Amount, security.StepUpFor(r, requestData.StepUp))
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -289,7 +319,7 @@ func (controller *AccountController) depositetToAccount(w http.ResponseWriter, r
 
 	// accountToUpdate.AccountNo = requestData.AccountNo
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
@@ -298,7 +328,7 @@ func (controller *AccountController) depositetToAccount(w http.ResponseWriter, r
 	accountToUpdate.UserID = userID
 	accountToUpdate.UpdatedBy = userID
 
-	err = controller.AccountService.Deposite(accountToUpdate, requestData.Amount)
+	err = controller.AccountService.Deposite(r.Context(), accountToUpdate, requestData.Amount)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -311,14 +341,14 @@ func (controller *AccountController) depositetToAccount(w http.ResponseWriter, r
 
 func (controller *AccountController) transfer(w http.ResponseWriter, r *http.Request) {
 
-	fromAccount := account.Account{}
-	toAccount := account.Account{}
 	parser := web.NewParser(r)
 
 	var requestData struct {
-		// FromAccountNo string  `json:"fromAccountNo"`
-		ToAccountNo string  `json:"toAccountNo"`
-		Amount      float32 `json:"amount"`
+		ToAccountNo  string  `json:"toAccountNo"`
+		ToBranchCode string  `json:"toBranchCode" example:"SBIN0000300"`
+		Amount       float32 `json:"amount"`
+		Rail         string  `json:"rail" example:"INSTANT/BATCH/HIGH_VALUE"`
+		security.StepUp
 	}
 
 	err := web.UnmarshalJSON(r, &requestData)
@@ -333,30 +363,105 @@ func (controller *AccountController) transfer(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// fromAccount.AccountNo = requestData.FromAccountNo
-	// fmt.Println("from account number =======================>", fromAccount.AccountNo)
-	fromAccount.ID = accountIDFromURL
-	toAccount.AccountNo = requestData.ToAccountNo
-
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
 		return
 	}
 
-	fromAccount.UserID = userID
-	fromAccount.UpdatedBy = userID
-	toAccount.UpdatedBy = userID
+	newPayment := payment.Payment{
+		FromAccountID: accountIDFromURL,
+		ToAccountNo:   requestData.ToAccountNo,
+		ToBranchCode:  strings.ToUpper(strings.TrimSpace(requestData.ToBranchCode)),
+		UserID:        userID,
+		Amount:        requestData.Amount,
+		Rail:          strings.ToUpper(requestData.Rail),
+	}
 
-	err = controller.AccountService.Transfer(fromAccount, toAccount, requestData.Amount)
+	err = controller.PaymentService.Initiate(r.Context(), &newPayment, security.StepUpFor(r, requestData.StepUp))
 	if err != nil {
 		web.RespondError(w, err)
 		return
 	}
 
-	web.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Money Transferred successfully",
-	})
+	switch newPayment.Status {
+	case payment.StatusCompleted:
+		web.RespondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Money Transferred successfully",
+			"payment": newPayment,
+		})
+	case payment.StatusQueued:
+		web.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message": "Transfer queued until the inter-bank exposure limit allows it",
+			"payment": newPayment,
+		})
+	case payment.StatusFailed:
+		web.RespondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"message": "Transfer failed: " + newPayment.FailureReason,
+			"payment": newPayment,
+		})
+	default:
+		web.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+			"message": "Transfer accepted and will be processed on the " + newPayment.Rail + " rail",
+			"payment": newPayment,
+		})
+	}
+}
+
+// getQueuedTransfers lists the caller's transfers held back by exposure limits, which are
+// their payments in QUEUED status.
+func (controller *AccountController) getQueuedTransfers(w http.ResponseWriter, r *http.Request) {
+
+	queuedTransfers := []payment.Payment{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		return
+	}
+
+	err = controller.PaymentService.GetPayments(userID, security.CurrentBankScope(r), payment.StatusQueued, &queuedTransfers, &totalCount, limit, offset)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if web.MaskAccountNumbers(r) {
+		for i := range queuedTransfers {
+			queuedTransfers[i].MaskAccountNumbers()
+		}
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, queuedTransfers)
+}
+
+func (controller *AccountController) releaseQueuedTransfers(w http.ResponseWriter, r *http.Request) {
+
+	// Queued transfers of every bank are released together.
+	if err := security.CurrentBankScope(r).CheckGlobal(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	processed, err := controller.PaymentService.ReleaseQueued(r.Context(), time.Now())
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
 
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(processed), processed)
 }
@@ -1,13 +1,18 @@
 package service
 
 import (
+	bankService "banking-app-be/components/bank/service"
 	"banking-app-be/components/errors"
+	exposureService "banking-app-be/components/exposure/service"
+	"banking-app-be/components/security"
 	"banking-app-be/model/account"
 	"banking-app-be/model/bank"
 	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/branch"
 	"banking-app-be/model/passbook"
 	"banking-app-be/model/user"
 	"banking-app-be/module/repository"
+	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -19,19 +24,26 @@ import (
 )
 
 type AccountService struct {
-	db         *gorm.DB
-	repository repository.Repository
+	db              *gorm.DB
+	repository      repository.Repository
+	reserveService  *bankService.ReserveService
+	exposureService *exposureService.ExposureService
 }
 
-func NewAccountService(DB *gorm.DB, repo repository.Repository) *AccountService {
+func NewAccountService(DB *gorm.DB, repo repository.Repository, reserveService *bankService.ReserveService,
+	exposureService *exposureService.ExposureService) *AccountService {
 	return &AccountService{
-		db:         DB,
-		repository: repo,
+		db:              DB,
+		repository:      repo,
+		reserveService:  reserveService,
+		exposureService: exposureService,
 	}
 }
 
-func (service *AccountService) CreateAccount(newAccount *account.Account) error {
-	uow := repository.NewUnitOfWork(service.db, false)
+// CreateAccount opens an account at the branch identified by homeBranchCode. The code may only be
+// left empty for banks that have no branches yet.
+func (service *AccountService) CreateAccount(ctx context.Context, newAccount *account.Account, homeBranchCode string) error {
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	accountOwner := user.User{}
@@ -52,6 +64,10 @@ func (service *AccountService) CreateAccount(newAccount *account.Account) error
 		return errors.NewInActiveUserError("Can not create a account in InActive bank")
 	}
 
+	if err := service.assignHomeBranch(uow, newAccount, homeBranchCode); err != nil {
+		return err
+	}
+
 	accountNo, err := service.generateUniqueAccountNumber()
 	if err != nil {
 		return err
@@ -91,8 +107,6 @@ func (service *AccountService) CreateAccount(newAccount *account.Account) error
 		return errors.NewDatabaseError("Failed to update Owners total balance")
 	}
 
-	fmt.Println(accountOwner)
-
 	uow.Commit()
 	return nil
 }
@@ -121,31 +135,37 @@ func (service *AccountService) GetAllAccountsByUserID(userID uuid.UUID, allAccou
 	return nil
 }
 
+// GetAccountByAccountID fetches an account of the user accountToGet.UserID names.
 func (service *AccountService) GetAccountByAccountID(accountToGet *account.AccountDTO) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	if err := service.repository.GetRecordByID(uow, accountToGet.ID, accountToGet); err != nil {
-		return errors.NewNotFoundError("Account not found with given Id")
+	if err := service.repository.GetRecord(uow, accountToGet,
+		repository.Filter("id = ? AND user_id = ?", accountToGet.ID, accountToGet.UserID)); err != nil {
+		return errors.NewHTTPError("Account not found with given Id", http.StatusNotFound)
 	}
 
-	// tempAccount := account.Account{}
-	// if err := service.repository.GetRecord(uow, tempAccount, repository.Filter("id = ? AND user_id = ?", accountToGet.ID, accountToGet.UserID)); err != nil {
-	// 	return errors.NewNotFoundError("does not match accountId and UserId")
-	// }
-
-	// if tempAccount.UserID != accountToGet.UserID {
-	// 	return errors.NewUnauthorizedError("AccountId is not related to Current User")
-	// }
-
 	uow.Commit()
 	return nil
 }
 
-func (service *AccountService) UpdateAccountById(accountToUpdate *account.Account) error {
+// AccountOwner implements security.AccountOwnerResolver.
+func (service *AccountService) AccountOwner(accountID uuid.UUID) (uuid.UUID, uuid.UUID, error) {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	ownedAccount := account.Account{}
+	if err := service.repository.GetRecordByID(uow, accountID, &ownedAccount); err != nil {
+		return uuid.Nil, uuid.Nil, errors.NewHTTPError("Account not found with given Id", http.StatusNotFound)
+	}
+	return ownedAccount.UserID, ownedAccount.BankID, nil
+}
+
+func (service *AccountService) UpdateAccountById(ctx context.Context, accountToUpdate *account.Account) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	// if err := service.repository.GetRecordByID(uow, accountToUpdate.ID, &accountToUpdate); err != nil {
@@ -180,9 +200,9 @@ func (service *AccountService) UpdateAccountById(accountToUpdate *account.Accoun
 	return nil
 }
 
-func (service *AccountService) DeleteAccountById(accountToDelete *account.Account) error {
+func (service *AccountService) DeleteAccountById(ctx context.Context, accountToDelete *account.Account) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	if err := service.repository.GetRecordByID(uow, accountToDelete.ID, accountToDelete); err != nil {
@@ -211,14 +231,19 @@ func (service *AccountService) DeleteAccountById(accountToDelete *account.Accoun
 	return nil
 }
 
-func (service *AccountService) Withdraw(accountToUpdate account.Account, amount float32) error {
+// Withdraw takes amount out of the account. Amounts above the step-up threshold also need the
+// holder's transaction PIN or a fresh MFA code in proof.
+func (service *AccountService) Withdraw(ctx context.Context, accountToUpdate account.Account, amount float32, proof security.StepUp) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	if amount <= 0 {
 		return errors.NewValidationError("Withdraw amount must be positive")
 	}
+	if err := security.RequireStepUp(ctx, accountToUpdate.UserID, amount, proof); err != nil {
+		return err
+	}
 
 	accountOwner := user.User{}
 	if err := service.repository.GetRecordByID(uow, accountToUpdate.UserID, &accountOwner); err != nil {
@@ -233,8 +258,8 @@ func (service *AccountService) Withdraw(accountToUpdate account.Account, amount
 	// 	return errors.NewHTTPError("Account not found with given Account Number for Current User ", http.StatusNotFound)
 	// }
 
-	if err := service.repository.GetRecordByID(uow, accountToUpdate.ID, &accountToUpdate); err != nil {
-		return errors.NewDatabaseError("Unable to find account with provided Id")
+	if err := service.repository.GetRecord(uow, &accountToUpdate, repository.Filter("id = ? AND user_id = ?", accountToUpdate.ID, accountToUpdate.UserID)); err != nil {
+		return errors.NewHTTPError("Account not found with given Id for Current User", http.StatusNotFound)
 	}
 
 	bank := bank.Bank{}
@@ -290,9 +315,9 @@ func (service *AccountService) Withdraw(accountToUpdate account.Account, amount
 	return nil
 }
 
-func (service *AccountService) Deposite(accountToUpdate account.Account, amount float32) error {
+func (service *AccountService) Deposite(ctx context.Context, accountToUpdate account.Account, amount float32) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	if amount <= 0 {
@@ -312,8 +337,8 @@ func (service *AccountService) Deposite(accountToUpdate account.Account, amount
 	// 	return errors.NewHTTPError("Account not found with given Account Number for Current User ", http.StatusNotFound)
 	// }
 
-	if err := service.repository.GetRecordByID(uow, accountToUpdate.ID, &accountToUpdate); err != nil {
-		return errors.NewDatabaseError("Unable to find account with provided Id")
+	if err := service.repository.GetRecord(uow, &accountToUpdate, repository.Filter("id = ? AND user_id = ?", accountToUpdate.ID, accountToUpdate.UserID)); err != nil {
+		return errors.NewHTTPError("Account not found with given Id for Current User", http.StatusNotFound)
 	}
 
 	bank := bank.Bank{}
@@ -363,47 +388,88 @@ func (service *AccountService) Deposite(accountToUpdate account.Account, amount
 	return nil
 }
 
-func (service *AccountService) Transfer(fromAccount, toAccount account.Account, amount float32) error {
-
-	uow := repository.NewUnitOfWork(service.db, false)
-	defer uow.RollBack()
+// Transfer moves money between accounts within uow, which the caller commits together with the
+// payment the transfer is for. The receiver is found by account number, within the branch
+// toBranchCode names when it is given. It returns true without moving anything when an
+// inter-bank exposure limit configured to queue holds the transfer back.
+func (service *AccountService) Transfer(uow *repository.UnitOfWork, fromAccount, toAccount account.Account, toBranchCode string, amount float32) (bool, error) {
 
 	if amount <= 0 {
-		return errors.NewValidationError("deposite amount must be positive")
+		return false, errors.NewValidationError("deposite amount must be positive")
 	}
 
 	//-------------------------sender user check
 	senderAccountOwner := user.User{}
 	if err := service.repository.GetRecordByID(uow, fromAccount.UserID, &senderAccountOwner); err != nil {
-		return errors.NewNotFoundError("Account owner not found")
+		return false, errors.NewNotFoundError("Account owner not found")
 	}
 	if !*senderAccountOwner.IsActive {
-		return errors.NewInActiveUserError("InActive user can not transfer money")
+		return false, errors.NewInActiveUserError("InActive user can not transfer money")
 	}
-	// if err := service.repository.GetRecord(uow, &fromAccount, repository.Filter("account_no = ? AND user_id = ?", fromAccount.AccountNo, fromAccount.UserID)); err != nil {
-	// 	return errors.NewHTTPError("user can only transfer money from its own acount", http.StatusNotFound)
-	// }
 
 	//-------------------------sender account check
-	if err := service.repository.GetRecordByID(uow, fromAccount.ID, &fromAccount); err != nil {
-		return errors.NewDatabaseError("Unable to find account with provided id")
+	if err := service.repository.GetRecord(uow, &fromAccount, repository.Filter("id = ? AND user_id = ?", fromAccount.ID, fromAccount.UserID)); err != nil {
+		return false, errors.NewHTTPError("Account not found with given Id for Current User", http.StatusNotFound)
 	}
 
 	if !*fromAccount.IsActive {
-		return errors.NewValidationError("Money can only be sent from active bank account")
+		return false, errors.NewValidationError("Money can only be sent from active bank account")
 	}
 
 	if fromAccount.AccountBalance < float32(amount) {
-		return errors.NewValidationError("Insufficient balance")
+		return false, errors.NewValidationError("Insufficient balance")
 	}
 
 	//-------------------------sender bank check
 	senderBank := bank.Bank{}
 	if err := service.repository.GetRecordByID(uow, fromAccount.BankID, &senderBank); err != nil {
-		return errors.NewNotFoundError("sender bank not found")
+		return false, errors.NewNotFoundError("sender bank not found")
 	}
 	if !*senderBank.IsActive {
-		return errors.NewInActiveUserError("Can not Transfer money from InActive Bank")
+		return false, errors.NewInActiveUserError("Can not Transfer money from InActive Bank")
+	}
+
+	//-------------------------receiver account check
+	receiverFilter := repository.Filter("account_no = ?", toAccount.AccountNo)
+	if toBranchCode != "" {
+		receiverBranch := branch.Branch{}
+		if err := bankService.FindBranchByCode(uow, service.repository, toBranchCode, &receiverBranch); err != nil {
+			return false, err
+		}
+		receiverFilter = repository.Filter("account_no = ? AND branch_id = ?", toAccount.AccountNo, receiverBranch.ID)
+	}
+	if err := service.repository.GetRecord(uow, &toAccount, receiverFilter); err != nil {
+		return false, errors.NewNotFoundError("receiver account not found with given accoutn number")
+	}
+	if !*toAccount.IsActive {
+		return false, errors.NewValidationError("Money can only be sent to active bank account")
+	}
+
+	//-------------------------receiver user check
+	receiverAccountOwner := user.User{}
+	if err := service.repository.GetRecordByID(uow, toAccount.UserID, &receiverAccountOwner); err != nil {
+		return false, errors.NewNotFoundError("receiver account owner not found.")
+	}
+	if !*receiverAccountOwner.IsActive {
+		return false, errors.NewValidationError("Money could not be sent to InActive user")
+	}
+
+	//-------------------------receiver bank check
+	receiverBank := bank.Bank{}
+	if err := service.repository.GetRecordByID(uow, toAccount.BankID, &receiverBank); err != nil {
+		return false, errors.NewNotFoundError("receiver bank not found")
+	}
+	if !*receiverBank.IsActive {
+		return false, errors.NewInActiveUserError("Can not Transfer money to InActive Bank")
+	}
+
+	//-------------------------exposure check
+	queue, err := service.exposureService.CheckTransfer(uow, fromAccount.BankID, toAccount.BankID, amount)
+	if err != nil {
+		return false, err
+	}
+	if queue {
+		return true, nil
 	}
 
 	//------------------------update sender account balance
@@ -414,7 +480,7 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		"updated_at":      time.Now(),
 	}
 	if err := service.repository.UpdateWithMap(uow, &account.Account{}, senderAccountData, repository.Filter("account_no = ? AND user_id = ?", fromAccount.AccountNo, fromAccount.UserID)); err != nil {
-		return errors.NewDatabaseError("failed to update sender account balance")
+		return false, errors.NewDatabaseError("failed to update sender account balance")
 	}
 
 	//-----------------------sender passbook entry
@@ -427,7 +493,7 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		AccountID:      fromAccount.ID,
 	}
 	if err := service.repository.Add(uow, senderTransaction); err != nil {
-		return errors.NewDatabaseError("Failed to record sender transaction")
+		return false, errors.NewDatabaseError("Failed to record sender transaction")
 	}
 
 	//------------------------update sender total balance
@@ -438,33 +504,7 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		"updated_at":    time.Now(),
 	}
 	if err := service.repository.UpdateWithMap(uow, &user.User{}, senderAccountOwnerData, repository.Filter("id = ?", fromAccount.UserID)); err != nil {
-		return errors.NewDatabaseError("failed to update total balance of sender user")
-	}
-
-	//-------------------------receiver account check
-	if err := service.repository.GetRecord(uow, &toAccount, repository.Filter("account_no = ?", toAccount.AccountNo)); err != nil {
-		return errors.NewNotFoundError("receiver account not found with given accoutn number")
-	}
-	if !*toAccount.IsActive {
-		return errors.NewValidationError("Money can only be sent to active bank account")
-	}
-
-	//-------------------------receiver user check
-	receiverAccountOwner := user.User{}
-	if err := service.repository.GetRecordByID(uow, toAccount.UserID, &receiverAccountOwner); err != nil {
-		return errors.NewNotFoundError("receiver account owner not found.")
-	}
-	if !*receiverAccountOwner.IsActive {
-		return errors.NewValidationError("Money could not be sent to InActive user")
-	}
-
-	//-------------------------receiver bank check
-	receiverBank := bank.Bank{}
-	if err := service.repository.GetRecordByID(uow, toAccount.BankID, &receiverBank); err != nil {
-		return errors.NewNotFoundError("receiver bank not found")
-	}
-	if !*receiverBank.IsActive {
-		return errors.NewInActiveUserError("Can not Transfer money to InActive Bank")
+		return false, errors.NewDatabaseError("failed to update total balance of sender user")
 	}
 
 	//-------------------------update receiver account balance
@@ -475,7 +515,7 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		"updated_at":      time.Now(),
 	}
 	if err := service.repository.UpdateWithMap(uow, &account.Account{}, receiverAccountData, repository.Filter("account_no = ? AND user_id = ?", toAccount.AccountNo, toAccount.UserID)); err != nil {
-		return errors.NewDatabaseError("failed to update receiver account balance")
+		return false, errors.NewDatabaseError("failed to update receiver account balance")
 	}
 
 	//------------------------receiver passbook entry
@@ -488,10 +528,14 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		AccountID:      toAccount.ID,
 	}
 	if err := service.repository.Add(uow, receiverTransaction); err != nil {
-		return errors.NewDatabaseError("Failed to record receiver transaction")
+		return false, errors.NewDatabaseError("Failed to record receiver transaction")
 	}
 
 	//-----------------------update reciver total balance
+	// The receiver may also be the sender, so the owner is re-read after the debit above.
+	if err := service.repository.GetRecordByID(uow, toAccount.UserID, &receiverAccountOwner); err != nil {
+		return false, errors.NewNotFoundError("receiver account owner not found.")
+	}
 	receiverAccountOwner.TotalBalance += amount
 	receiverAccountOwnerData := map[string]interface{}{
 		"total_balance": receiverAccountOwner.TotalBalance,
@@ -499,7 +543,7 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 		"updated_at":    time.Now(),
 	}
 	if err := service.repository.UpdateWithMap(uow, &user.User{}, receiverAccountOwnerData, repository.Filter("id = ?", toAccount.UserID)); err != nil {
-		return errors.NewDatabaseError("failed to update total balance of receiver user")
+		return false, errors.NewDatabaseError("failed to update total balance of receiver user")
 	}
 
 	//-------------------------ledger check
@@ -509,17 +553,50 @@ func (service *AccountService) Transfer(fromAccount, toAccount account.Account,
 			ReceiverBankID: toAccount.BankID,
 			Amount:         amount,
 		}
+		bankTransfer.CreatedBy = fromAccount.UpdatedBy
 		if err := service.repository.Add(uow, &bankTransfer); err != nil {
-			return errors.NewDatabaseError("Failed to record bank transaction")
+			return false, errors.NewDatabaseError("Failed to record bank transaction")
+		}
+
+		if err := service.reserveService.PostInterBankTransfer(uow, &bankTransfer); err != nil {
+			return false, err
 		}
 	}
 
-	uow.Commit()
-	return nil
+	return false, nil
 }
 
 //===================================================================================================================
 
+// assignHomeBranch resolves the home branch code and checks it belongs to the account's bank.
+func (service *AccountService) assignHomeBranch(uow *repository.UnitOfWork, newAccount *account.Account, homeBranchCode string) error {
+
+	if homeBranchCode == "" {
+		branchCount := 0
+		if err := service.repository.GetCount(uow, &branch.Branch{}, &branchCount, repository.Filter("bank_id = ?", newAccount.BankID)); err != nil {
+			return errors.NewDatabaseError("Unable to check bank branches")
+		}
+		if branchCount > 0 {
+			return errors.NewValidationError("Home branch code must be specified for this bank")
+		}
+		return nil
+	}
+
+	homeBranch := branch.Branch{}
+	if err := bankService.FindBranchByCode(uow, service.repository, homeBranchCode, &homeBranch); err != nil {
+		return err
+	}
+	if homeBranch.BankID != newAccount.BankID {
+		return errors.NewValidationError("Home branch does not belong to the selected bank")
+	}
+	if homeBranch.IsActive != nil && !*homeBranch.IsActive {
+		return errors.NewValidationError("Can not open an account at an InActive branch")
+	}
+
+	newAccount.BranchID = &homeBranch.ID
+	return nil
+}
+
 func (service *AccountService) generateUniqueAccountNumber() (string, error) {
 	const maxAttempts = 5
 	const accountLength = 12
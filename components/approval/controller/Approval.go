@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/approval"
+	"banking-app-be/model/role"
+	"context"
+	"net/http"
+	"strconv"
+
+	approvalService "banking-app-be/components/approval/service"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+type ApprovalController struct {
+	log             log.Logger
+	ApprovalService *approvalService.ApprovalService
+}
+
+func NewApprovalController(approvalService *approvalService.ApprovalService, log log.Logger) *ApprovalController {
+	return &ApprovalController{
+		log:             log,
+		ApprovalService: approvalService,
+	}
+}
+
+func (Controller *ApprovalController) RegisterRoutes(router *mux.Router) {
+
+	approvalRouter := router.PathPrefix("/approval").Subrouter()
+	guardedRouter := approvalRouter.PathPrefix("/").Subrouter()
+
+	//Get
+	guardedRouter.HandleFunc("/", security.AuthorizeAny(Controller.getRequests, role.ApprovalRead, role.ApprovalDecide)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeAny(Controller.getRequestById, role.ApprovalRead, role.ApprovalDecide)).Methods(http.MethodGet)
+	//Decisions
+	guardedRouter.HandleFunc("/{id}/approve", security.Authorize(Controller.approveRequest, role.ApprovalDecide)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/reject", security.Authorize(Controller.rejectRequest, role.ApprovalDecide)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/cancel", security.AuthorizeAny(Controller.cancelRequest, role.ApprovalRead, role.ApprovalDecide)).Methods(http.MethodPost)
+	guardedRouter.Use(security.MiddlewareActive)
+}
+
+func (controller *ApprovalController) getRequests(w http.ResponseWriter, r *http.Request) {
+	requests := []approval.Request{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	err = controller.ApprovalService.GetRequests(security.CurrentBankScope(r), query.Get("status"), &requests, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, requests)
+}
+
+func (controller *ApprovalController) getRequestById(w http.ResponseWriter, r *http.Request) {
+	request := approval.Request{}
+
+	requestID, err := web.NewParser(r).GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid approval request ID format"))
+		return
+	}
+
+	if err := controller.ApprovalService.GetRequest(requestID, security.CurrentBankScope(r), &request); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, request)
+}
+
+func (controller *ApprovalController) approveRequest(w http.ResponseWriter, r *http.Request) {
+	controller.decide(w, r, controller.ApprovalService.Approve)
+}
+
+func (controller *ApprovalController) rejectRequest(w http.ResponseWriter, r *http.Request) {
+	controller.decide(w, r, controller.ApprovalService.Reject)
+}
+
+func (controller *ApprovalController) decide(w http.ResponseWriter, r *http.Request,
+	decide func(ctx context.Context, requestID uuid.UUID, approver *security.Principal, decision *approval.Decision, request *approval.Request) error) {
+
+	request := approval.Request{}
+	decision := approval.Decision{}
+
+	if r.ContentLength != 0 {
+		if err := web.UnmarshalJSON(r, &decision); err != nil {
+			web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+			return
+		}
+	}
+
+	requestID, err := web.NewParser(r).GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid approval request ID format"))
+		return
+	}
+
+	approver, err := security.CurrentPrincipal(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := decide(r.Context(), requestID, approver, &decision, &request); err != nil {
+		controller.log.Error("Approval request " + requestID.String() + ": " + err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, request)
+}
+
+func (controller *ApprovalController) cancelRequest(w http.ResponseWriter, r *http.Request) {
+	request := approval.Request{}
+
+	requestID, err := web.NewParser(r).GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid approval request ID format"))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ApprovalService.Cancel(r.Context(), requestID, userID, &request); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, request)
+}
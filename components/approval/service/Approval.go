@@ -0,0 +1,326 @@
+package service
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/approval"
+	"banking-app-be/module/repository"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Executor carries out an approved request on behalf of its proposer. ctx is the context of the
+// approval.
+type Executor func(ctx context.Context, request *approval.Request) error
+
+type action struct {
+	permission string
+	execute    Executor
+}
+
+// ApprovalService holds sensitive admin actions until a second admin approves them. Modules
+// register the actions they offer; APPROVAL_REQUIRED_ACTIONS decides which ones are held.
+type ApprovalService struct {
+	db         *gorm.DB
+	repository repository.Repository
+	actions    map[string]action
+}
+
+func NewApprovalService(DB *gorm.DB, repo repository.Repository) *ApprovalService {
+	return &ApprovalService{
+		db:         DB,
+		repository: repo,
+		actions:    map[string]action{},
+	}
+}
+
+// RegisterAction makes name available for approval. Approvers need permission as well as the
+// right to decide approvals, the same permission the proposer needed to ask for it.
+func (service *ApprovalService) RegisterAction(name, permission string, execute Executor) {
+	service.actions[name] = action{permission: permission, execute: execute}
+}
+
+// IsRequired reports whether name has to be approved before it runs. Every registered action
+// is held unless APPROVAL_REQUIRED_ACTIONS lists the ones that are, or is "none".
+func (service *ApprovalService) IsRequired(name string) bool {
+	if _, registered := service.actions[name]; !registered {
+		return false
+	}
+	required := config.ApprovalRequiredActions.GetStringValueOrDefault("")
+	if strings.TrimSpace(required) == "" {
+		return true
+	}
+	for _, configured := range strings.Split(required, ",") {
+		if strings.TrimSpace(configured) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Propose files request when its action has to be approved. It reports false, filing nothing,
+// when the action may run straight away. The payload is whatever the executor needs to run it.
+func (service *ApprovalService) Propose(ctx context.Context, request *approval.Request, payload interface{}) (bool, error) {
+
+	if !service.IsRequired(request.Action) {
+		return false, nil
+	}
+
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return false, errors.NewValidationError("Unable to record the details of the request")
+		}
+		request.Payload = encoded
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	now := time.Now()
+	pending := approval.Request{}
+	err := service.repository.GetRecord(uow, &pending,
+		repository.Filter("action = ? AND target_id = ? AND status = ? AND expires_at > ?", request.Action, request.TargetID, approval.StatusPending, now))
+	if err == nil {
+		return false, errors.NewValidationError("An approval request for this action is already pending: " + pending.ID.String())
+	}
+
+	request.Status = approval.StatusPending
+	request.ProposedAt = now
+	request.ExpiresAt = now.Add(time.Duration(config.ApprovalTTLHours.GetInt64ValueOrDefault(72)) * time.Hour)
+	request.CreatedBy = request.ProposedBy
+
+	if err := service.repository.Add(uow, request); err != nil {
+		return false, errors.NewDatabaseError("Failed to file the approval request")
+	}
+
+	uow.Commit()
+	return true, nil
+}
+
+// GetRequests lists approval requests, newest first. Bank-scoped admins only see the requests
+// of their banks.
+func (service *ApprovalService) GetRequests(scope security.BankScope, status string, requests *[]approval.Request, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{scope.Filter("bank_id")}
+	if status != "" {
+		filters = append(filters, repository.Filter("status = ?", strings.ToUpper(status)))
+	}
+
+	err := service.repository.GetAll(uow, requests,
+		append(filters, repository.OrderBy("proposed_at DESC"), repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, &approval.Request{}, totalCount, filters...)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *ApprovalService) GetRequest(requestID uuid.UUID, scope security.BankScope, request *approval.Request) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.requestOf(uow, requestID, scope, request); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// Approve runs a pending request on behalf of its proposer. The approver must be someone else
+// holding the permission the action needs. The request is claimed before it runs, so two
+// approvers can not both execute it; its outcome is recorded whether it succeeds or fails.
+func (service *ApprovalService) Approve(ctx context.Context, requestID uuid.UUID, approver *security.Principal, decision *approval.Decision, request *approval.Request) error {
+
+	if err := decision.Validate(); err != nil {
+		return err
+	}
+
+	registered, err := service.decide(ctx, requestID, approver, approval.StatusApproved, decision.Comment, request)
+	if err != nil {
+		return err
+	}
+
+	runErr := registered.execute(ctx, request)
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	now := time.Now()
+	outcome := map[string]interface{}{
+		"status":      approval.StatusExecuted,
+		"executed_at": now,
+		"updated_by":  approver.UserID,
+	}
+	if runErr != nil {
+		outcome["status"] = approval.StatusFailed
+		outcome["failure_reason"] = truncate(runErr.Error(), 255)
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &approval.Request{}, outcome,
+		repository.Filter("id = ?", request.ID)); err != nil {
+		log.NewLog().Error("Recording the outcome of approval request " + request.ID.String() + ": " + err.Error())
+		return errors.NewDatabaseError("Failed to record the outcome of the approval request")
+	}
+	uow.Commit()
+
+	request.Status = outcome["status"].(string)
+	request.ExecutedAt = &now
+	if runErr != nil {
+		request.FailureReason = outcome["failure_reason"].(string)
+		return runErr
+	}
+	return nil
+}
+
+// Reject closes a pending request without running it.
+func (service *ApprovalService) Reject(ctx context.Context, requestID uuid.UUID, approver *security.Principal, decision *approval.Decision, request *approval.Request) error {
+
+	if err := decision.Validate(); err != nil {
+		return err
+	}
+
+	_, err := service.decide(ctx, requestID, approver, approval.StatusRejected, decision.Comment, request)
+	return err
+}
+
+// Cancel withdraws a pending request; only its proposer can.
+func (service *ApprovalService) Cancel(ctx context.Context, requestID, proposerID uuid.UUID, request *approval.Request) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.repository.GetRecordByID(uow, requestID, request); err != nil {
+		return errors.NewNotFoundError("Approval request not found")
+	}
+	if request.ProposedBy != proposerID {
+		return errors.NewNotFoundError("Approval request not found")
+	}
+	if request.Status != approval.StatusPending {
+		return errors.NewValidationError("Only pending approval requests can be cancelled")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &approval.Request{}, map[string]interface{}{
+		"status":     approval.StatusCancelled,
+		"updated_by": proposerID,
+	}, repository.Filter("id = ? AND status = ?", requestID, approval.StatusPending)); err != nil {
+		return errors.NewDatabaseError("Failed to cancel the approval request")
+	}
+
+	uow.Commit()
+	request.Status = approval.StatusCancelled
+	return nil
+}
+
+// decide records the approver's decision on a pending request and returns its action. The
+// update only applies while the request is pending, and reading it back tells whether this
+// approver's decision is the one that was recorded.
+func (service *ApprovalService) decide(ctx context.Context, requestID uuid.UUID, approver *security.Principal, status, comment string, request *approval.Request) (action, error) {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.requestOf(uow, requestID, approver.BankScope, request); err != nil {
+		return action{}, err
+	}
+
+	registered, exists := service.actions[request.Action]
+	if !exists {
+		return action{}, errors.NewValidationError("Action " + request.Action + " can no longer be approved")
+	}
+	if err := service.checkApprover(request, approver, registered); err != nil {
+		return action{}, err
+	}
+
+	now := time.Now()
+	if request.Status == approval.StatusPending && !request.IsOpen(now) {
+		if err := service.repository.UpdateWithMap(uow, &approval.Request{}, map[string]interface{}{
+			"status": approval.StatusExpired,
+		}, repository.Filter("id = ? AND status = ?", request.ID, approval.StatusPending)); err != nil {
+			return action{}, errors.NewDatabaseError("Failed to expire the approval request")
+		}
+		uow.Commit()
+		request.Status = approval.StatusExpired
+		return action{}, errors.NewValidationError("Approval request has expired")
+	}
+	if request.Status != approval.StatusPending {
+		return action{}, errors.NewValidationError("Approval request is already " + strings.ToLower(request.Status))
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &approval.Request{}, map[string]interface{}{
+		"status":           status,
+		"decided_by":       approver.UserID,
+		"decided_at":       now,
+		"decision_comment": comment,
+		"updated_by":       approver.UserID,
+	}, repository.Filter("id = ? AND status = ?", request.ID, approval.StatusPending)); err != nil {
+		return action{}, errors.NewDatabaseError("Failed to record the decision")
+	}
+
+	decided := approval.Request{}
+	if err := service.repository.GetRecordByID(uow, request.ID, &decided); err != nil {
+		return action{}, errors.NewDatabaseError("Failed to record the decision")
+	}
+	if decided.Status != status || decided.DecidedBy == nil || *decided.DecidedBy != approver.UserID {
+		return action{}, errors.NewValidationError("Approval request was decided by someone else")
+	}
+
+	uow.Commit()
+	*request = decided
+	return registered, nil
+}
+
+// checkApprover enforces the four-eyes rule: the approver is neither the proposer nor the user
+// the request is about, holds the action's permission and reaches its bank.
+func (service *ApprovalService) checkApprover(request *approval.Request, approver *security.Principal, registered action) error {
+
+	if approver.UserID == request.ProposedBy {
+		return errors.NewOutOfScopeError("Approval requests must be decided by someone other than their proposer")
+	}
+	if approver.UserID == request.TargetID {
+		return errors.NewOutOfScopeError("Approval requests about you must be decided by someone else")
+	}
+	if !approver.HasPermission(registered.permission) {
+		return errors.NewForbiddenError(registered.permission)
+	}
+	if request.BankID != nil {
+		return approver.BankScope.Check(*request.BankID)
+	}
+	return approver.BankScope.CheckGlobal()
+}
+
+// requestOf loads a request inside scope. Requests outside it are reported as missing.
+func (service *ApprovalService) requestOf(uow *repository.UnitOfWork, requestID uuid.UUID, scope security.BankScope, request *approval.Request) error {
+	if err := service.repository.GetRecordByID(uow, requestID, request); err != nil {
+		return errors.NewNotFoundError("Approval request not found")
+	}
+	if request.BankID != nil && !scope.Allows(*request.BankID) || request.BankID == nil && scope.Restricted {
+		return errors.NewNotFoundError("Approval request not found")
+	}
+	return nil
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
@@ -0,0 +1,287 @@
+package audit
+
+import (
+	"banking-app-be/components/pii"
+	"banking-app-be/model/audit"
+	"banking-app-be/module/repository"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// redacted stands in for the values of columns hidden from the API, such as password hashes.
+const redacted = "[redacted]"
+
+// bookkeepingColumns change with every write and are already covered by the entry itself.
+var bookkeepingColumns = map[string]bool{
+	"created_at": true, "created_by": true, "updated_at": true, "updated_by": true, "deleted_by": true,
+}
+
+// Repository records an audit entry for every row its writes create, update or delete. The
+// entries are written in the same unit of work, so they are kept exactly when the change is.
+type Repository struct {
+	repository.Repository
+	untracked map[string]bool
+}
+
+// NewRepository audits the writes made through inner, except to the untracked tables, which
+// are logs of their own such as the login history.
+func NewRepository(inner repository.Repository, untracked ...string) *Repository {
+	auditor := &Repository{Repository: inner, untracked: map[string]bool{}}
+	for _, table := range untracked {
+		auditor.untracked[table] = true
+	}
+	return auditor
+}
+
+func (auditor *Repository) Add(uow *repository.UnitOfWork, out interface{}) error {
+	if err := auditor.Repository.Add(uow, out); err != nil {
+		return err
+	}
+
+	scope := uow.DB.NewScope(out)
+	if auditor.untracked[scope.TableName()] {
+		return nil
+	}
+	return auditor.record(uow, audit.ActionCreate, scope, createdChanges(scope), actorOf(scope, "CreatedBy"))
+}
+
+func (auditor *Repository) UpdateWithMap(uow *repository.UnitOfWork, model interface{}, value map[string]interface{},
+	queryProcessors ...repository.QueryProcessor) error {
+
+	if auditor.untracked[uow.DB.NewScope(model).TableName()] {
+		return auditor.Repository.UpdateWithMap(uow, model, value, queryProcessors...)
+	}
+
+	before, err := auditor.snapshot(uow, model, queryProcessors)
+	if err != nil {
+		return err
+	}
+	if err := auditor.Repository.UpdateWithMap(uow, model, value, queryProcessors...); err != nil {
+		return err
+	}
+	return auditor.recordUpdates(uow, before)
+}
+
+func (auditor *Repository) Update(uow *repository.UnitOfWork, out interface{}, queryProcessors ...repository.QueryProcessor) error {
+
+	if auditor.untracked[uow.DB.NewScope(out).TableName()] {
+		return auditor.Repository.Update(uow, out, queryProcessors...)
+	}
+
+	before, err := auditor.snapshot(uow, out, queryProcessors)
+	if err != nil {
+		return err
+	}
+	if err := auditor.Repository.Update(uow, out, queryProcessors...); err != nil {
+		return err
+	}
+	return auditor.recordUpdates(uow, before)
+}
+
+// snapshot loads the rows a write to model filtered by queryProcessors is about to change. Like
+// the write, it is limited to model's primary key when that is set.
+func (auditor *Repository) snapshot(uow *repository.UnitOfWork, model interface{}, queryProcessors []repository.QueryProcessor) (reflect.Value, error) {
+
+	modelType := reflect.TypeOf(model)
+	if modelType.Kind() != reflect.Ptr || modelType.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("audit: can not track writes to %T", model)
+	}
+
+	scope := uow.DB.NewScope(model)
+	if !scope.PrimaryKeyZero() {
+		queryProcessors = append([]repository.QueryProcessor{
+			repository.Filter(scope.Quote(scope.PrimaryKey())+" = ?", scope.PrimaryKeyValue()),
+		}, queryProcessors...)
+	}
+
+	rows := reflect.New(reflect.SliceOf(modelType.Elem()))
+	if err := auditor.Repository.GetAll(uow, rows.Interface(), queryProcessors...); err != nil {
+		return reflect.Value{}, err
+	}
+	return rows.Elem(), nil
+}
+
+// recordUpdates compares the rows in before with their state after the write. A row whose
+// deleted_at was set is recorded as deleted.
+func (auditor *Repository) recordUpdates(uow *repository.UnitOfWork, before reflect.Value) error {
+
+	if before.Len() == 0 {
+		return nil
+	}
+
+	primaryKey := uow.DB.NewScope(before.Index(0).Addr().Interface()).PrimaryKey()
+	ids := make([]interface{}, before.Len())
+	for i := 0; i < before.Len(); i++ {
+		ids[i] = uow.DB.NewScope(before.Index(i).Addr().Interface()).PrimaryKeyValue()
+	}
+
+	after := reflect.New(before.Type())
+	if err := uow.DB.Unscoped().Where(primaryKey+" IN (?)", ids).Find(after.Interface()).Error; err != nil {
+		return err
+	}
+	afterByID := map[string]*gorm.Scope{}
+	for i := 0; i < after.Elem().Len(); i++ {
+		scope := uow.DB.NewScope(after.Elem().Index(i).Addr().Interface())
+		afterByID[fmt.Sprint(scope.PrimaryKeyValue())] = scope
+	}
+
+	for i := 0; i < before.Len(); i++ {
+		beforeScope := uow.DB.NewScope(before.Index(i).Addr().Interface())
+		afterScope, exists := afterByID[fmt.Sprint(beforeScope.PrimaryKeyValue())]
+		if !exists {
+			continue
+		}
+
+		changes, action := updatedChanges(beforeScope, afterScope)
+		if len(changes) == 0 {
+			continue
+		}
+
+		actorField := "UpdatedBy"
+		if action == audit.ActionDelete {
+			actorField = "DeletedBy"
+		}
+		if err := auditor.record(uow, action, afterScope, changes, actorOf(afterScope, actorField)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record adds the entry for one changed row. The user the unit of work's request was
+// authenticated as is the actor; writes outside a request fall back to the row's own
+// bookkeeping column.
+func (auditor *Repository) record(uow *repository.UnitOfWork, action string, scope *gorm.Scope, changes map[string]audit.Change, actorID uuid.UUID) error {
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	entry := audit.Entry{
+		ID:         uuid.NewV4(),
+		OccurredAt: time.Now(),
+		Action:     action,
+		EntityType: scope.TableName(),
+		EntityID:   fmt.Sprint(scope.PrimaryKeyValue()),
+		Changes:    encoded,
+	}
+	if request := RequestOf(uow.Context); request != nil {
+		entry.RequestID = request.ID
+		entry.IPAddress = request.IPAddress
+		entry.Method = request.Method
+		entry.Path = request.Path
+		entry.ActorID = request.ActorID
+		entry.ImpersonatorID = request.ImpersonatorID
+	}
+	if entry.ActorID == nil && actorID != uuid.Nil {
+		entry.ActorID = &actorID
+	}
+
+	return auditor.Repository.Add(uow, &entry)
+}
+
+// createdChanges returns what is recorded of a created row: every column that is set.
+func createdChanges(scope *gorm.Scope) map[string]audit.Change {
+	changes := map[string]audit.Change{}
+	for _, field := range auditedFields(scope) {
+		if !field.IsBlank {
+			changes[field.DBName] = audit.Change{After: valueOf(field)}
+		}
+	}
+	return changes
+}
+
+// updatedChanges returns the columns that differ between a row before and after a write, and
+// whether the write updated or deleted the row.
+func updatedChanges(before, after *gorm.Scope) (map[string]audit.Change, string) {
+	action := audit.ActionUpdate
+	changes := map[string]audit.Change{}
+	for _, field := range auditedFields(before) {
+		changed, _ := after.FieldByName(field.Name)
+		oldValue, newValue := valueOf(field), valueOf(changed)
+		if isRedacted(field) {
+			// Both sides are redacted, so the plain values tell whether it changed.
+			if changed != nil && reflect.DeepEqual(field.Field.Interface(), changed.Field.Interface()) {
+				continue
+			}
+		} else if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes[field.DBName] = audit.Change{Before: oldValue, After: newValue}
+		if field.DBName == "deleted_at" && oldValue == nil {
+			action = audit.ActionDelete
+		}
+	}
+	return changes, action
+}
+
+// auditedFields returns the columns of a row worth recording.
+func auditedFields(scope *gorm.Scope) []*gorm.Field {
+	fields := []*gorm.Field{}
+	for _, field := range scope.Fields() {
+		if !field.IsNormal || field.IsIgnored || bookkeepingColumns[field.DBName] {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// valueOf returns a column's value as it is recorded: pointers are followed, times and IDs are
+// written as strings, and secret columns are redacted.
+func valueOf(field *gorm.Field) interface{} {
+	if field == nil {
+		return nil
+	}
+
+	value := field.Field
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if isRedacted(field) {
+		return redacted
+	}
+
+	switch typed := value.Interface().(type) {
+	case time.Time:
+		return typed.UTC().Format(time.RFC3339)
+	case uuid.UUID:
+		return typed.String()
+	case json.RawMessage:
+		return string(typed)
+	default:
+		return typed
+	}
+}
+
+// isRedacted tells whether a column is kept out of the audit trail: personal data, columns
+// hidden from the API and columns tagged audit:"-", such as the password hash of a credential.
+func isRedacted(field *gorm.Field) bool {
+	return isPersonal(field) || field.Tag.Get("audit") == "-" ||
+		(field.Tag.Get("json") == "-" && field.DBName != "deleted_at")
+}
+
+// isPersonal tells whether a column holds personal data, which the audit trail must not copy
+// out of its encrypted column.
+func isPersonal(field *gorm.Field) bool {
+	return field.Field.Type() == reflect.TypeOf(pii.String(""))
+}
+
+// actorOf reads a bookkeeping column such as CreatedBy from a row.
+func actorOf(scope *gorm.Scope, name string) uuid.UUID {
+	field, ok := scope.FieldByName(name)
+	if !ok {
+		return uuid.Nil
+	}
+	actorID, _ := field.Field.Interface().(uuid.UUID)
+	return actorID
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"banking-app-be/model/audit"
+	"banking-app-be/model/credential"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	googleuuid "github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+)
+
+// noConnection lets gorm build scopes for the tests without a database behind them.
+type noConnection struct {
+	gorm.SQLCommon
+}
+
+func TestPasswordIsRedacted(t *testing.T) {
+
+	db, err := gorm.Open("mysql", noConnection{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const oldHash = "$2a$10$abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0"
+	const newHash = "$2a$10$0ZYXWVUTSRQPONMLKJIHGFEDCBAzyxwvutsrqponmlkjihgfedcba"
+	before := credential.Credential{Password: oldHash, UserID: googleuuid.New()}
+	after := before
+	after.Password = newHash
+
+	created := createdChanges(db.NewScope(&before))
+	changed, action := updatedChanges(db.NewScope(&before), db.NewScope(&after))
+	unchanged, _ := updatedChanges(db.NewScope(&before), db.NewScope(&before))
+
+	tests := []struct {
+		name    string
+		changes map[string]audit.Change
+		want    *audit.Change
+	}{
+		{name: "create", changes: created, want: &audit.Change{After: redacted}},
+		{name: "password change", changes: changed, want: &audit.Change{Before: redacted, After: redacted}},
+		{name: "other change", changes: unchanged},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, recorded := test.changes["password"]
+			if test.want == nil {
+				if recorded {
+					t.Errorf("got password change %v, want none", got)
+				}
+				return
+			}
+			if !recorded || got != *test.want {
+				t.Errorf("got password change %v, want %v", got, *test.want)
+			}
+
+			encoded, err := json.Marshal(test.changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(encoded), oldHash) || strings.Contains(string(encoded), newHash) {
+				t.Errorf("password hash recorded in %s", encoded)
+			}
+		})
+	}
+
+	if action != audit.ActionUpdate {
+		t.Errorf("got action %q, want %q", action, audit.ActionUpdate)
+	}
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"regexp"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// RequestIDHeader carries the ID of a request, taken from the caller or generated, so audit
+// entries can be matched with proxy and client logs.
+const RequestIDHeader = "X-Request-ID"
+
+var requestIDFormat = regexp.MustCompile(`^[A-Za-z0-9._-]{8,64}$`)
+
+// Request describes the HTTP request a change was made in.
+type Request struct {
+	ID        string
+	IPAddress string
+	Method    string
+	Path      string
+	ActorID   *uuid.UUID
+	// ImpersonatorID is the admin acting as the actor, if any.
+	ImpersonatorID *uuid.UUID
+}
+
+type requestContextKey struct{}
+
+// MiddlewareRequest assigns the request its ID and attaches it to the request's context, from
+// where units of work opened for the request carry it to the audit trail.
+func MiddlewareRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !requestIDFormat.MatchString(requestID) {
+			requestID = uuid.NewV4().String()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ipAddress = r.RemoteAddr
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestContextKey{}, &Request{
+			ID:        requestID,
+			IPAddress: ipAddress,
+			Method:    r.Method,
+			Path:      r.URL.Path,
+		})))
+	})
+}
+
+// WithActor returns r with who it was authenticated as and the admin impersonating them
+// recorded for the audit trail.
+func WithActor(r *http.Request, actorID uuid.UUID, impersonatorID *uuid.UUID) *http.Request {
+	current := RequestOf(r.Context())
+	if current == nil {
+		return r
+	}
+	request := *current
+	request.ActorID = &actorID
+	request.ImpersonatorID = impersonatorID
+	return r.WithContext(context.WithValue(r.Context(), requestContextKey{}, &request))
+}
+
+// RequestOf returns the request ctx was derived from, or nil for work done outside a request.
+func RequestOf(ctx context.Context) *Request {
+	if ctx == nil {
+		return nil
+	}
+	request, _ := ctx.Value(requestContextKey{}).(*Request)
+	return request
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/audit"
+	"banking-app-be/model/role"
+	"net/http"
+	"strconv"
+	"time"
+
+	auditService "banking-app-be/components/audit/service"
+
+	"github.com/gorilla/mux"
+)
+
+type AuditController struct {
+	log          log.Logger
+	AuditService *auditService.AuditService
+}
+
+func NewAuditController(auditService *auditService.AuditService, log log.Logger) *AuditController {
+	return &AuditController{
+		log:          log,
+		AuditService: auditService,
+	}
+}
+
+func (Controller *AuditController) RegisterRoutes(router *mux.Router) {
+
+	auditRouter := router.PathPrefix("/audit").Subrouter()
+	guardedRouter := auditRouter.PathPrefix("/").Subrouter()
+
+	//Get
+	guardedRouter.HandleFunc("/", security.Authorize(Controller.getEntries, role.AuditRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/verify", security.Authorize(Controller.verifyChain, role.AuditRead)).Methods(http.MethodGet)
+	guardedRouter.Use(security.MiddlewareActive)
+}
+
+// getEntries lists audit entries. They span every bank, so bank-scoped staff can not read them.
+func (controller *AuditController) getEntries(w http.ResponseWriter, r *http.Request) {
+	entries := []audit.Entry{}
+	var totalCount int
+	query := r.URL.Query()
+
+	if err := security.CurrentBankScope(r).CheckGlobal(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	filter := audit.Query{
+		EntityType: query.Get("entityType"),
+		EntityID:   query.Get("entityId"),
+		Action:     query.Get("action"),
+		RequestID:  query.Get("requestId"),
+	}
+	if actorID := query.Get("actorId"); actorID != "" {
+		if filter.ActorID, err = web.ParseUUID(actorID); err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid actor ID format"))
+			return
+		}
+	}
+	if impersonatorID := query.Get("impersonatorId"); impersonatorID != "" {
+		if filter.ImpersonatorID, err = web.ParseUUID(impersonatorID); err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid impersonator ID format"))
+			return
+		}
+	}
+	if filter.From, err = parseTime(query.Get("from")); err != nil {
+		web.RespondError(w, errors.NewValidationError("from must be an RFC 3339 time"))
+		return
+	}
+	if filter.To, err = parseTime(query.Get("to")); err != nil {
+		web.RespondError(w, errors.NewValidationError("to must be an RFC 3339 time"))
+		return
+	}
+
+	err = controller.AuditService.GetEntries(filter, &entries, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, entries)
+}
+
+func (controller *AuditController) verifyChain(w http.ResponseWriter, r *http.Request) {
+	verification := audit.Verification{}
+
+	if err := security.CurrentBankScope(r).CheckGlobal(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.AuditService.Verify(&verification); err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if !verification.Intact {
+		controller.log.Error("Audit chain is broken at link ", *verification.BrokenAt, ": ", verification.Reason)
+	}
+	web.RespondJSON(w, http.StatusOK, verification)
+}
+
+func parseTime(input string) (*time.Time, error) {
+	if input == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, input)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/model/audit"
+	"banking-app-be/module/repository"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type AuditService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewAuditService(DB *gorm.DB, repo repository.Repository) *AuditService {
+	return &AuditService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// GetEntries lists audit entries matching query, newest first, with the link sealing each one.
+func (service *AuditService) GetEntries(query audit.Query, entries *[]audit.Entry, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{}
+	if query.ActorID != uuid.Nil {
+		filters = append(filters, repository.Filter("actor_id = ?", query.ActorID))
+	}
+	if query.ImpersonatorID != uuid.Nil {
+		filters = append(filters, repository.Filter("impersonator_id = ?", query.ImpersonatorID))
+	}
+	if query.EntityType != "" {
+		filters = append(filters, repository.Filter("entity_type = ?", query.EntityType))
+	}
+	if query.EntityID != "" {
+		filters = append(filters, repository.Filter("entity_id = ?", query.EntityID))
+	}
+	if query.Action != "" {
+		filters = append(filters, repository.Filter("action = ?", query.Action))
+	}
+	if query.RequestID != "" {
+		filters = append(filters, repository.Filter("request_id = ?", query.RequestID))
+	}
+	if query.From != nil {
+		filters = append(filters, repository.Filter("occurred_at >= ?", *query.From))
+	}
+	if query.To != nil {
+		filters = append(filters, repository.Filter("occurred_at < ?", *query.To))
+	}
+
+	err := service.repository.GetAll(uow, entries, append(filters,
+		repository.PreloadAssociations([]string{"Link"}),
+		repository.OrderBy("occurred_at DESC"),
+		repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return errors.NewDatabaseError("Failed to fetch audit entries")
+	}
+
+	err = service.repository.GetCount(uow, &audit.Entry{}, totalCount, filters...)
+	if err != nil {
+		return errors.NewDatabaseError("Failed to count audit entries")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// Seal links the entries not yet in the hash chain onto its end and returns how many it
+// linked. Sequences are unique, so when two instances seal at once one of them fails and the
+// entries it read are linked on its next run.
+func (service *AuditService) Seal(now time.Time) (int, error) {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	head := audit.ChainLink{Hash: audit.GenesisHash}
+	err := service.repository.GetRecord(uow, &head, repository.OrderBy("sequence DESC"))
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return 0, err
+	}
+
+	pending := []audit.Entry{}
+	err = service.repository.GetAll(uow, &pending,
+		repository.Filter("id NOT IN (SELECT entry_id FROM audit_chain_links)"),
+		repository.OrderBy("occurred_at, id"),
+		repository.Paginate(int(config.AuditSealBatchSize.GetInt64ValueOrDefault(500)), 0, nil))
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range pending {
+		link := audit.ChainLink{
+			Sequence:     head.Sequence + 1,
+			EntryID:      pending[i].ID,
+			PreviousHash: head.Hash,
+			Hash:         audit.HashEntry(head.Hash, &pending[i]),
+			SealedAt:     now,
+		}
+		if err := service.repository.Add(uow, &link); err != nil {
+			return 0, err
+		}
+		head = link
+	}
+
+	uow.Commit()
+	return len(pending), nil
+}
+
+// Verify walks the hash chain from its first link and recomputes every hash. The chain is
+// intact when every link follows the one before it and matches its entry as stored.
+func (service *AuditService) Verify(verification *audit.Verification) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	const batchSize = 1000
+	previous := audit.ChainLink{Hash: audit.GenesisHash}
+	*verification = audit.Verification{Intact: true}
+
+	for {
+		links := []audit.ChainLink{}
+		err := service.repository.GetAll(uow, &links,
+			repository.Filter("sequence > ?", previous.Sequence),
+			repository.OrderBy("sequence"),
+			repository.Paginate(batchSize, 0, nil))
+		if err != nil {
+			return errors.NewDatabaseError("Failed to read the audit chain")
+		}
+
+		for _, link := range links {
+			if reason := service.checkLink(uow, &previous, &link); reason != "" {
+				sequence := link.Sequence
+				verification.Intact = false
+				verification.BrokenAt = &sequence
+				verification.Reason = reason
+				return service.countUnsealed(uow, verification)
+			}
+			verification.SealedCount++
+			previous = link
+		}
+
+		if len(links) < batchSize {
+			return service.countUnsealed(uow, verification)
+		}
+	}
+}
+
+// checkLink returns why link does not follow previous, or nothing when it does.
+func (service *AuditService) checkLink(uow *repository.UnitOfWork, previous, link *audit.ChainLink) string {
+
+	if link.Sequence != previous.Sequence+1 {
+		return fmt.Sprintf("Links %d to %d are missing", previous.Sequence+1, link.Sequence-1)
+	}
+	if link.PreviousHash != previous.Hash {
+		return "Link does not follow the hash of the link before it"
+	}
+
+	entry := audit.Entry{}
+	if err := service.repository.GetRecordByID(uow, link.EntryID, &entry); err != nil {
+		return "Sealed entry " + link.EntryID.String() + " is missing"
+	}
+	if audit.HashEntry(link.PreviousHash, &entry) != link.Hash {
+		return "Entry " + entry.ID.String() + " was changed after it was sealed"
+	}
+	return ""
+}
+
+func (service *AuditService) countUnsealed(uow *repository.UnitOfWork, verification *audit.Verification) error {
+	err := service.repository.GetCount(uow, &audit.Entry{}, &verification.UnsealedCount,
+		repository.Filter("id NOT IN (SELECT entry_id FROM audit_chain_links)"))
+	if err != nil {
+		return errors.NewDatabaseError("Failed to count unsealed audit entries")
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"time"
+)
+
+// AuditSealer links new audit entries into the hash chain. Entries are written with the change
+// they record and sealed here shortly after, outside the transactions of the services.
+type AuditSealer struct {
+	log      log.Logger
+	service  *AuditService
+	interval time.Duration
+}
+
+func NewAuditSealer(service *AuditService, log log.Logger) *AuditSealer {
+	return &AuditSealer{
+		log:      log,
+		service:  service,
+		interval: time.Duration(config.AuditSealIntervalSeconds.GetInt64ValueOrDefault(5)) * time.Second,
+	}
+}
+
+func (sealer *AuditSealer) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(sealer.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			if _, err := sealer.service.Seal(now); err != nil {
+				sealer.log.Error("Audit sealing failed: ", err.Error())
+			}
+		}
+	}
+}
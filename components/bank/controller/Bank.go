@@ -5,25 +5,38 @@ import (
 	"banking-app-be/components/log"
 	"banking-app-be/components/security"
 	"banking-app-be/components/web"
+	"banking-app-be/model/approval"
 	"banking-app-be/model/bank"
 	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/reserve"
+	"banking-app-be/model/role"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	approvalService "banking-app-be/components/approval/service"
 	bankService "banking-app-be/components/bank/service"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
 )
 
 type BankController struct {
-	log         log.Logger
-	BankService *bankService.BankService
+	log            log.Logger
+	BankService    *bankService.BankService
+	ReserveService *bankService.ReserveService
+
+	ApprovalService *approvalService.ApprovalService
 }
 
-func NewBankController(userService *bankService.BankService, log log.Logger) *BankController {
+func NewBankController(userService *bankService.BankService, reserveService *bankService.ReserveService,
+	approvalService *approvalService.ApprovalService, log log.Logger) *BankController {
 	return &BankController{
-		log:         log,
-		BankService: userService,
+		log:            log,
+		BankService:    userService,
+		ReserveService: reserveService,
+
+		ApprovalService: approvalService,
 	}
 }
 
@@ -35,14 +48,18 @@ func (Controller *BankController) RegisterRoutes(router *mux.Router) {
 	commonRouter := bankRouter.PathPrefix("/").Subrouter()
 
 	//Post
-	guardedRouter.HandleFunc("/register-bank", Controller.addBank).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/register-bank", security.Authorize(Controller.addBank, role.BankCreate)).Methods(http.MethodPost)
 	//Settlement
-	guardedRouter.HandleFunc("/settlement", Controller.settlement).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/settlement", security.Authorize(Controller.settlement, role.SettlementRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/settlement/confirm", security.Authorize(Controller.confirmSettlement, role.SettlementConfirm)).Methods(http.MethodPost)
+	//Reserve
+	guardedRouter.HandleFunc("/{id}/reserve", security.AuthorizeBank(Controller.getReserve, "id", role.ReserveRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}/reserve/fund", security.AuthorizeBank(Controller.fundReserve, "id", role.ReserveFund)).Methods(http.MethodPost)
 	//Update
-	guardedRouter.HandleFunc("/{id}", Controller.updateBankById).Methods(http.MethodPut)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeBank(Controller.updateBankById, "id", role.BankUpdate)).Methods(http.MethodPut)
 	//Delete
-	guardedRouter.HandleFunc("/{id}", Controller.deleteBankById).Methods(http.MethodDelete)
-	guardedRouter.Use(security.MiddlewareAdmin)
+	guardedRouter.HandleFunc("/{id}", security.AuthorizeBank(Controller.deleteBankById, "id", role.BankDelete)).Methods(http.MethodDelete)
+	guardedRouter.Use(security.MiddlewareActive)
 
 	//===========================
 
@@ -59,14 +76,14 @@ func (controller *BankController) addBank(w http.ResponseWriter, r *http.Request
 	}
 
 	var err error
-	newBank.CreatedBy, err = security.ExtractUserIDFromToken(r)
+	newBank.CreatedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
 		return
 	}
 
-	if err := controller.BankService.CreateBank(&newBank); err != nil {
+	if err := controller.BankService.CreateBank(r.Context(), &newBank); err != nil {
 		web.RespondError(w, err)
 		return
 	}
@@ -130,7 +147,7 @@ func (controller *BankController) updateBankById(w http.ResponseWriter, r *http.
 
 	var err error
 
-	bankToUpdate.UpdatedBy, err = security.ExtractUserIDFromToken(r)
+	bankToUpdate.UpdatedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -155,7 +172,7 @@ func (controller *BankController) updateBankById(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if err := controller.BankService.UpdateBank(&bankToUpdate); err != nil {
+	if err := controller.BankService.UpdateBank(r.Context(), &bankToUpdate); err != nil {
 		web.RespondError(w, err)
 		return
 	}
@@ -175,7 +192,7 @@ func (controller *BankController) deleteBankById(w http.ResponseWriter, r *http.
 	bankToDelete := bank.Bank{}
 	parser := web.NewParser(r)
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -190,7 +207,14 @@ func (controller *BankController) deleteBankById(w http.ResponseWriter, r *http.
 	}
 	bankToDelete.ID = bankIdFromURL
 
-	if err := controller.BankService.DeleteBank(&bankToDelete); err != nil {
+	request := approval.Request{Action: approval.ActionBankDelete, TargetID: bankToDelete.ID, BankID: &bankToDelete.ID,
+		Summary: "Delete bank " + bankToDelete.ID.String(), ProposedBy: userID}
+	if filed, err := controller.ApprovalService.Propose(r.Context(), &request, nil); err != nil || filed {
+		respondProposal(w, &request, err)
+		return
+	}
+
+	if err := controller.BankService.DeleteBank(r.Context(), &bankToDelete); err != nil {
 		web.RespondError(w, err)
 		return
 	}
@@ -202,14 +226,14 @@ func (controller *BankController) settlement(w http.ResponseWriter, r *http.Requ
 	ledger := []banktransaction.BankTransactionDTO{}
 	var totalCount int
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	userID, err := security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error("Failed to extract user ID: " + err.Error())
 		web.RespondError(w, err)
 		return
 	}
 
-	err = controller.BankService.Settlement(userID, &ledger, &totalCount)
+	err = controller.BankService.Settlement(userID, security.CurrentBankScope(r), &ledger, &totalCount)
 	if err != nil {
 		controller.log.Error("Failed to compute settlement: " + err.Error())
 		web.RespondError(w, err)
@@ -218,3 +242,125 @@ func (controller *BankController) settlement(w http.ResponseWriter, r *http.Requ
 
 	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, ledger)
 }
+
+func (controller *BankController) confirmSettlement(w http.ResponseWriter, r *http.Request) {
+	ledger := []banktransaction.BankTransactionDTO{}
+	var totalCount int
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error("Failed to extract user ID: " + err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	scope := security.CurrentBankScope(r)
+	if err := scope.CheckGlobal(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	request := approval.Request{Action: approval.ActionSettlementConfirm, TargetID: uuid.Nil,
+		Summary: "Confirm the inter-bank settlement", ProposedBy: userID}
+	if filed, err := controller.ApprovalService.Propose(r.Context(), &request, nil); err != nil || filed {
+		respondProposal(w, &request, err)
+		return
+	}
+
+	err = controller.BankService.ConfirmSettlement(r.Context(), userID, scope, &ledger, &totalCount)
+	if err != nil {
+		controller.log.Error("Failed to confirm settlement: " + err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, ledger)
+}
+
+func (controller *BankController) getReserve(w http.ResponseWriter, r *http.Request) {
+	reserveAccount := reserve.ReserveAccount{}
+	entries := []reserve.ReserveEntry{}
+	var totalCount int
+	parser := web.NewParser(r)
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	bankID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	err = controller.ReserveService.GetReserve(bankID, &reserveAccount, &entries, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	reserveAccount.Entries = entries
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, reserveAccount)
+}
+
+func (controller *BankController) fundReserve(w http.ResponseWriter, r *http.Request) {
+	parser := web.NewParser(r)
+
+	var requestData struct {
+		Amount float32 `json:"amount"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	bankID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	if requestData.Amount <= 0 {
+		web.RespondError(w, errors.NewValidationError("Funding amount must be positive"))
+		return
+	}
+
+	request := approval.Request{Action: approval.ActionReserveFund, TargetID: bankID, BankID: &bankID,
+		Summary: fmt.Sprintf("Fund the reserve of bank %s with %0.2f", bankID, requestData.Amount), ProposedBy: userID}
+	if filed, err := controller.ApprovalService.Propose(r.Context(), &request, approval.ReserveFunding{Amount: requestData.Amount}); err != nil || filed {
+		respondProposal(w, &request, err)
+		return
+	}
+
+	if err := controller.ReserveService.Fund(r.Context(), bankID, requestData.Amount, userID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Reserve funded successfully"})
+}
+
+// respondProposal answers a request that was held for approval instead of being carried out.
+func respondProposal(w http.ResponseWriter, request *approval.Request, err error) {
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSON(w, http.StatusAccepted, request)
+}
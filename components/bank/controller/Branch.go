@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/branch"
+	"banking-app-be/model/role"
+	"net/http"
+	"strconv"
+
+	bankService "banking-app-be/components/bank/service"
+
+	"github.com/gorilla/mux"
+)
+
+type BranchController struct {
+	log           log.Logger
+	BranchService *bankService.BranchService
+}
+
+func NewBranchController(branchService *bankService.BranchService, log log.Logger) *BranchController {
+	return &BranchController{
+		log:           log,
+		BranchService: branchService,
+	}
+}
+
+func (Controller *BranchController) RegisterRoutes(router *mux.Router) {
+
+	bankRouter := router.PathPrefix("/bank").Subrouter()
+	guardedRouter := bankRouter.PathPrefix("/").Subrouter()
+	commonRouter := bankRouter.PathPrefix("/").Subrouter()
+
+	//Post
+	guardedRouter.HandleFunc("/{id}/branch", security.AuthorizeBank(Controller.addBranch, "id", role.BranchManage)).Methods(http.MethodPost)
+	//Update
+	guardedRouter.HandleFunc("/branch/{id}", security.Authorize(Controller.updateBranchById, role.BranchManage)).Methods(http.MethodPut)
+	//Delete
+	guardedRouter.HandleFunc("/branch/{id}", security.Authorize(Controller.deleteBranchById, role.BranchManage)).Methods(http.MethodDelete)
+	guardedRouter.Use(security.MiddlewareActive)
+
+	//===========================
+
+	//Get
+	commonRouter.HandleFunc("/branch/{code}", Controller.getBranchByCode).Methods(http.MethodGet)
+	commonRouter.HandleFunc("/{id}/branch", Controller.getAllBranches).Methods(http.MethodGet)
+}
+
+func (controller *BranchController) addBranch(w http.ResponseWriter, r *http.Request) {
+	newBranch := branch.Branch{}
+	parser := web.NewParser(r)
+
+	if err := web.UnmarshalJSON(r, &newBranch); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	newBranch.CreatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	newBranch.BankID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	if err := controller.BranchService.CreateBranch(r.Context(), &newBranch); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newBranch)
+}
+
+func (controller *BranchController) getAllBranches(w http.ResponseWriter, r *http.Request) {
+	allBranches := []branch.Branch{}
+	var totalCount int
+	query := r.URL.Query()
+	parser := web.NewParser(r)
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	bankID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	err = controller.BranchService.GetAllBranches(bankID, &allBranches, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allBranches)
+}
+
+func (controller *BranchController) getBranchByCode(w http.ResponseWriter, r *http.Request) {
+	targetBranch := branch.Branch{}
+
+	if err := controller.BranchService.GetBranchByCode(mux.Vars(r)["code"], &targetBranch); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, targetBranch)
+}
+
+func (controller *BranchController) updateBranchById(w http.ResponseWriter, r *http.Request) {
+	branchToUpdate := branch.Branch{}
+	parser := web.NewParser(r)
+
+	if err := web.UnmarshalJSON(r, &branchToUpdate); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	branchToUpdate.UpdatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	branchToUpdate.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid branch ID format"))
+		return
+	}
+
+	if err := controller.BranchService.UpdateBranch(r.Context(), &branchToUpdate, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, branchToUpdate)
+}
+
+func (controller *BranchController) deleteBranchById(w http.ResponseWriter, r *http.Request) {
+	branchToDelete := branch.Branch{}
+	parser := web.NewParser(r)
+
+	var err error
+	branchToDelete.DeletedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	branchToDelete.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid branch ID format"))
+		return
+	}
+
+	if err := controller.BranchService.DeleteBranch(r.Context(), &branchToDelete, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Branch deleted successfully"})
+}
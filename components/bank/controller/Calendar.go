@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/holiday"
+	"banking-app-be/model/role"
+	"net/http"
+	"strconv"
+	"time"
+
+	bankService "banking-app-be/components/bank/service"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+type CalendarController struct {
+	log             log.Logger
+	CalendarService *bankService.CalendarService
+}
+
+func NewCalendarController(calendarService *bankService.CalendarService, log log.Logger) *CalendarController {
+	return &CalendarController{
+		log:             log,
+		CalendarService: calendarService,
+	}
+}
+
+func (Controller *CalendarController) RegisterRoutes(router *mux.Router) {
+
+	bankRouter := router.PathPrefix("/bank").Subrouter()
+	guardedRouter := bankRouter.PathPrefix("/").Subrouter()
+	commonRouter := bankRouter.PathPrefix("/").Subrouter()
+
+	//Holidays
+	guardedRouter.HandleFunc("/holiday", security.Authorize(Controller.addHoliday, role.CalendarManage)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/holiday", security.Authorize(Controller.getHolidays, role.CalendarRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/holiday/{id}", security.Authorize(Controller.deleteHolidayById, role.CalendarManage)).Methods(http.MethodDelete)
+	guardedRouter.Use(security.MiddlewareActive)
+
+	//===========================
+
+	//Business days
+	commonRouter.HandleFunc("/business-day", Controller.getBusinessDay).Methods(http.MethodGet)
+	commonRouter.HandleFunc("/{id}/business-day", Controller.getBusinessDay).Methods(http.MethodGet)
+}
+
+func (controller *CalendarController) addHoliday(w http.ResponseWriter, r *http.Request) {
+	newHoliday := holiday.Holiday{}
+	if err := web.UnmarshalJSON(r, &newHoliday); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	newHoliday.CreatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.CalendarService.CreateHoliday(r.Context(), &newHoliday, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newHoliday)
+}
+
+func (controller *CalendarController) getHolidays(w http.ResponseWriter, r *http.Request) {
+	allHolidays := []holiday.Holiday{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	var bankID *uuid.UUID
+	if query.Get("bankId") != "" {
+		parsedID, err := web.ParseUUID(query.Get("bankId"))
+		if err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+			return
+		}
+		bankID = &parsedID
+	}
+
+	err = controller.CalendarService.GetHolidays(bankID, query.Get("year"), &allHolidays, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allHolidays)
+}
+
+func (controller *CalendarController) deleteHolidayById(w http.ResponseWriter, r *http.Request) {
+	holidayToDelete := holiday.Holiday{}
+	parser := web.NewParser(r)
+
+	var err error
+	holidayToDelete.DeletedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	holidayToDelete.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid holiday ID format"))
+		return
+	}
+
+	if err := controller.CalendarService.DeleteHoliday(r.Context(), &holidayToDelete, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Holiday deleted successfully"})
+}
+
+// getBusinessDay answers for the bank in the path, or for the system calendar on /business-day.
+// The date defaults to today.
+func (controller *CalendarController) getBusinessDay(w http.ResponseWriter, r *http.Request) {
+	businessDay := holiday.BusinessDay{
+		Date: r.URL.Query().Get("date"),
+	}
+	if businessDay.Date == "" {
+		businessDay.Date = time.Now().Format(holiday.DateLayout)
+	}
+
+	if _, hasBank := mux.Vars(r)["id"]; hasBank {
+		bankID, err := web.NewParser(r).GetUUID("id")
+		if err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+			return
+		}
+		businessDay.BankID = &bankID
+	}
+
+	if err := controller.CalendarService.GetBusinessDay(&businessDay); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, businessDay)
+}
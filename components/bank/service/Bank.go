@@ -3,10 +3,14 @@ package service
 import (
 	"banking-app-be/components/errors"
 	"banking-app-be/components/log"
+	"banking-app-be/components/security"
 	"banking-app-be/model/bank"
 	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/reserve"
+	"banking-app-be/model/role"
 	"banking-app-be/model/user"
 	"banking-app-be/module/repository"
+	"context"
 	"fmt"
 	"time"
 
@@ -15,19 +19,23 @@ import (
 )
 
 type BankService struct {
-	db         *gorm.DB
-	repository repository.Repository
+	db              *gorm.DB
+	repository      repository.Repository
+	reserveService  *ReserveService
+	calendarService *CalendarService
 }
 
-func NewBankService(DB *gorm.DB, repo repository.Repository) *BankService {
+func NewBankService(DB *gorm.DB, repo repository.Repository, reserveService *ReserveService, calendarService *CalendarService) *BankService {
 	return &BankService{
-		db:         DB,
-		repository: repo,
+		db:              DB,
+		repository:      repo,
+		reserveService:  reserveService,
+		calendarService: calendarService,
 	}
 }
 
-func (service *BankService) CreateBank(newBank *bank.Bank) error {
-	uow := repository.NewUnitOfWork(service.db, false)
+func (service *BankService) CreateBank(ctx context.Context, newBank *bank.Bank) error {
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	if err := newBank.Validate(); err != nil {
@@ -35,10 +43,19 @@ func (service *BankService) CreateBank(newBank *bank.Bank) error {
 		return err
 	}
 
-	if err := uow.DB.Create(newBank).Error; err != nil {
+	newBank.Reserve = nil
+	if err := service.repository.Add(uow, newBank); err != nil {
 		return errors.NewDatabaseError("Failed to create bank")
 	}
 
+	// Every bank opens with an empty reserve; funds are added through the funding API.
+	newReserve := reserve.ReserveAccount{BankID: newBank.ID}
+	newReserve.CreatedBy = newBank.CreatedBy
+	if err := service.repository.Add(uow, &newReserve); err != nil {
+		return errors.NewDatabaseError("Failed to open reserve account")
+	}
+	newBank.Reserve = &newReserve
+
 	uow.Commit()
 	return nil
 }
@@ -69,7 +86,7 @@ func (service *BankService) GetBankByID(targetBank *bank.BankDTO) error {
 	defer uow.RollBack()
 
 	//repository.PreloadAssociations([]string{"Accounts", "bankTransactions"})
-	err := service.repository.GetRecordByID(uow, targetBank.ID, targetBank, repository.PreloadAssociations([]string{"Accounts", "Accounts.User"}))
+	err := service.repository.GetRecordByID(uow, targetBank.ID, targetBank, repository.PreloadAssociations([]string{"Accounts", "Accounts.User", "Accounts.Branch", "Reserve", "Branches"}))
 	if err != nil {
 		return err
 	}
@@ -78,14 +95,14 @@ func (service *BankService) GetBankByID(targetBank *bank.BankDTO) error {
 	return nil
 }
 
-func (service *BankService) UpdateBank(bankToUpdate *bank.Bank) error {
+func (service *BankService) UpdateBank(ctx context.Context, bankToUpdate *bank.Bank) error {
 
 	err := service.doesBankExist(bankToUpdate.ID)
 	if err != nil {
 		return err
 	}
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	// existingBank := bank.Bank{}
@@ -114,8 +131,8 @@ func (service *BankService) UpdateBank(bankToUpdate *bank.Bank) error {
 	return nil
 }
 
-func (service *BankService) DeleteBank(bankToDelete *bank.Bank) error {
-	uow := repository.NewUnitOfWork(service.db, false)
+func (service *BankService) DeleteBank(ctx context.Context, bankToDelete *bank.Bank) error {
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	if err := service.repository.UpdateWithMap(uow, bankToDelete, map[string]interface{}{
@@ -132,25 +149,153 @@ func (service *BankService) DeleteBank(bankToDelete *bank.Bank) error {
 	return nil
 }
 
-func (service *BankService) Settlement(userId uuid.UUID, ledger *[]banktransaction.BankTransactionDTO, totalCount *int) error {
+// Settlement previews the net settlement lines; bank-scoped admins only see lines involving
+// their banks.
+func (service *BankService) Settlement(userId uuid.UUID, scope security.BankScope, ledger *[]banktransaction.BankTransactionDTO, totalCount *int) error {
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	// Verify user is admin and active
-	adminUser := user.User{}
-	if err := service.repository.GetRecordByID(uow, userId, &adminUser); err != nil {
+	if err := service.verifyPermission(uow, userId, role.SettlementRead); err != nil {
+		return err
+	}
+
+	// Fetch unsettled transactions
+	allTransactions := []banktransaction.BankTransaction{}
+	if err := service.repository.GetAll(uow, &allTransactions, repository.Filter("is_settled = ?", false),
+		scope.Filter("sender_bank_id", "receiver_bank_id")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch bank transaction entries")
+	}
+
+	netSettlements := computeNetSettlements(allTransactions)
+	if err := service.loadBankNames(uow, netSettlements); err != nil {
+		return err
+	}
+
+	*ledger = netSettlements
+	*totalCount = len(netSettlements)
+	uow.Commit()
+	return nil
+}
+
+// ConfirmSettlement settles every outstanding inter-bank transaction: the net amount of each
+// bank pair moves between their reserves and the underlying transactions are marked settled.
+// As it moves every bank's reserve, bank-scoped admins can not confirm it.
+func (service *BankService) ConfirmSettlement(ctx context.Context, userId uuid.UUID, scope security.BankScope, ledger *[]banktransaction.BankTransactionDTO, totalCount *int) error {
+
+	if err := scope.CheckGlobal(); err != nil {
+		return err
+	}
+
+	// Reserves only move on business days of the system calendar.
+	isBusinessDay, err := service.calendarService.IsBusinessDay(nil, time.Now())
+	if err != nil {
 		return err
 	}
-	if adminUser.IsAdmin == nil || !*adminUser.IsAdmin || adminUser.IsActive == nil || !*adminUser.IsActive {
-		return errors.NewValidationError("Only active admin users can access settlement records")
+	if !isBusinessDay {
+		return errors.NewValidationError("Settlement can only be confirmed on a business day")
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.verifyPermission(uow, userId, role.SettlementConfirm); err != nil {
+		return err
 	}
 
-	// Fetch all transactions
 	allTransactions := []banktransaction.BankTransaction{}
-	if err := service.repository.GetAll(uow, &allTransactions); err != nil {
+	if err := service.repository.GetAll(uow, &allTransactions, repository.Filter("is_settled = ?", false)); err != nil {
 		return errors.NewDatabaseError("Unable to fetch bank transaction entries")
 	}
 
+	netSettlements := computeNetSettlements(allTransactions)
+	for _, settlement := range netSettlements {
+		if err := service.reserveService.Settle(uow, settlement.SenderBankID, settlement.ReceiverBankID, settlement.Amount, userId); err != nil {
+			return err
+		}
+	}
+
+	for _, tx := range allTransactions {
+		updateData := map[string]interface{}{
+			"is_settled": true,
+			"settled_at": time.Now(),
+			"settled_by": userId,
+		}
+		if err := service.repository.UpdateWithMap(uow, &banktransaction.BankTransaction{}, updateData, repository.Filter("id = ?", tx.ID)); err != nil {
+			return errors.NewDatabaseError("Failed to mark bank transaction as settled")
+		}
+	}
+
+	if err := service.loadBankNames(uow, netSettlements); err != nil {
+		return err
+	}
+
+	*ledger = netSettlements
+	*totalCount = len(netSettlements)
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+func (service *BankService) doesBankExist(ID uuid.UUID) error {
+	exists, err := repository.DoesRecordExistForUser(service.db, ID, bank.Bank{},
+		repository.Filter("`id` = ?", ID))
+	if !exists || err != nil {
+		return errors.NewValidationError("User ID is Invalid")
+	}
+	return nil
+}
+
+// verifyPermission checks the user is still active and one of their roles grants permission.
+// Confirmed settlements run as their proposer, possibly long after the route authorized them.
+func (service *BankService) verifyPermission(uow *repository.UnitOfWork, userId uuid.UUID, permission string) error {
+	settlingUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userId, &settlingUser); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+	if settlingUser.IsActive == nil || !*settlingUser.IsActive {
+		return errors.NewInActiveUserError("Only active users can access settlement records")
+	}
+
+	userRoles := []role.UserRole{}
+	if err := service.repository.GetAll(uow, &userRoles, repository.Filter("user_id = ?", userId)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch roles")
+	}
+	roles := make([]string, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		roles = append(roles, userRole.Role)
+	}
+	if !role.PermissionsOf(roles...)[permission] {
+		return errors.NewForbiddenError(permission)
+	}
+	return nil
+}
+
+// Load bank names for each settlement
+func (service *BankService) loadBankNames(uow *repository.UnitOfWork, netSettlements []banktransaction.BankTransactionDTO) error {
+	for i, tx := range netSettlements {
+		// Load Sender Bank Name
+		senderBank := banktransaction.SenderBankName{}
+		if err := service.repository.GetRecordByID(uow, tx.SenderBankID, &senderBank); err != nil {
+			return fmt.Errorf("failed to load sender bank name: %w", err)
+		}
+		tx.SenderBank = senderBank
+
+		// Load Receiver Bank Name
+		receiverBank := banktransaction.ReceiverBankName{}
+		if err := service.repository.GetRecordByID(uow, tx.ReceiverBankID, &receiverBank); err != nil {
+			return fmt.Errorf("failed to load receiver bank name: %w", err)
+		}
+		tx.ReceiverBank = receiverBank
+
+		netSettlements[i] = tx
+	}
+	return nil
+}
+
+// computeNetSettlements nets the transactions of every bank pair into a single line where
+// SenderBankID is the bank that owes and ReceiverBankID the bank that is owed.
+func computeNetSettlements(allTransactions []banktransaction.BankTransaction) []banktransaction.BankTransactionDTO {
 	// Build pairwise ledger
 	pairwise := make(map[uuid.UUID]map[uuid.UUID]float32)
 	for _, tx := range allTransactions {
@@ -177,14 +322,14 @@ func (service *BankService) Settlement(userId uuid.UUID, ledger *[]banktransacti
 
 			if net > 0 {
 				netSettlements = append(netSettlements, banktransaction.BankTransactionDTO{
-					SenderBankID:   toBank,
-					ReceiverBankID: fromBank,
+					SenderBankID:   fromBank,
+					ReceiverBankID: toBank,
 					Amount:         net,
 				})
 			} else if net < 0 {
 				netSettlements = append(netSettlements, banktransaction.BankTransactionDTO{
-					SenderBankID:   fromBank,
-					ReceiverBankID: toBank,
+					SenderBankID:   toBank,
+					ReceiverBankID: fromBank,
 					Amount:         -net,
 				})
 			}
@@ -193,39 +338,5 @@ func (service *BankService) Settlement(userId uuid.UUID, ledger *[]banktransacti
 			processed[reversePairKey] = true
 		}
 	}
-
-	// Load bank names for each settlement
-	for i, tx := range netSettlements {
-		// Load Sender Bank Name
-		senderBank := banktransaction.SenderBankName{}
-		if err := service.repository.GetRecordByID(uow, tx.SenderBankID, &senderBank); err != nil {
-			return fmt.Errorf("failed to load sender bank name: %w", err)
-		}
-		tx.SenderBank = senderBank
-
-		// Load Receiver Bank Name
-		receiverBank := banktransaction.ReceiverBankName{}
-		if err := service.repository.GetRecordByID(uow, tx.ReceiverBankID, &receiverBank); err != nil {
-			return fmt.Errorf("failed to load receiver bank name: %w", err)
-		}
-		tx.ReceiverBank = receiverBank
-
-		netSettlements[i] = tx
-	}
-
-	*ledger = netSettlements
-	*totalCount = len(netSettlements)
-	uow.Commit()
-	return nil
-}
-
-//=======================================================================================
-
-func (service *BankService) doesBankExist(ID uuid.UUID) error {
-	exists, err := repository.DoesRecordExistForUser(service.db, ID, bank.Bank{},
-		repository.Filter("`id` = ?", ID))
-	if !exists || err != nil {
-		return errors.NewValidationError("User ID is Invalid")
-	}
-	return nil
+	return netSettlements
 }
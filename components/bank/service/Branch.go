@@ -0,0 +1,228 @@
+package service
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/model/account"
+	"banking-app-be/model/bank"
+	"banking-app-be/model/branch"
+	"banking-app-be/module/repository"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type BranchService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewBranchService(DB *gorm.DB, repo repository.Repository) *BranchService {
+	return &BranchService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+func (service *BranchService) CreateBranch(ctx context.Context, newBranch *branch.Branch) error {
+
+	if err := newBranch.Validate(); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	parentBank := bank.Bank{}
+	if err := service.repository.GetRecordByID(uow, newBranch.BankID, &parentBank); err != nil {
+		return errors.NewNotFoundError("Bank not found with given Id")
+	}
+	if parentBank.IsActive != nil && !*parentBank.IsActive {
+		return errors.NewValidationError("Can not open a branch in InActive bank")
+	}
+
+	if err := service.isCodeAvailable(uow, newBranch, uuid.Nil); err != nil {
+		return err
+	}
+
+	if err := service.repository.Add(uow, newBranch); err != nil {
+		return errors.NewDatabaseError("Failed to create branch")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *BranchService) GetAllBranches(bankID uuid.UUID, allBranches *[]branch.Branch, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	exists, err := repository.DoesRecordExistForUser(uow.DB, bankID, bank.Bank{}, repository.Filter("`id` = ?", bankID))
+	if !exists || err != nil {
+		return errors.NewNotFoundError("Bank not found with given Id")
+	}
+
+	err = service.repository.GetAll(uow, allBranches, repository.Filter("bank_id = ?", bankID),
+		repository.OrderBy("name ASC"), repository.Paginate(limit, offset, totalCount))
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allBranches, totalCount, repository.Filter("bank_id = ?", bankID))
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetBranchByCode looks a branch up by its IFSC or BIC.
+func (service *BranchService) GetBranchByCode(code string, targetBranch *branch.Branch) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := FindBranchByCode(uow, service.repository, code, targetBranch); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *BranchService) UpdateBranch(ctx context.Context, branchToUpdate *branch.Branch, scope security.BankScope) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	existingBranch := branch.Branch{}
+	if err := service.repository.GetRecordByID(uow, branchToUpdate.ID, &existingBranch); err != nil {
+		return errors.NewNotFoundError("Branch not found with given Id")
+	}
+	if err := scope.Check(existingBranch.BankID); err != nil {
+		return err
+	}
+
+	// A branch can not move to another bank.
+	branchToUpdate.BankID = existingBranch.BankID
+	if err := branchToUpdate.Validate(); err != nil {
+		return err
+	}
+	if err := service.isCodeAvailable(uow, branchToUpdate, branchToUpdate.ID); err != nil {
+		return err
+	}
+
+	updateData := map[string]interface{}{
+		"name":        branchToUpdate.Name,
+		"ifsc":        branchToUpdate.IFSC,
+		"bic":         branchToUpdate.BIC,
+		"address":     branchToUpdate.Address,
+		"city":        branchToUpdate.City,
+		"state":       branchToUpdate.State,
+		"postal_code": branchToUpdate.PostalCode,
+		"updated_by":  branchToUpdate.UpdatedBy,
+		"updated_at":  time.Now(),
+	}
+	if branchToUpdate.IsActive != nil {
+		updateData["is_active"] = *branchToUpdate.IsActive
+	}
+	if err := service.repository.UpdateWithMap(uow, &branch.Branch{}, updateData, repository.Filter("id = ?", branchToUpdate.ID)); err != nil {
+		return errors.NewDatabaseError("Unable to update branch")
+	}
+
+	if err := service.repository.GetRecordByID(uow, branchToUpdate.ID, branchToUpdate); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// DeleteBranch closes a branch. Branches that are still home to accounts can only be deactivated.
+func (service *BranchService) DeleteBranch(ctx context.Context, branchToDelete *branch.Branch, scope security.BankScope) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	existingBranch := branch.Branch{}
+	if err := service.repository.GetRecordByID(uow, branchToDelete.ID, &existingBranch); err != nil {
+		return errors.NewNotFoundError("Branch not found with given Id")
+	}
+	if err := scope.Check(existingBranch.BankID); err != nil {
+		return err
+	}
+
+	accountCount := 0
+	if err := service.repository.GetCount(uow, &account.Account{}, &accountCount, repository.Filter("branch_id = ?", branchToDelete.ID)); err != nil {
+		return errors.NewDatabaseError("Unable to check branch accounts")
+	}
+	if accountCount > 0 {
+		return errors.NewValidationError("Branch still has accounts; deactivate it instead")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &branch.Branch{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": branchToDelete.DeletedBy,
+		"is_active":  false,
+	}, repository.Filter("id = ?", branchToDelete.ID)); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// FindBranchByCode resolves an IFSC or BIC to its branch within an existing unit of work.
+func FindBranchByCode(uow *repository.UnitOfWork, repo repository.Repository, code string, targetBranch *branch.Branch) error {
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	var filter repository.QueryProcessor
+	switch {
+	case branch.IsValidIFSC(code):
+		filter = repository.Filter("ifsc = ?", code)
+	case branch.IsValidBIC(code):
+		filter = repository.Filter("bic = ?", code)
+	default:
+		return errors.NewValidationError("Branch code must be a valid IFSC or BIC")
+	}
+
+	if err := repo.GetRecord(uow, targetBranch, filter); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return errors.NewNotFoundError("Branch not found with code " + code)
+		}
+		return errors.NewDatabaseError("Unable to fetch branch")
+	}
+	return nil
+}
+
+//=======================================================================================
+
+// isCodeAvailable checks no other branch than exceptID already uses the IFSC or BIC.
+func (service *BranchService) isCodeAvailable(uow *repository.UnitOfWork, candidate *branch.Branch, exceptID uuid.UUID) error {
+
+	count := 0
+	if err := service.repository.GetCount(uow, &branch.Branch{}, &count,
+		repository.Filter("ifsc = ? AND id <> ?", candidate.IFSC, exceptID)); err != nil {
+		return errors.NewDatabaseError("Unable to check branch codes")
+	}
+	if count > 0 {
+		return errors.NewValidationError("A branch with IFSC " + candidate.IFSC + " already exists")
+	}
+
+	if candidate.BIC == nil {
+		return nil
+	}
+	if err := service.repository.GetCount(uow, &branch.Branch{}, &count,
+		repository.Filter("bic = ? AND id <> ?", *candidate.BIC, exceptID)); err != nil {
+		return errors.NewDatabaseError("Unable to check branch codes")
+	}
+	if count > 0 {
+		return errors.NewValidationError("A branch with BIC " + *candidate.BIC + " already exists")
+	}
+	return nil
+}
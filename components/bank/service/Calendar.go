@@ -0,0 +1,205 @@
+package service
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/model/bank"
+	"banking-app-be/model/holiday"
+	"banking-app-be/module/repository"
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// maxCalendarLookahead bounds the search for the next business day.
+const maxCalendarLookahead = 366
+
+type CalendarService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewCalendarService(DB *gorm.DB, repo repository.Repository) *CalendarService {
+	return &CalendarService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+func (service *CalendarService) CreateHoliday(ctx context.Context, newHoliday *holiday.Holiday, scope security.BankScope) error {
+
+	if err := newHoliday.Validate(); err != nil {
+		return err
+	}
+	if err := checkCalendarScope(scope, newHoliday.BankID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if newHoliday.BankID != nil {
+		exists, err := repository.DoesRecordExistForUser(uow.DB, *newHoliday.BankID, bank.Bank{}, repository.Filter("`id` = ?", *newHoliday.BankID))
+		if !exists || err != nil {
+			return errors.NewNotFoundError("Bank not found with given Id")
+		}
+	}
+
+	count := 0
+	if err := service.repository.GetCount(uow, &holiday.Holiday{}, &count,
+		repository.Filter("holiday_date = ?", newHoliday.Date), ownerFilter(newHoliday.BankID)); err != nil {
+		return errors.NewDatabaseError("Unable to check existing holidays")
+	}
+	if count > 0 {
+		return errors.NewValidationError("Holiday already exists on " + newHoliday.Date)
+	}
+
+	if err := service.repository.Add(uow, newHoliday); err != nil {
+		return errors.NewDatabaseError("Failed to create holiday")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetHolidays lists the holidays of a bank together with the system holidays, or only the
+// system holidays when bankID is nil. An empty year lists every year.
+func (service *CalendarService) GetHolidays(bankID *uuid.UUID, year string, allHolidays *[]holiday.Holiday, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{calendarFilter(bankID)}
+	if year != "" {
+		filters = append(filters, repository.Filter("holiday_date LIKE ?", year+"-%"))
+	}
+
+	err := service.repository.GetAll(uow, allHolidays, append(filters, repository.OrderBy("holiday_date ASC"), repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allHolidays, totalCount, filters...)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *CalendarService) DeleteHoliday(ctx context.Context, holidayToDelete *holiday.Holiday, scope security.BankScope) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	existingHoliday := holiday.Holiday{}
+	if err := service.repository.GetRecordByID(uow, holidayToDelete.ID, &existingHoliday); err != nil {
+		return errors.NewNotFoundError("Holiday not found with given Id")
+	}
+	if err := checkCalendarScope(scope, existingHoliday.BankID); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &holiday.Holiday{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": holidayToDelete.DeletedBy,
+	}, repository.Filter("id = ?", holidayToDelete.ID)); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// IsBusinessDay reports whether day is neither a weekend nor a holiday of the bank or of the
+// system calendar. A nil bankID consults the system calendar only.
+func (service *CalendarService) IsBusinessDay(bankID *uuid.UUID, day time.Time) (bool, error) {
+
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false, nil
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	count := 0
+	if err := service.repository.GetCount(uow, &holiday.Holiday{}, &count,
+		repository.Filter("holiday_date = ?", day.Format(holiday.DateLayout)), calendarFilter(bankID)); err != nil {
+		return false, errors.NewDatabaseError("Unable to check holidays")
+	}
+	return count == 0, nil
+}
+
+// NextBusinessDay returns the first business day strictly after day.
+func (service *CalendarService) NextBusinessDay(bankID *uuid.UUID, day time.Time) (time.Time, error) {
+
+	next := day
+	for i := 0; i < maxCalendarLookahead; i++ {
+		next = next.AddDate(0, 0, 1)
+		isBusinessDay, err := service.IsBusinessDay(bankID, next)
+		if err != nil {
+			return day, err
+		}
+		if isBusinessDay {
+			return next, nil
+		}
+	}
+	return day, errors.NewValidationError("No business day found within a year")
+}
+
+func (service *CalendarService) GetBusinessDay(businessDay *holiday.BusinessDay) error {
+
+	day, err := time.ParseInLocation(holiday.DateLayout, businessDay.Date, time.Local)
+	if err != nil {
+		return errors.NewValidationError("Date must be of the format YYYY-MM-DD")
+	}
+
+	if businessDay.BankID != nil {
+		exists, err := repository.DoesRecordExistForUser(service.db, *businessDay.BankID, bank.Bank{}, repository.Filter("`id` = ?", *businessDay.BankID))
+		if !exists || err != nil {
+			return errors.NewNotFoundError("Bank not found with given Id")
+		}
+	}
+
+	businessDay.IsBusinessDay, err = service.IsBusinessDay(businessDay.BankID, day)
+	if err != nil {
+		return err
+	}
+
+	next, err := service.NextBusinessDay(businessDay.BankID, day)
+	if err != nil {
+		return err
+	}
+	businessDay.NextBusinessDay = next.Format(holiday.DateLayout)
+	return nil
+}
+
+//=======================================================================================
+
+// calendarFilter selects system holidays plus, when bankID is set, the bank's own holidays.
+func calendarFilter(bankID *uuid.UUID) repository.QueryProcessor {
+	if bankID == nil {
+		return repository.Filter("bank_id IS NULL")
+	}
+	return repository.Filter("(bank_id IS NULL OR bank_id = ?)", *bankID)
+}
+
+// ownerFilter selects exactly the holidays owned by bankID, or the system holidays when nil.
+func ownerFilter(bankID *uuid.UUID) repository.QueryProcessor {
+	if bankID == nil {
+		return repository.Filter("bank_id IS NULL")
+	}
+	return repository.Filter("bank_id = ?", *bankID)
+}
+
+// checkCalendarScope lets bank-scoped admins manage the holidays of their banks only; system
+// holidays apply to every bank.
+func checkCalendarScope(scope security.BankScope, bankID *uuid.UUID) error {
+	if bankID == nil {
+		return scope.CheckGlobal()
+	}
+	return scope.Check(*bankID)
+}
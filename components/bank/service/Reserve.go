@@ -0,0 +1,202 @@
+package service
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/model/bank"
+	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/reserve"
+	"banking-app-be/module/repository"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type ReserveService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewReserveService(DB *gorm.DB, repo repository.Repository) *ReserveService {
+	return &ReserveService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// GetReserve loads the reserve of a bank with a page of its entries. Banks created before
+// reserves existed have none until their first funding or settlement opens it.
+func (service *ReserveService) GetReserve(bankID uuid.UUID, reserveAccount *reserve.ReserveAccount, entries *[]reserve.ReserveEntry,
+	totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetRecord(uow, reserveAccount, repository.Filter("bank_id = ?", bankID))
+	if gorm.IsRecordNotFoundError(err) {
+		return errors.NewNotFoundError("Reserve account not found for given bank")
+	}
+	if err != nil {
+		return errors.NewDatabaseError("Unable to fetch reserve account")
+	}
+
+	queryProcessor := []repository.QueryProcessor{
+		repository.Filter("reserve_account_id = ?", reserveAccount.ID),
+		repository.Paginate(limit, offset, totalCount),
+	}
+	if err := service.repository.GetAll(uow, entries, queryProcessor...); err != nil {
+		return err
+	}
+
+	if err := service.repository.GetCount(uow, entries, totalCount, repository.Filter("reserve_account_id = ?", reserveAccount.ID)); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *ReserveService) Fund(ctx context.Context, bankID uuid.UUID, amount float32, fundedBy uuid.UUID) error {
+
+	if amount <= 0 {
+		return errors.NewValidationError("Funding amount must be positive")
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	reserveAccount := reserve.ReserveAccount{}
+	if err := service.getOrCreateReserve(uow, bankID, &reserveAccount); err != nil {
+		return err
+	}
+
+	reserveAccount.Balance += amount
+	if err := service.postEntry(uow, &reserveAccount, reserve.EntryFunding, amount, uuid.Nil, fundedBy,
+		fmt.Sprintf("Reserve funded with %0.2f", amount)); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// PostInterBankTransfer records an unsettled inter-bank transfer against both reserves.
+// The sender's pending balance is debited and the receiver's credited; settled balances
+// only move once the settlement is confirmed.
+func (service *ReserveService) PostInterBankTransfer(uow *repository.UnitOfWork, bankTransfer *banktransaction.BankTransaction) error {
+
+	senderReserve := reserve.ReserveAccount{}
+	if err := service.getOrCreateReserve(uow, bankTransfer.SenderBankID, &senderReserve); err != nil {
+		return err
+	}
+	senderReserve.PendingBalance -= bankTransfer.Amount
+	if err := service.postEntry(uow, &senderReserve, reserve.EntryTransferOut, -bankTransfer.Amount, bankTransfer.ID, bankTransfer.CreatedBy,
+		fmt.Sprintf("%0.2f owed to bank %s", bankTransfer.Amount, bankTransfer.ReceiverBankID)); err != nil {
+		return err
+	}
+
+	receiverReserve := reserve.ReserveAccount{}
+	if err := service.getOrCreateReserve(uow, bankTransfer.ReceiverBankID, &receiverReserve); err != nil {
+		return err
+	}
+	receiverReserve.PendingBalance += bankTransfer.Amount
+	if err := service.postEntry(uow, &receiverReserve, reserve.EntryTransferIn, bankTransfer.Amount, bankTransfer.ID, bankTransfer.CreatedBy,
+		fmt.Sprintf("%0.2f receivable from bank %s", bankTransfer.Amount, bankTransfer.SenderBankID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Settle moves the net amount from the debtor bank's reserve to the creditor bank's reserve
+// and releases the matching pending balances.
+func (service *ReserveService) Settle(uow *repository.UnitOfWork, debtorBankID, creditorBankID uuid.UUID, amount float32, settledBy uuid.UUID) error {
+
+	debtorReserve := reserve.ReserveAccount{}
+	if err := service.getOrCreateReserve(uow, debtorBankID, &debtorReserve); err != nil {
+		return err
+	}
+	if debtorReserve.Balance < amount {
+		return errors.NewValidationError(fmt.Sprintf("Insufficient reserve balance for bank %s to settle %0.2f", debtorBankID, amount))
+	}
+
+	debtorReserve.Balance -= amount
+	debtorReserve.PendingBalance += amount
+	if err := service.postEntry(uow, &debtorReserve, reserve.EntrySettlementDebit, -amount, uuid.Nil, settledBy,
+		fmt.Sprintf("%0.2f settled to bank %s", amount, creditorBankID)); err != nil {
+		return err
+	}
+
+	creditorReserve := reserve.ReserveAccount{}
+	if err := service.getOrCreateReserve(uow, creditorBankID, &creditorReserve); err != nil {
+		return err
+	}
+
+	creditorReserve.Balance += amount
+	creditorReserve.PendingBalance -= amount
+	if err := service.postEntry(uow, &creditorReserve, reserve.EntrySettlementCredit, amount, uuid.Nil, settledBy,
+		fmt.Sprintf("%0.2f settled from bank %s", amount, debtorBankID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+//=======================================================================================
+
+// getOrCreateReserve loads the reserve of the bank, opening an empty one for banks
+// created before reserves existed. The row stays locked until uow ends, as its balances are
+// written back from the values read here.
+func (service *ReserveService) getOrCreateReserve(uow *repository.UnitOfWork, bankID uuid.UUID, reserveAccount *reserve.ReserveAccount) error {
+
+	err := service.repository.GetRecord(uow, reserveAccount, repository.Filter("bank_id = ?", bankID), repository.ForUpdate())
+	if err == nil {
+		return nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return errors.NewDatabaseError("Unable to fetch reserve account")
+	}
+
+	exists, err := repository.DoesRecordExistForUser(uow.DB, bankID, bank.Bank{}, repository.Filter("`id` = ?", bankID))
+	if !exists || err != nil {
+		return errors.NewNotFoundError("Bank not found with given Id")
+	}
+
+	*reserveAccount = reserve.ReserveAccount{BankID: bankID}
+	if err := service.repository.Add(uow, reserveAccount); err != nil {
+		return errors.NewDatabaseError("Failed to open reserve account")
+	}
+	return nil
+}
+
+func (service *ReserveService) postEntry(uow *repository.UnitOfWork, reserveAccount *reserve.ReserveAccount, entryType string,
+	amount float32, bankTransactionID, userID uuid.UUID, note string) error {
+
+	updateData := map[string]interface{}{
+		"balance":         reserveAccount.Balance,
+		"pending_balance": reserveAccount.PendingBalance,
+		"updated_by":      userID,
+		"updated_at":      time.Now(),
+	}
+	if err := service.repository.UpdateWithMap(uow, &reserve.ReserveAccount{}, updateData, repository.Filter("id = ?", reserveAccount.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to update reserve balance")
+	}
+
+	entry := reserve.ReserveEntry{
+		ReserveAccountID:  reserveAccount.ID,
+		TimeStamp:         time.Now(),
+		Type:              entryType,
+		Amount:            amount,
+		Balance:           reserveAccount.Balance,
+		PendingBalance:    reserveAccount.PendingBalance,
+		BankTransactionID: bankTransactionID,
+		Note:              note,
+	}
+	entry.CreatedBy = userID
+	if err := service.repository.Add(uow, &entry); err != nil {
+		return errors.NewDatabaseError("Failed to record reserve entry")
+	}
+	return nil
+}
@@ -13,6 +13,100 @@ const (
 	DBHost EnvKey = "DB_HOST"
 
 	// For Server
-	PORT   EnvKey = "PORT"
-	JWTKey EnvKey = "JWT_KEY"
+	PORT EnvKey = "PORT"
+
+	// For Token Signing
+	JWTKeysDir             EnvKey = "JWT_KEYS_DIR"
+	JWTAlgorithm           EnvKey = "JWT_ALGORITHM"
+	JWTGenerateMissingKeys EnvKey = "JWT_GENERATE_MISSING_KEYS"
+	JWTKeyReloadSeconds    EnvKey = "JWT_KEY_RELOAD_SECONDS"
+
+	// For Sessions
+	AccessTokenTTLMinutes EnvKey = "ACCESS_TOKEN_TTL_MINUTES"
+	RefreshTokenTTLHours  EnvKey = "REFRESH_TOKEN_TTL_HOURS"
+
+	// For Impersonation
+	ImpersonationDefaultMinutes EnvKey = "IMPERSONATION_DEFAULT_MINUTES"
+	ImpersonationMaxMinutes     EnvKey = "IMPERSONATION_MAX_MINUTES"
+
+	// For Admin Onboarding
+	AdminSetupToken     EnvKey = "ADMIN_SETUP_TOKEN"
+	AdminInviteTTLHours EnvKey = "ADMIN_INVITE_TTL_HOURS"
+	AdminInviteURL      EnvKey = "ADMIN_INVITE_URL"
+
+	// For Notifications
+	Notifier         EnvKey = "NOTIFIER"
+	NotifierFilePath EnvKey = "NOTIFIER_FILE_PATH"
+
+	// For Passwords
+	PasswordMinLength            EnvKey = "PASSWORD_MIN_LENGTH"
+	PasswordRequireUpper         EnvKey = "PASSWORD_REQUIRE_UPPER"
+	PasswordRequireLower         EnvKey = "PASSWORD_REQUIRE_LOWER"
+	PasswordRequireDigit         EnvKey = "PASSWORD_REQUIRE_DIGIT"
+	PasswordRequireSymbol        EnvKey = "PASSWORD_REQUIRE_SYMBOL"
+	PasswordMaxAgeDays           EnvKey = "PASSWORD_MAX_AGE_DAYS"
+	PasswordDisallowPersonalInfo EnvKey = "PASSWORD_DISALLOW_PERSONAL_INFO"
+	BreachedPasswordsDir         EnvKey = "BREACHED_PASSWORDS_DIR"
+	PasswordHistorySize          EnvKey = "PASSWORD_HISTORY_SIZE"
+	PasswordResetTTLMinutes      EnvKey = "PASSWORD_RESET_TTL_MINUTES"
+	PasswordResetURL             EnvKey = "PASSWORD_RESET_URL"
+	PasswordResetMaxRequests     EnvKey = "PASSWORD_RESET_MAX_REQUESTS"
+	PasswordResetIPMaxRequests   EnvKey = "PASSWORD_RESET_IP_MAX_REQUESTS"
+
+	// For Login Throttling
+	LoginFreeAttempts         EnvKey = "LOGIN_FREE_ATTEMPTS"
+	LoginDelayBaseSeconds     EnvKey = "LOGIN_DELAY_BASE_SECONDS"
+	LoginDelayMaxSeconds      EnvKey = "LOGIN_DELAY_MAX_SECONDS"
+	LoginAccountMaxFailures   EnvKey = "LOGIN_ACCOUNT_MAX_FAILURES"
+	LoginIPMaxFailures        EnvKey = "LOGIN_IP_MAX_FAILURES"
+	LoginLockoutMinutes       EnvKey = "LOGIN_LOCKOUT_MINUTES"
+	LoginFailureWindowMinutes EnvKey = "LOGIN_FAILURE_WINDOW_MINUTES"
+
+	// For Multi-Factor Authentication
+	MFAIssuer              EnvKey = "MFA_ISSUER"
+	MFAChallengeTTLMinutes EnvKey = "MFA_CHALLENGE_TTL_MINUTES"
+
+	// For Step-Up Authentication
+	StepUpThresholdAmount        EnvKey = "STEP_UP_THRESHOLD_AMOUNT"
+	TransactionPINMaxAttempts    EnvKey = "TRANSACTION_PIN_MAX_ATTEMPTS"
+	TransactionPINLockoutMinutes EnvKey = "TRANSACTION_PIN_LOCKOUT_MINUTES"
+
+	// For API Keys
+	APIKeyDefaultTTLDays EnvKey = "API_KEY_DEFAULT_TTL_DAYS"
+	APIKeyMaxTTLDays     EnvKey = "API_KEY_MAX_TTL_DAYS"
+
+	// For Request Signing
+	SignatureMaxSkewSeconds EnvKey = "SIGNATURE_MAX_SKEW_SECONDS"
+	SignatureMaxBodyBytes   EnvKey = "SIGNATURE_MAX_BODY_BYTES"
+
+	// For Approvals
+	ApprovalRequiredActions EnvKey = "APPROVAL_REQUIRED_ACTIONS"
+	ApprovalTTLHours        EnvKey = "APPROVAL_TTL_HOURS"
+
+	// For Audit Trail
+	AuditSealIntervalSeconds EnvKey = "AUDIT_SEAL_INTERVAL_SECONDS"
+	AuditSealBatchSize       EnvKey = "AUDIT_SEAL_BATCH_SIZE"
+
+	// For Personal Data Encryption
+	PIIKeyFile                EnvKey = "PII_KEY_FILE"
+	PIIGenerateMissingKeyfile EnvKey = "PII_GENERATE_MISSING_KEYFILE"
+
+	// For Redaction
+	MaskAccountNumbers EnvKey = "MASK_ACCOUNT_NUMBERS"
+
+	// For Security Middleware
+	PrincipalCacheTTLSeconds EnvKey = "PRINCIPAL_CACHE_TTL_SECONDS"
+	PrincipalCacheMaxEntries EnvKey = "PRINCIPAL_CACHE_MAX_ENTRIES"
+
+	// For Payment Rails
+	InstantMaxAmount         EnvKey = "INSTANT_MAX_AMOUNT"
+	BatchWindowMinutes       EnvKey = "BATCH_WINDOW_MINUTES"
+	BatchStartHour           EnvKey = "BATCH_START_HOUR"
+	BatchEndHour             EnvKey = "BATCH_END_HOUR"
+	HighValueMinAmount       EnvKey = "HIGH_VALUE_MIN_AMOUNT"
+	HighValueOpenHour        EnvKey = "HIGH_VALUE_OPEN_HOUR"
+	HighValueCloseHour       EnvKey = "HIGH_VALUE_CLOSE_HOUR"
+	PaymentSchedulerInterval EnvKey = "PAYMENT_SCHEDULER_INTERVAL_SECONDS"
+	PaymentRetryBaseSeconds  EnvKey = "PAYMENT_RETRY_BASE_SECONDS"
+	PaymentRetryMaxSeconds   EnvKey = "PAYMENT_RETRY_MAX_SECONDS"
 )
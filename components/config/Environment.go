@@ -1,5 +1,7 @@
 package config
 
+import "strconv"
+
 type Environment string
 
 type EnvKey string
@@ -9,6 +11,34 @@ func (e EnvKey) GetStringValue() string {
 	return GlobalConfig.GetString(e)
 }
 
+// GetStringValueOrDefault returns defaultValue when the key is not configured.
+func (e EnvKey) GetStringValueOrDefault(defaultValue string) string {
+	if !GlobalConfig.IsSet(e) {
+		return defaultValue
+	}
+	return GlobalConfig.GetString(e)
+}
+
 func (e EnvKey) GetInt64Value() int64 {
 	return GlobalConfig.GetInt64(e)
 }
+
+// GetInt64ValueOrDefault returns defaultValue when the key is not configured.
+func (e EnvKey) GetInt64ValueOrDefault(defaultValue int64) int64 {
+	if !GlobalConfig.IsSet(e) {
+		return defaultValue
+	}
+	return GlobalConfig.GetInt64(e)
+}
+
+// GetBoolValueOrDefault returns defaultValue when the key is not configured or is not a boolean.
+func (e EnvKey) GetBoolValueOrDefault(defaultValue bool) bool {
+	if !GlobalConfig.IsSet(e) {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(GlobalConfig.GetString(e))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,43 @@
+package errors
+
+import "net/http"
+
+// ForbiddenError represents an authenticated request that lacks a permission the resource requires.
+type ForbiddenError struct {
+	HTTPStatus        int    `example:"403" json:"-"`
+	Message           string `example:"Missing permission bank:create" json:"message"`
+	MissingPermission string `example:"bank:create" json:"missingPermission,omitempty"`
+	StepUpRequired    bool   `example:"true" json:"stepUpRequired,omitempty"`
+}
+
+// Error Implements error interface
+func (e ForbiddenError) Error() string {
+	return e.Message
+}
+
+// NewForbiddenError returns new instance of Forbidden error naming the missing permission.
+func NewForbiddenError(permission string) *ForbiddenError {
+	return &ForbiddenError{
+		HTTPStatus:        http.StatusForbidden,
+		Message:           "Missing permission " + permission,
+		MissingPermission: permission,
+	}
+}
+
+// NewOutOfScopeError returns a Forbidden error for resources outside the caller's bank scope.
+func NewOutOfScopeError(msg string) *ForbiddenError {
+	return &ForbiddenError{
+		HTTPStatus: http.StatusForbidden,
+		Message:    msg,
+	}
+}
+
+// NewStepUpRequiredError returns a Forbidden error telling the client to repeat the request with
+// a transaction PIN or a fresh MFA code.
+func NewStepUpRequiredError(msg string) *ForbiddenError {
+	return &ForbiddenError{
+		HTTPStatus:     http.StatusForbidden,
+		Message:        msg,
+		StepUpRequired: true,
+	}
+}
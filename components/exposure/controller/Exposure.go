@@ -0,0 +1,209 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/exposure"
+	"banking-app-be/model/role"
+	"net/http"
+	"strconv"
+
+	exposureService "banking-app-be/components/exposure/service"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+type ExposureController struct {
+	log             log.Logger
+	ExposureService *exposureService.ExposureService
+}
+
+func NewExposureController(exposureService *exposureService.ExposureService, log log.Logger) *ExposureController {
+	return &ExposureController{
+		log:             log,
+		ExposureService: exposureService,
+	}
+}
+
+func (Controller *ExposureController) RegisterRoutes(router *mux.Router) {
+
+	bankRouter := router.PathPrefix("/bank").Subrouter()
+	guardedRouter := bankRouter.PathPrefix("/").Subrouter()
+
+	//Exposure limits
+	guardedRouter.HandleFunc("/exposure-limit", security.Authorize(Controller.addLimit, role.ExposureManage)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/exposure-limit", security.Authorize(Controller.getAllLimits, role.ExposureRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/exposure-limit/{id}", security.Authorize(Controller.updateLimitById, role.ExposureManage)).Methods(http.MethodPut)
+	guardedRouter.HandleFunc("/exposure-limit/{id}", security.Authorize(Controller.deleteLimitById, role.ExposureManage)).Methods(http.MethodDelete)
+	//Alerts
+	guardedRouter.HandleFunc("/liquidity-alert", security.Authorize(Controller.getAlerts, role.ExposureRead)).Methods(http.MethodGet)
+	//Position
+	guardedRouter.HandleFunc("/{id}/position", security.AuthorizeBank(Controller.getPosition, "id", role.ExposureRead)).Methods(http.MethodGet)
+	guardedRouter.Use(security.MiddlewareActive)
+}
+
+func (controller *ExposureController) addLimit(w http.ResponseWriter, r *http.Request) {
+	newLimit := exposure.ExposureLimit{}
+	if err := web.UnmarshalJSON(r, &newLimit); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	newLimit.CreatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ExposureService.CreateLimit(&newLimit, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newLimit)
+}
+
+func (controller *ExposureController) getAllLimits(w http.ResponseWriter, r *http.Request) {
+	allLimits := []exposure.ExposureLimit{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, offset := parsePagination(query.Get("limit"), query.Get("offset"))
+
+	bankID, err := parseOptionalUUID(query.Get("bankId"))
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	err = controller.ExposureService.GetAllLimits(&allLimits, bankID, security.CurrentBankScope(r), &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allLimits)
+}
+
+func (controller *ExposureController) updateLimitById(w http.ResponseWriter, r *http.Request) {
+	limitToUpdate := exposure.ExposureLimit{}
+	parser := web.NewParser(r)
+
+	if err := web.UnmarshalJSON(r, &limitToUpdate); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse request data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	limitToUpdate.UpdatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	limitToUpdate.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid exposure limit ID format"))
+		return
+	}
+
+	if err := controller.ExposureService.UpdateLimit(&limitToUpdate, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, limitToUpdate)
+}
+
+func (controller *ExposureController) deleteLimitById(w http.ResponseWriter, r *http.Request) {
+	limitToDelete := exposure.ExposureLimit{}
+	parser := web.NewParser(r)
+
+	var err error
+	limitToDelete.DeletedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	limitToDelete.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid exposure limit ID format"))
+		return
+	}
+
+	if err := controller.ExposureService.DeleteLimit(&limitToDelete, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Exposure limit deleted successfully"})
+}
+
+func (controller *ExposureController) getAlerts(w http.ResponseWriter, r *http.Request) {
+	allAlerts := []exposure.LiquidityAlert{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, offset := parsePagination(query.Get("limit"), query.Get("offset"))
+
+	bankID, err := parseOptionalUUID(query.Get("bankId"))
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	err = controller.ExposureService.GetAlerts(&allAlerts, bankID, security.CurrentBankScope(r), &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allAlerts)
+}
+
+func (controller *ExposureController) getPosition(w http.ResponseWriter, r *http.Request) {
+	positions := []exposure.BankPosition{}
+	parser := web.NewParser(r)
+
+	bankID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+		return
+	}
+
+	if err := controller.ExposureService.GetPosition(bankID, &positions); err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(positions), positions)
+}
+
+func parsePagination(limitStr, offsetStr string) (int, int) {
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+	return limit, offset
+}
+
+func parseOptionalUUID(input string) (uuid.UUID, error) {
+	if input == "" {
+		return uuid.Nil, nil
+	}
+	return web.ParseUUID(input)
+}
@@ -0,0 +1,369 @@
+package service
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/bank"
+	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/exposure"
+	"banking-app-be/module/repository"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type ExposureService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewExposureService(DB *gorm.DB, repo repository.Repository) *ExposureService {
+	return &ExposureService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+func (service *ExposureService) CreateLimit(newLimit *exposure.ExposureLimit, scope security.BankScope) error {
+
+	if err := newLimit.Validate(); err != nil {
+		return err
+	}
+	if err := scope.Check(newLimit.BankID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.doBanksExist(uow, newLimit.BankID, newLimit.CounterpartyBankID); err != nil {
+		return err
+	}
+
+	existingLimit := exposure.ExposureLimit{}
+	err := service.repository.GetRecord(uow, &existingLimit,
+		repository.Filter("bank_id = ? AND counterparty_bank_id = ?", newLimit.BankID, newLimit.CounterpartyBankID))
+	if err == nil {
+		return errors.NewValidationError("Exposure limit already exists for this bank pair")
+	}
+
+	if err := service.repository.Add(uow, newLimit); err != nil {
+		return errors.NewDatabaseError("Failed to create exposure limit")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *ExposureService) GetAllLimits(allLimits *[]exposure.ExposureLimit, bankID uuid.UUID, scope security.BankScope, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{scope.Filter("bank_id")}
+	if bankID != uuid.Nil {
+		filters = append(filters, repository.Filter("bank_id = ?", bankID))
+	}
+
+	err := service.repository.GetAll(uow, allLimits, append(filters, repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allLimits, totalCount, filters...)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *ExposureService) UpdateLimit(limitToUpdate *exposure.ExposureLimit, scope security.BankScope) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingLimit := exposure.ExposureLimit{}
+	if err := service.repository.GetRecordByID(uow, limitToUpdate.ID, &existingLimit); err != nil {
+		return errors.NewNotFoundError("Exposure limit not found with given Id")
+	}
+	if err := scope.Check(existingLimit.BankID); err != nil {
+		return err
+	}
+
+	// The bank pair of a limit is fixed; only the cap and its behaviour can change.
+	limitToUpdate.BankID = existingLimit.BankID
+	limitToUpdate.CounterpartyBankID = existingLimit.CounterpartyBankID
+	if err := limitToUpdate.Validate(); err != nil {
+		return err
+	}
+
+	updateData := map[string]interface{}{
+		"debit_cap":       limitToUpdate.DebitCap,
+		"alert_threshold": limitToUpdate.AlertThreshold,
+		"breach_action":   limitToUpdate.BreachAction,
+		"updated_by":      limitToUpdate.UpdatedBy,
+		"updated_at":      time.Now(),
+	}
+	if limitToUpdate.IsActive != nil {
+		updateData["is_active"] = *limitToUpdate.IsActive
+	}
+	if err := service.repository.UpdateWithMap(uow, &exposure.ExposureLimit{}, updateData, repository.Filter("id = ?", limitToUpdate.ID)); err != nil {
+		return errors.NewDatabaseError("Unable to update exposure limit")
+	}
+
+	if err := service.repository.GetRecordByID(uow, limitToUpdate.ID, limitToUpdate); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *ExposureService) DeleteLimit(limitToDelete *exposure.ExposureLimit, scope security.BankScope) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	existingLimit := exposure.ExposureLimit{}
+	if err := service.repository.GetRecordByID(uow, limitToDelete.ID, &existingLimit); err != nil {
+		return errors.NewNotFoundError("Exposure limit not found with given Id")
+	}
+	if err := scope.Check(existingLimit.BankID); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &exposure.ExposureLimit{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": limitToDelete.DeletedBy,
+	}, repository.Filter("id = ?", limitToDelete.ID)); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetPosition computes the gross and net unsettled positions of a bank against every
+// counterparty it has exchanged transfers with. A positive net position means the bank owes.
+func (service *ExposureService) GetPosition(bankID uuid.UUID, positions *[]exposure.BankPosition) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.doBanksExist(uow, bankID); err != nil {
+		return err
+	}
+
+	unsettled := []banktransaction.BankTransaction{}
+	if err := service.repository.GetAll(uow, &unsettled,
+		repository.Filter("is_settled = ? AND (sender_bank_id = ? OR receiver_bank_id = ?)", false, bankID, bankID)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch bank transaction entries")
+	}
+
+	byCounterparty := make(map[uuid.UUID]*exposure.BankPosition)
+	order := []uuid.UUID{}
+	positionFor := func(counterpartyID uuid.UUID) *exposure.BankPosition {
+		if _, exists := byCounterparty[counterpartyID]; !exists {
+			byCounterparty[counterpartyID] = &exposure.BankPosition{CounterpartyBankID: counterpartyID}
+			order = append(order, counterpartyID)
+		}
+		return byCounterparty[counterpartyID]
+	}
+
+	for _, tx := range unsettled {
+		if tx.SenderBankID == bankID {
+			positionFor(tx.ReceiverBankID).GrossOutgoing += tx.Amount
+		} else {
+			positionFor(tx.SenderBankID).GrossIncoming += tx.Amount
+		}
+	}
+
+	limits := []exposure.ExposureLimit{}
+	if err := service.repository.GetAll(uow, &limits, repository.Filter("bank_id = ? AND is_active = ?", bankID, true)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch exposure limits")
+	}
+	for _, limit := range limits {
+		debitCap := limit.DebitCap
+		positionFor(limit.CounterpartyBankID).DebitCap = &debitCap
+	}
+
+	result := []exposure.BankPosition{}
+	for _, counterpartyID := range order {
+		position := byCounterparty[counterpartyID]
+		position.NetPosition = position.GrossOutgoing - position.GrossIncoming
+
+		counterparty := bank.Bank{}
+		if err := service.repository.GetRecordByID(uow, counterpartyID, &counterparty); err == nil {
+			position.CounterpartyBankName = counterparty.FullName
+		}
+		result = append(result, *position)
+	}
+
+	*positions = result
+	uow.Commit()
+	return nil
+}
+
+// CheckTransfer verifies that moving amount from senderBankID to receiverBankID keeps the
+// sender within its exposure limit. It returns queue=true when the limit asks for breaching
+// transfers to be held, and a validation error when they must be rejected. Warning alerts
+// share the transfer's unit of work so they are kept only if the transfer goes through.
+// The limit row stays locked until that unit of work ends, so concurrent transfers between
+// the same banks are checked one after the other against the exposure the previous one left.
+func (service *ExposureService) CheckTransfer(uow *repository.UnitOfWork, senderBankID, receiverBankID uuid.UUID, amount float32) (bool, error) {
+
+	if senderBankID == receiverBankID {
+		return false, nil
+	}
+
+	limit := exposure.ExposureLimit{}
+	err := service.repository.GetRecord(uow, &limit,
+		repository.Filter("bank_id = ? AND counterparty_bank_id = ? AND is_active = ?", senderBankID, receiverBankID, true),
+		repository.ForUpdate())
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return false, nil
+		}
+		return false, errors.NewDatabaseError("Unable to fetch exposure limit")
+	}
+
+	currentExposure, err := service.netExposure(uow, senderBankID, receiverBankID)
+	if err != nil {
+		return false, err
+	}
+	projectedExposure := currentExposure + amount
+
+	if projectedExposure > limit.DebitCap {
+		// Breach alerts are written outside the transfer so they survive its rollback.
+		alertUOW := repository.NewUnitOfWork(service.db, false)
+		service.raiseAlert(alertUOW, &limit, exposure.AlertLevelBreach, projectedExposure)
+		alertUOW.Commit()
+		if limit.BreachAction == exposure.BreachActionQueue {
+			return true, nil
+		}
+		return false, errors.NewValidationError(fmt.Sprintf("Transfer would exceed the inter-bank exposure limit of %0.2f", limit.DebitCap))
+	}
+
+	warningLevel := limit.DebitCap * limit.AlertThreshold / 100
+	if currentExposure < warningLevel && projectedExposure >= warningLevel {
+		service.raiseAlert(uow, &limit, exposure.AlertLevelWarning, projectedExposure)
+	}
+
+	// The exposure is within the cap again, and possibly below the warning level.
+	resolvedLevels := []string{exposure.AlertLevelBreach}
+	if projectedExposure < warningLevel {
+		resolvedLevels = append(resolvedLevels, exposure.AlertLevelWarning)
+	}
+	service.resolveAlerts(uow, &limit, resolvedLevels...)
+
+	return false, nil
+}
+
+func (service *ExposureService) GetAlerts(allAlerts *[]exposure.LiquidityAlert, bankID uuid.UUID, scope security.BankScope, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{scope.Filter("bank_id")}
+	if bankID != uuid.Nil {
+		filters = append(filters, repository.Filter("bank_id = ?", bankID))
+	}
+
+	err := service.repository.GetAll(uow, allAlerts, append(filters, repository.OrderBy("created_at DESC"), repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allAlerts, totalCount, filters...)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+// netExposure is the amount senderBankID currently owes receiverBankID in unsettled transfers.
+func (service *ExposureService) netExposure(uow *repository.UnitOfWork, senderBankID, receiverBankID uuid.UUID) (float32, error) {
+
+	unsettled := []banktransaction.BankTransaction{}
+	err := service.repository.GetAll(uow, &unsettled,
+		repository.Filter("is_settled = ? AND ((sender_bank_id = ? AND receiver_bank_id = ?) OR (sender_bank_id = ? AND receiver_bank_id = ?))",
+			false, senderBankID, receiverBankID, receiverBankID, senderBankID))
+	if err != nil {
+		return 0, errors.NewDatabaseError("Unable to fetch bank transaction entries")
+	}
+
+	var net float32
+	for _, tx := range unsettled {
+		if tx.SenderBankID == senderBankID {
+			net += tx.Amount
+		} else {
+			net -= tx.Amount
+		}
+	}
+	return net, nil
+}
+
+// raiseAlert records an alert of level for the limit's bank pair, unless one is still open.
+// Transfers held back by a limit are retried until it allows them, and each retry would
+// otherwise raise the same breach again.
+func (service *ExposureService) raiseAlert(uow *repository.UnitOfWork, limit *exposure.ExposureLimit, level string, projectedExposure float32) {
+
+	openAlerts := 0
+	if err := service.repository.GetCount(uow, &exposure.LiquidityAlert{}, &openAlerts,
+		repository.Filter("bank_id = ? AND counterparty_bank_id = ? AND level = ? AND resolved_at IS NULL",
+			limit.BankID, limit.CounterpartyBankID, level)); err != nil {
+		log.GetLogger().Error("Failed to check open liquidity alerts: ", err.Error())
+		return
+	}
+	if openAlerts > 0 {
+		return
+	}
+
+	alert := exposure.LiquidityAlert{
+		TimeStamp:          time.Now(),
+		BankID:             limit.BankID,
+		CounterpartyBankID: limit.CounterpartyBankID,
+		Level:              level,
+		NetExposure:        projectedExposure,
+		DebitCap:           limit.DebitCap,
+		Message: fmt.Sprintf("Net exposure %0.2f against cap %0.2f (%s)",
+			projectedExposure, limit.DebitCap, level),
+	}
+
+	log.GetLogger().Warn("Liquidity alert for bank ", limit.BankID, ": ", alert.Message)
+
+	if err := service.repository.Add(uow, &alert); err != nil {
+		log.GetLogger().Error("Failed to record liquidity alert: ", err.Error())
+	}
+}
+
+// resolveAlerts closes the open alerts of the given levels for the limit's bank pair.
+func (service *ExposureService) resolveAlerts(uow *repository.UnitOfWork, limit *exposure.ExposureLimit, levels ...string) {
+
+	err := service.repository.UpdateWithMap(uow, &exposure.LiquidityAlert{}, map[string]interface{}{
+		"resolved_at": time.Now(),
+	}, repository.Filter("bank_id = ? AND counterparty_bank_id = ? AND level IN (?) AND resolved_at IS NULL",
+		limit.BankID, limit.CounterpartyBankID, levels))
+	if err != nil {
+		log.GetLogger().Error("Failed to resolve liquidity alerts: ", err.Error())
+	}
+}
+
+func (service *ExposureService) doBanksExist(uow *repository.UnitOfWork, bankIDs ...uuid.UUID) error {
+	for _, bankID := range bankIDs {
+		exists, err := repository.DoesRecordExistForUser(uow.DB, bankID, bank.Bank{}, repository.Filter("`id` = ?", bankID))
+		if !exists || err != nil {
+			return errors.NewNotFoundError("Bank not found with given Id")
+		}
+	}
+	return nil
+}
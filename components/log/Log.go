@@ -11,7 +11,13 @@ type Logger interface {
 	Fatalf(format string, args ...interface{})
 }
 
-var logger = logrus.New()
+var logger = newLogger()
+
+func newLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetFormatter(redactingFormatter{Formatter: logger.Formatter})
+	return logger
+}
 
 func GetLogger() Logger {
 	return logger
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"banking-app-be/components/redact"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+// redactingFormatter masks personal data and secrets in the message and fields of every entry
+// before it is written, whoever logged it.
+type redactingFormatter struct {
+	logrus.Formatter
+}
+
+func (formatter redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	entry.Message = redact.Text(entry.Message)
+	for key, value := range entry.Data {
+		switch value.(type) {
+		case string, error, fmt.Stringer:
+			entry.Data[key] = redact.Text(fmt.Sprint(value))
+		}
+	}
+	return formatter.Formatter.Format(entry)
+}
+
+var terminalColors = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// sqlLogger writes the statements gorm logs, with their values, through the logger so they are
+// redacted like everything else.
+type sqlLogger struct{}
+
+// SQLLogger is the logger to give gorm in place of its own, which writes values as they are.
+func SQLLogger() gorm.Logger {
+	return gorm.Logger{LogWriter: sqlLogger{}}
+}
+
+func (sqlLogger) Println(values ...interface{}) {
+	message := terminalColors.ReplaceAllString(fmt.Sprint(values...), "")
+	logger.Info(strings.Join(strings.Fields(message), " "))
+}
@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a notice for a single recipient, such as a password reset link.
+type Message struct {
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	SentAt  time.Time `json:"sentAt"`
+}
+
+// Notifier delivers messages to users. Deployments plug in a mail or SMS gateway; locally
+// messages go to the log or to a file.
+type Notifier interface {
+	Notify(message Message) error
+}
+
+// NewNotifier returns the notifier NOTIFIER names, "log" or "file".
+func NewNotifier(log log.Logger) Notifier {
+	switch config.Notifier.GetStringValue() {
+	case "file":
+		path := config.NotifierFilePath.GetStringValue()
+		if path == "" {
+			path = "notifications.log"
+		}
+		return NewFileNotifier(path)
+	default:
+		return NewLogNotifier(log)
+	}
+}
+
+// LogNotifier writes messages to the application log. The log masks the tokens and addresses
+// in them like everything else it writes, so use the file notifier to read them.
+type LogNotifier struct {
+	log log.Logger
+}
+
+func NewLogNotifier(log log.Logger) *LogNotifier {
+	return &LogNotifier{log: log}
+}
+
+func (notifier *LogNotifier) Notify(message Message) error {
+	notifier.log.Printf("Notification to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// FileNotifier appends messages to a file, one JSON object per line.
+type FileNotifier struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (notifier *FileNotifier) Notify(message Message) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	file, err := os.OpenFile(notifier.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
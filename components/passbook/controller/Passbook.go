@@ -7,11 +7,12 @@ import (
 	"banking-app-be/components/security"
 	"banking-app-be/components/web"
 	"banking-app-be/model/passbook"
-	"fmt"
+	"banking-app-be/model/role"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
 )
 
 type PassbookController struct {
@@ -34,10 +35,11 @@ func (Controller *PassbookController) RegisterRoutes(router *mux.Router) {
 	commonRouter := accountRouter.PathPrefix("/").Subrouter()
 
 	//Get
-	guardedRouter.HandleFunc("/", Controller.getPassbookByAccountNo).Methods(http.MethodPost)
-	commonRouter.HandleFunc("/{accountId}", Controller.getPassbookByAccountId).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/", security.AuthorizeAny(Controller.getPassbookByAccountNo, role.PassbookReadOwn, role.PassbookReadAll)).Methods(http.MethodPost)
+	commonRouter.HandleFunc("/{accountId}", security.AuthorizeAccount(Controller.getPassbookByAccountId, "accountId", role.PassbookReadOwn, role.PassbookReadAll)).Methods(http.MethodGet)
 
-	guardedRouter.Use(security.MiddlewareUser)
+	guardedRouter.Use(security.MiddlewareActive)
+	commonRouter.Use(security.MiddlewareActive)
 }
 
 func (controller *PassbookController) getPassbookByAccountNo(w http.ResponseWriter, r *http.Request) {
@@ -70,18 +72,29 @@ func (controller *PassbookController) getPassbookByAccountNo(w http.ResponseWrit
 		offset = 0 //default
 	}
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	principal, err := security.CurrentPrincipal(r)
 	if err != nil {
-		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		web.RespondError(w, err)
 		return
 	}
 
-	err = controller.PassbookService.GetPassbookByAccountNo(&passbook, userID, &requestData.AccountNo, &totalCount, limit, offset)
+	// Holders only reach their own accounts; staff reach any account of the banks in their scope.
+	holderID := principal.UserID
+	if principal.HasPermission(role.PassbookReadAll) {
+		holderID = uuid.Nil
+	}
+
+	err = controller.PassbookService.GetPassbookByAccountNo(&passbook, holderID, principal.BankScope, &requestData.AccountNo, &totalCount, limit, offset)
 	if err != nil {
 		web.RespondError(w, err)
 		return
 	}
 
+	if web.MaskAccountNumbers(r) {
+		for i := range passbook {
+			passbook[i].MaskAccountNumbers()
+		}
+	}
 	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, passbook)
 
 }
@@ -112,20 +125,23 @@ func (controller *PassbookController) getPassbookByAccountId(w http.ResponseWrit
 		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
 		return
 	}
-	fmt.Println("Account Id ===================>", accountId)
 
-	userID, err := security.ExtractUserIDFromToken(r)
+	holderID, err := security.CurrentAccountHolder(r)
 	if err != nil {
-		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		web.RespondError(w, err)
 		return
 	}
-	fmt.Println("User Id ======================>", userID)
 
-	err = controller.PassbookService.GetPassbookByAccountId(&passbook, userID, accountId, &totalCount, limit, offset)
+	err = controller.PassbookService.GetPassbookByAccountId(&passbook, holderID, accountId, &totalCount, limit, offset)
 	if err != nil {
 		web.RespondError(w, err)
 		return
 	}
 
+	if web.MaskAccountNumbers(r) {
+		for i := range passbook {
+			passbook[i].MaskAccountNumbers()
+		}
+	}
 	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, passbook)
 }
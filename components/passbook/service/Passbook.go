@@ -2,11 +2,13 @@ package service
 
 import (
 	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
 	"banking-app-be/model/account"
 	"banking-app-be/model/bank"
 	"banking-app-be/model/passbook"
 	"banking-app-be/model/user"
 	"banking-app-be/module/repository"
+	"net/http"
 
 	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
@@ -24,22 +26,32 @@ func NewPassbookService(DB *gorm.DB, repo repository.Repository) *PassbookServic
 	}
 }
 
-func (service *PassbookService) GetPassbookByAccountNo(passbook *[]passbook.Transaction, userId uuid.UUID, accountNo *string, totalCount *int, limit, offset int) error {
+// GetPassbookByAccountNo lists the transactions of the account numbered accountNo. A nil holderID
+// accepts any holder among the banks of scope; otherwise the account must belong to holderID.
+func (service *PassbookService) GetPassbookByAccountNo(passbook *[]passbook.Transaction, holderID uuid.UUID, scope security.BankScope, accountNo *string, totalCount *int, limit, offset int) error {
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	accountOwner := user.User{}
-	if err := service.repository.GetRecordByID(uow, userId, &accountOwner); err != nil {
-		return errors.NewDatabaseError("user not found")
+	accountFilters := []repository.QueryProcessor{
+		repository.Filter("account_no = ?", accountNo),
+		scope.Filter("bank_id"),
 	}
-	if accountOwner.IsActive != nil && !*accountOwner.IsActive {
-		return errors.NewInActiveUserError("can not get the passbook records for InActive user")
+	if holderID != uuid.Nil {
+		accountFilters = append(accountFilters, repository.Filter("user_id = ?", holderID))
 	}
 
 	userAccount := account.Account{}
-	if err := service.repository.GetRecord(uow, &userAccount, repository.Filter("account_no = ?", accountNo)); err != nil {
+	if err := service.repository.GetRecord(uow, &userAccount, accountFilters...); err != nil {
 		return errors.NewValidationError("Record not found for given Account number")
 	}
+
+	accountOwner := user.User{}
+	if err := service.repository.GetRecordByID(uow, userAccount.UserID, &accountOwner); err != nil {
+		return errors.NewDatabaseError("user not found")
+	}
+	if accountOwner.IsActive != nil && !*accountOwner.IsActive {
+		return errors.NewInActiveUserError("can not get the passbook records for InActive user")
+	}
 	if userAccount.IsActive != nil && !*userAccount.IsActive {
 		return errors.NewInActiveUserError("can not get the passbook records for InActive Account")
 	}
@@ -70,13 +82,14 @@ func (service *PassbookService) GetPassbookByAccountNo(passbook *[]passbook.Tran
 	return nil
 }
 
-func (service *PassbookService) GetPassbookByAccountId(passbook *[]passbook.Transaction, userId, accountId uuid.UUID, totalCount *int, limit, offset int) error {
+// GetPassbookByAccountId lists the transactions of an account held by holderID.
+func (service *PassbookService) GetPassbookByAccountId(passbook *[]passbook.Transaction, holderID, accountId uuid.UUID, totalCount *int, limit, offset int) error {
 
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
 	accountOwner := user.User{}
-	if err := service.repository.GetRecordByID(uow, userId, &accountOwner); err != nil {
+	if err := service.repository.GetRecordByID(uow, holderID, &accountOwner); err != nil {
 		return errors.NewDatabaseError("user not found")
 	}
 	if accountOwner.IsActive != nil && !*accountOwner.IsActive {
@@ -84,8 +97,8 @@ func (service *PassbookService) GetPassbookByAccountId(passbook *[]passbook.Tran
 	}
 
 	userAccount := account.Account{}
-	if err := service.repository.GetRecordByID(uow, accountId, &userAccount); err != nil {
-		return errors.NewNotFoundError("Unable fetch account details")
+	if err := service.repository.GetRecord(uow, &userAccount, repository.Filter("id = ? AND user_id = ?", accountId, holderID)); err != nil {
+		return errors.NewHTTPError("Account not found with given Id", http.StatusNotFound)
 	}
 	if userAccount.IsActive != nil && !*userAccount.IsActive {
 		return errors.NewInActiveUserError("can not get the passbook records for InActive Account")
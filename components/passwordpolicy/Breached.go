@@ -0,0 +1,57 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength is how many hex digits of the SHA-1 name a list file, as in the k-anonymity
+// range API of breach-notification services.
+const prefixLength = 5
+
+// IsBreached looks password up in the breached-password list kept in dir. The list is split
+// into one file per SHA-1 prefix, named after the prefix with or without ".txt", with a
+// "SUFFIX:COUNT" line for every breached hash starting with it. Only the matching prefix file
+// is read, and prefixes without a file have no breached passwords.
+func IsBreached(dir, password string) (bool, error) {
+
+	if _, err := os.Stat(dir); err != nil {
+		return false, err
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		found, err := containsSuffix(file, suffix)
+		file.Close()
+		return found, err
+	}
+	return false, nil
+}
+
+func containsSuffix(file *os.File, suffix string) (bool, error) {
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if colon := strings.Index(line, ":"); colon >= 0 {
+			line = line[:colon]
+		}
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
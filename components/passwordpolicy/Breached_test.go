@@ -0,0 +1,69 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsBreached(t *testing.T) {
+
+	// The SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+	const suffix = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		password string
+		breached bool
+	}{
+		{name: "listed", files: map[string]string{"5BAA6": "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + suffix + ":3861493\n"}, password: "password", breached: true},
+		{name: "listed in a .txt file", files: map[string]string{"5BAA6.txt": suffix + ":3861493\n"}, password: "password", breached: true},
+		{name: "listed in lowercase without a count", files: map[string]string{"5BAA6": strings.ToLower(suffix) + "\n"}, password: "password", breached: true},
+		{name: "prefix file without the suffix", files: map[string]string{"5BAA6": "0018A45C4D1DEF81644B54AB7F969B88D65:1\n"}, password: "password"},
+		{name: "no prefix file", files: map[string]string{"00000": suffix + ":1\n"}, password: "password"},
+		{name: "other password", files: map[string]string{"5BAA6": suffix + ":3861493\n"}, password: "Password"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range test.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			breached, err := IsBreached(dir, test.password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if breached != test.breached {
+				t.Errorf("got breached %v, want %v", breached, test.breached)
+			}
+		})
+	}
+}
+
+func TestIsBreachedWithoutDir(t *testing.T) {
+	if _, err := IsBreached(filepath.Join(t.TempDir(), "missing"), "password"); err == nil {
+		t.Error("got no error for a missing directory")
+	}
+}
+
+func TestCheckBreached(t *testing.T) {
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6"), []byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy := Policy{MinLength: 8, BreachedPasswordsDir: dir}
+
+	if err := policy.Check("password"); err == nil || !strings.Contains(err.Error(), "data breach") {
+		t.Errorf("got error %v, want a breached password error", err)
+	}
+	if err := policy.Check("correct horse battery"); err != nil {
+		t.Errorf("got error %v, want none", err)
+	}
+}
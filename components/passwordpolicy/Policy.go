@@ -0,0 +1,116 @@
+package passwordpolicy
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// minPersonalInfoLength keeps very short names and email parts from ruling out passwords.
+const minPersonalInfoLength = 3
+
+// Policy is the set of rules new passwords have to follow.
+type Policy struct {
+	MinLength            int   `json:"minLength"`
+	RequireUpper         bool  `json:"requireUpper"`
+	RequireLower         bool  `json:"requireLower"`
+	RequireDigit         bool  `json:"requireDigit"`
+	RequireSymbol        bool  `json:"requireSymbol"`
+	MaxAgeDays           int64 `json:"maxAgeDays"`
+	DisallowPersonalInfo bool  `json:"disallowPersonalInfo"`
+	// BreachedPasswordsDir holds the breached-password list; see IsBreached. Empty turns the
+	// check off.
+	BreachedPasswordsDir string `json:"-"`
+}
+
+// Current returns the policy as configured.
+func Current() Policy {
+	return Policy{
+		MinLength:            int(config.PasswordMinLength.GetInt64ValueOrDefault(8)),
+		RequireUpper:         config.PasswordRequireUpper.GetBoolValueOrDefault(false),
+		RequireLower:         config.PasswordRequireLower.GetBoolValueOrDefault(false),
+		RequireDigit:         config.PasswordRequireDigit.GetBoolValueOrDefault(false),
+		RequireSymbol:        config.PasswordRequireSymbol.GetBoolValueOrDefault(false),
+		MaxAgeDays:           config.PasswordMaxAgeDays.GetInt64ValueOrDefault(0),
+		DisallowPersonalInfo: config.PasswordDisallowPersonalInfo.GetBoolValueOrDefault(false),
+		BreachedPasswordsDir: config.BreachedPasswordsDir.GetStringValue(),
+	}
+}
+
+// Check validates password against the policy. personalInfo holds the user's email and names,
+// which the password may not contain.
+func (policy Policy) Check(password string, personalInfo ...string) error {
+
+	if len(password) < policy.MinLength {
+		return errors.NewValidationError(fmt.Sprintf("Password should consist of %d or more characters", policy.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	switch {
+	case policy.RequireUpper && !hasUpper:
+		return errors.NewValidationError("Password should contain an uppercase letter")
+	case policy.RequireLower && !hasLower:
+		return errors.NewValidationError("Password should contain a lowercase letter")
+	case policy.RequireDigit && !hasDigit:
+		return errors.NewValidationError("Password should contain a digit")
+	case policy.RequireSymbol && !hasSymbol:
+		return errors.NewValidationError("Password should contain a symbol")
+	}
+
+	if policy.DisallowPersonalInfo {
+		lowered := strings.ToLower(password)
+		for _, info := range personalTerms(personalInfo) {
+			if strings.Contains(lowered, info) {
+				return errors.NewValidationError("Password should not contain your name or email")
+			}
+		}
+	}
+
+	if policy.BreachedPasswordsDir != "" {
+		breached, err := IsBreached(policy.BreachedPasswordsDir, password)
+		if err != nil {
+			return errors.NewHTTPError("Unable to check password against breached passwords", http.StatusInternalServerError)
+		}
+		if breached {
+			return errors.NewValidationError("Password has appeared in a data breach, choose a different one")
+		}
+	}
+	return nil
+}
+
+// IsExpired reports whether a password set at changedAt has to be replaced at now. A zero
+// MaxAgeDays lets passwords live forever.
+func (policy Policy) IsExpired(changedAt, now time.Time) bool {
+	return policy.MaxAgeDays > 0 && now.Sub(changedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour
+}
+
+// personalTerms lowercases personalInfo, keeping only the part before the @ of emails.
+func personalTerms(personalInfo []string) []string {
+	terms := []string{}
+	for _, info := range personalInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if at := strings.Index(info, "@"); at >= 0 {
+			info = info[:at]
+		}
+		if len(info) >= minPersonalInfoLength {
+			terms = append(terms, info)
+		}
+	}
+	return terms
+}
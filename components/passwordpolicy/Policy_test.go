@@ -0,0 +1,62 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+
+	strict := Policy{MinLength: 8, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+	personal := Policy{MinLength: 8, DisallowPersonalInfo: true}
+	personalInfo := []string{"Jo.Smith@Example.com", "Jo", "Smithers"}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		password string
+		info     []string
+		err      string
+	}{
+		{name: "every class", policy: strict, password: "Passw0rd!"},
+		{name: "too short", policy: strict, password: "Pa0!", err: "8 or more characters"},
+		{name: "no uppercase", policy: strict, password: "passw0rd!", err: "uppercase"},
+		{name: "no lowercase", policy: strict, password: "PASSW0RD!", err: "lowercase"},
+		{name: "no digit", policy: strict, password: "Password!", err: "digit"},
+		{name: "no symbol", policy: strict, password: "Passw0rdd", err: "symbol"},
+		{name: "space counts as a symbol", policy: strict, password: "Passw0rd x"},
+		{name: "non-ASCII letters", policy: strict, password: "Ünïcödé9!"},
+		{name: "classes not required", policy: Policy{MinLength: 8}, password: "aaaaaaaa"},
+		{name: "email local part", policy: personal, password: "xxjo.smithxx", info: personalInfo, err: "name or email"},
+		{name: "email local part in another case", policy: personal, password: "XXJO.SMITHXX", info: personalInfo, err: "name or email"},
+		{name: "email domain", policy: personal, password: "example.com1", info: personalInfo},
+		{name: "last name", policy: personal, password: "mr-smithers-1", info: personalInfo, err: "name or email"},
+		{name: "name shorter than the minimum", policy: personal, password: "jojojojo", info: personalInfo},
+		{name: "personal info allowed", policy: Policy{MinLength: 8}, password: "smithers-1", info: personalInfo},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Check(test.password, test.info...)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestPersonalTerms(t *testing.T) {
+
+	got := personalTerms([]string{" Jo.Smith@Example.com ", "Al", "Bob", "al@example.com", ""})
+	want := []string{"jo.smith", "bob"}
+
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/payment"
+	"banking-app-be/model/role"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	paymentService "banking-app-be/components/payment/service"
+
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+type PaymentController struct {
+	log            log.Logger
+	PaymentService *paymentService.PaymentService
+}
+
+func NewPaymentController(paymentService *paymentService.PaymentService, log log.Logger) *PaymentController {
+	return &PaymentController{
+		log:            log,
+		PaymentService: paymentService,
+	}
+}
+
+func (Controller *PaymentController) RegisterRoutes(router *mux.Router) {
+
+	paymentRouter := router.PathPrefix("/payment").Subrouter()
+	adminRouter := paymentRouter.PathPrefix("/").Subrouter()
+	guardedRouter := paymentRouter.PathPrefix("/").Subrouter()
+
+	//Admin
+	adminRouter.HandleFunc("/all", security.Authorize(Controller.getAllPayments, role.PaymentReadAll)).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/process", security.Authorize(Controller.processDuePayments, role.PaymentProcess)).Methods(http.MethodPost)
+	adminRouter.Use(security.MiddlewareActive)
+
+	//===========================
+
+	//Get
+	guardedRouter.HandleFunc("/", security.Authorize(Controller.getUserPayments, role.PaymentReadOwn)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}", security.Authorize(Controller.getPaymentById, role.PaymentReadOwn)).Methods(http.MethodGet)
+	guardedRouter.Use(security.MiddlewareActive)
+}
+
+func (controller *PaymentController) getUserPayments(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		return
+	}
+
+	controller.listPayments(w, r, userID)
+}
+
+func (controller *PaymentController) getAllPayments(w http.ResponseWriter, r *http.Request) {
+	controller.listPayments(w, r, uuid.Nil)
+}
+
+func (controller *PaymentController) getPaymentById(w http.ResponseWriter, r *http.Request) {
+
+	targetPayment := payment.Payment{}
+	parser := web.NewParser(r)
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("Unauthorized", http.StatusUnauthorized))
+		return
+	}
+
+	targetPayment.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid payment ID format"))
+		return
+	}
+
+	err = controller.PaymentService.GetPaymentByID(userID, &targetPayment)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, targetPayment)
+}
+
+func (controller *PaymentController) processDuePayments(w http.ResponseWriter, r *http.Request) {
+
+	// Due payments of every bank are processed together.
+	if err := security.CurrentBankScope(r).CheckGlobal(); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	processed, err := controller.PaymentService.ProcessDue(r.Context(), time.Now())
+	if err != nil {
+		controller.log.Error("Failed to process due payments: " + err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(processed), processed)
+}
+
+func (controller *PaymentController) listPayments(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+
+	allPayments := []payment.Payment{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	status := strings.ToUpper(query.Get("status"))
+
+	err = controller.PaymentService.GetPayments(userID, security.CurrentBankScope(r), status, &allPayments, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if web.MaskAccountNumbers(r) {
+		for i := range allPayments {
+			allPayments[i].MaskAccountNumbers()
+		}
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allPayments)
+}
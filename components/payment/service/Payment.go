@@ -0,0 +1,456 @@
+package service
+
+import (
+	accountService "banking-app-be/components/account/service"
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/account"
+	"banking-app-be/model/payment"
+	"banking-app-be/module/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type PaymentService struct {
+	sync.Mutex
+	db             *gorm.DB
+	repository     repository.Repository
+	accountService *accountService.AccountService
+	schedule       *railSchedule
+}
+
+// NewPaymentService schedules the batch and high-value rails of each payment against the
+// calendar of the bank it is sent from.
+func NewPaymentService(DB *gorm.DB, repo repository.Repository, accountService *accountService.AccountService, calendar BusinessCalendar) *PaymentService {
+	return &PaymentService{
+		db:             DB,
+		repository:     repo,
+		accountService: accountService,
+		schedule:       newRailSchedule(calendar),
+	}
+}
+
+// Initiate validates the payment, picks its rail when none was requested and either executes
+// it straight away or schedules it for the rail's next operating window. Amounts above the
+// step-up threshold are only accepted with a valid proof.
+func (service *PaymentService) Initiate(ctx context.Context, newPayment *payment.Payment, proof security.StepUp) error {
+
+	if err := newPayment.Validate(); err != nil {
+		return err
+	}
+	if err := security.RequireStepUp(ctx, newPayment.UserID, newPayment.Amount, proof); err != nil {
+		return err
+	}
+	if err := service.selectRail(newPayment); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	fromAccount := account.Account{}
+	if err := service.repository.GetRecord(uow, &fromAccount,
+		repository.Filter("id = ? AND user_id = ?", newPayment.FromAccountID, newPayment.UserID)); err != nil {
+		return errors.NewNotFoundError("Account not found with given Id for Current User")
+	}
+
+	now := time.Now()
+	newPayment.Status = payment.StatusInitiated
+	newPayment.CreatedBy = newPayment.UserID
+	if err := service.repository.Add(uow, newPayment); err != nil {
+		return errors.NewDatabaseError("Failed to record payment")
+	}
+	if err := service.recordEvent(uow, newPayment, "", "Payment initiated on "+newPayment.Rail+" rail"); err != nil {
+		return err
+	}
+
+	executeNow := false
+	switch newPayment.Rail {
+	case payment.RailInstant:
+		executeNow = true
+	case payment.RailBatch:
+		window, err := service.schedule.nextBatchWindow(fromAccount.BankID, now)
+		if err != nil {
+			return err
+		}
+		newPayment.ScheduledFor = &window
+		if err := service.transition(uow, newPayment, payment.StatusPendingBatch,
+			fmt.Sprintf("Queued for batch window %s", window.Format(time.RFC3339))); err != nil {
+			return err
+		}
+	case payment.RailHighValue:
+		isOpen, err := service.schedule.isHighValueOpen(fromAccount.BankID, now)
+		if err != nil {
+			return err
+		}
+		if isOpen {
+			executeNow = true
+		} else {
+			opening, err := service.schedule.nextHighValueOpening(fromAccount.BankID, now)
+			if err != nil {
+				return err
+			}
+			newPayment.ScheduledFor = &opening
+			if err := service.transition(uow, newPayment, payment.StatusScheduled,
+				fmt.Sprintf("High-value rail closed, scheduled for %s", opening.Format(time.RFC3339))); err != nil {
+				return err
+			}
+		}
+	}
+
+	uow.Commit()
+
+	if executeNow {
+		if err := service.execute(ctx, newPayment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessDue executes every payment whose window has arrived and retries payments held
+// back by exposure limits, once their backoff has passed, while their rail is operating.
+func (service *PaymentService) ProcessDue(ctx context.Context, now time.Time) ([]payment.Payment, error) {
+
+	service.Lock()
+	defer service.Unlock()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	duePayments := []payment.Payment{}
+	err := service.repository.GetAll(uow, &duePayments,
+		repository.Filter("(status IN (?) AND scheduled_for <= ?) OR (status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?))",
+			[]string{payment.StatusPendingBatch, payment.StatusScheduled}, now, payment.StatusQueued, now),
+		repository.OrderBy("scheduled_for ASC, created_at ASC"))
+	uow.RollBack()
+	if err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch due payments")
+	}
+
+	return service.executeAll(ctx, duePayments, now)
+}
+
+// ReleaseQueued retries the payments held back by exposure limits right away, in the order
+// they were queued, whatever their backoff. Payments that still breach their limit stay queued.
+func (service *PaymentService) ReleaseQueued(ctx context.Context, now time.Time) ([]payment.Payment, error) {
+
+	service.Lock()
+	defer service.Unlock()
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	queuedPayments := []payment.Payment{}
+	err := service.repository.GetAll(uow, &queuedPayments,
+		repository.Filter("status = ?", payment.StatusQueued), repository.OrderBy("created_at ASC"))
+	uow.RollBack()
+	if err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch queued payments")
+	}
+
+	return service.executeAll(ctx, queuedPayments, now)
+}
+
+// GetPayments lists the payments of a user, or of everyone when userID is nil. Bank-scoped
+// staff only see payments drawn on accounts with their banks.
+func (service *PaymentService) GetPayments(userID uuid.UUID, scope security.BankScope, status string, allPayments *[]payment.Payment, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	filters := []repository.QueryProcessor{
+		scope.Where("from_account_id IN (SELECT id FROM accounts WHERE bank_id IN (?))"),
+	}
+	if userID != uuid.Nil {
+		filters = append(filters, repository.Filter("user_id = ?", userID))
+	}
+	if status != "" {
+		filters = append(filters, repository.Filter("status = ?", status))
+	}
+
+	err := service.repository.GetAll(uow, allPayments, append(filters, repository.OrderBy("created_at DESC"), repository.Paginate(limit, offset, totalCount))...)
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allPayments, totalCount, filters...)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *PaymentService) GetPaymentByID(userID uuid.UUID, targetPayment *payment.Payment) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetRecord(uow, targetPayment,
+		repository.Filter("id = ? AND user_id = ?", targetPayment.ID, userID),
+		repository.PreloadAssociations([]string{"Events"}))
+	if err != nil {
+		return errors.NewNotFoundError("Payment not found with given Id")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+// selectRail routes small amounts to the instant rail, large ones to the high-value rail and
+// the rest to batches, and checks an explicitly requested rail accepts the amount.
+func (service *PaymentService) selectRail(newPayment *payment.Payment) error {
+
+	instantMax, highValueMin := railLimits()
+
+	switch newPayment.Rail {
+	case "":
+		if newPayment.Amount >= highValueMin {
+			newPayment.Rail = payment.RailHighValue
+		} else if newPayment.Amount <= instantMax {
+			newPayment.Rail = payment.RailInstant
+		} else {
+			newPayment.Rail = payment.RailBatch
+		}
+	case payment.RailInstant:
+		if newPayment.Amount > instantMax {
+			return errors.NewValidationError(fmt.Sprintf("Instant transfers are limited to %0.2f", instantMax))
+		}
+	case payment.RailHighValue:
+		if newPayment.Amount < highValueMin {
+			return errors.NewValidationError(fmt.Sprintf("High-value transfers require at least %0.2f", highValueMin))
+		}
+	}
+	return nil
+}
+
+// ValidateRailLimits refuses rail limits that leave no amount to the batch rail. The app checks
+// them at startup.
+func ValidateRailLimits() error {
+	instantMax, highValueMin := railLimits()
+	if instantMax >= highValueMin {
+		return fmt.Errorf("%s (%0.2f) must be below %s (%0.2f)",
+			config.InstantMaxAmount, instantMax, config.HighValueMinAmount, highValueMin)
+	}
+	return nil
+}
+
+// railLimits returns the largest instant amount and the smallest high-value amount; the batch
+// rail takes the amounts in between.
+func railLimits() (float32, float32) {
+	return float32(config.InstantMaxAmount.GetInt64ValueOrDefault(100000)),
+		float32(config.HighValueMinAmount.GetInt64ValueOrDefault(200000))
+}
+
+// isRailOperating reports whether the rail runs at now for the bank a payment is sent from.
+func (service *PaymentService) isRailOperating(rail string, bankID uuid.UUID, now time.Time) (bool, error) {
+	switch rail {
+	case payment.RailBatch:
+		return service.schedule.isBatchOpen(bankID, now)
+	case payment.RailHighValue:
+		return service.schedule.isHighValueOpen(bankID, now)
+	}
+	return true, nil
+}
+
+// executeAll executes the payments whose rail is operating at now. A payment that can not be
+// executed is logged and left for the next run, so it does not hold back the others.
+func (service *PaymentService) executeAll(ctx context.Context, duePayments []payment.Payment, now time.Time) ([]payment.Payment, error) {
+
+	senderBanks, err := service.senderBanksOf(duePayments)
+	if err != nil {
+		return nil, err
+	}
+
+	processed := []payment.Payment{}
+	for i := range duePayments {
+		duePayment := &duePayments[i]
+		isOperating, err := service.isRailOperating(duePayment.Rail, senderBanks[duePayment.FromAccountID], now)
+		if err != nil {
+			log.GetLogger().Error("Unable to check the ", duePayment.Rail, " rail for payment ", duePayment.ID, ": ", err.Error())
+			continue
+		}
+		if !isOperating {
+			continue
+		}
+		if err := service.execute(ctx, duePayment); err != nil {
+			log.GetLogger().Error("Failed to execute payment ", duePayment.ID, ": ", err.Error())
+			continue
+		}
+		processed = append(processed, *duePayment)
+	}
+	return processed, nil
+}
+
+// senderBanksOf maps the accounts the payments are sent from to their banks, whose calendars
+// the rails follow.
+func (service *PaymentService) senderBanksOf(payments []payment.Payment) (map[uuid.UUID]uuid.UUID, error) {
+
+	senderBanks := make(map[uuid.UUID]uuid.UUID)
+	if len(payments) == 0 {
+		return senderBanks, nil
+	}
+	accountIDs := make([]uuid.UUID, 0, len(payments))
+	for _, duePayment := range payments {
+		accountIDs = append(accountIDs, duePayment.FromAccountID)
+	}
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	senderAccounts := []account.Account{}
+	if err := service.repository.GetAll(uow, &senderAccounts, repository.Filter("id IN (?)", accountIDs)); err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch sender accounts")
+	}
+	for _, senderAccount := range senderAccounts {
+		senderBanks[senderAccount.ID] = senderAccount.BankID
+	}
+	return senderBanks, nil
+}
+
+// execute moves the money for a payment and records the outcome as its new status. The funds
+// move in the same unit of work that completes the payment, so a payment is never left
+// PROCESSING, and never COMPLETED without its funds or FAILED with them moved.
+func (service *PaymentService) execute(ctx context.Context, duePayment *payment.Payment) error {
+
+	attempt := *duePayment
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.transition(uow, &attempt, payment.StatusProcessing, "Processing on "+attempt.Rail+" rail"); err != nil {
+		return err
+	}
+
+	fromAccount := account.Account{}
+	fromAccount.ID = attempt.FromAccountID
+	fromAccount.UserID = attempt.UserID
+	fromAccount.UpdatedBy = attempt.UserID
+	toAccount := account.Account{AccountNo: attempt.ToAccountNo}
+	toAccount.UpdatedBy = attempt.UserID
+
+	held, transferErr := service.accountService.Transfer(uow, fromAccount, toAccount, attempt.ToBranchCode, attempt.Amount)
+	if transferErr == nil && !held {
+		processedAt := time.Now()
+		attempt.ProcessedAt = &processedAt
+		if err := service.transition(uow, &attempt, payment.StatusCompleted, "Funds transferred"); err != nil {
+			return err
+		}
+		uow.Commit()
+		*duePayment = attempt
+		return nil
+	}
+
+	// Nothing the attempt wrote is kept; it is recorded on its own.
+	uow.RollBack()
+	return service.recordUnsuccessfulAttempt(ctx, duePayment, held, transferErr)
+}
+
+// recordUnsuccessfulAttempt records that a payment was processed but either failed with
+// transferErr or was held back by an exposure limit. Held payments are retried after a delay
+// doubling with every attempt; a payment held again while QUEUED only has its retry postponed.
+func (service *PaymentService) recordUnsuccessfulAttempt(ctx context.Context, duePayment *payment.Payment, held bool, transferErr error) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if held {
+		duePayment.Attempts++
+		nextAttemptAt := time.Now().Add(retryDelay(duePayment.Attempts))
+		duePayment.NextAttemptAt = &nextAttemptAt
+	}
+	if held && duePayment.Status == payment.StatusQueued {
+		if err := service.repository.UpdateWithMap(uow, &payment.Payment{}, map[string]interface{}{
+			"attempts":        duePayment.Attempts,
+			"next_attempt_at": duePayment.NextAttemptAt,
+			"updated_by":      duePayment.UserID,
+			"updated_at":      time.Now(),
+		}, repository.Filter("id = ?", duePayment.ID)); err != nil {
+			return errors.NewDatabaseError("Failed to postpone queued payment")
+		}
+		uow.Commit()
+		return nil
+	}
+
+	if err := service.transition(uow, duePayment, payment.StatusProcessing, "Processing on "+duePayment.Rail+" rail"); err != nil {
+		return err
+	}
+
+	var err error
+	if held {
+		err = service.transition(uow, duePayment, payment.StatusQueued, "Held by inter-bank exposure limit")
+	} else {
+		duePayment.FailureReason = transferErr.Error()
+		err = service.transition(uow, duePayment, payment.StatusFailed, transferErr.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// retryDelay is how long a payment held back attempts times waits before its next attempt.
+func retryDelay(attempts int) time.Duration {
+	base := time.Duration(config.PaymentRetryBaseSeconds.GetInt64ValueOrDefault(60)) * time.Second
+	maxDelay := time.Duration(config.PaymentRetryMaxSeconds.GetInt64ValueOrDefault(3600)) * time.Second
+
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// transition moves the payment to status if its rail allows it and records the change.
+func (service *PaymentService) transition(uow *repository.UnitOfWork, targetPayment *payment.Payment, status, note string) error {
+
+	if !targetPayment.CanTransition(status) {
+		return errors.NewValidationError(fmt.Sprintf("%s payment can not move from %s to %s",
+			targetPayment.Rail, targetPayment.Status, status))
+	}
+
+	fromStatus := targetPayment.Status
+	targetPayment.Status = status
+
+	updateData := map[string]interface{}{
+		"status":          targetPayment.Status,
+		"scheduled_for":   targetPayment.ScheduledFor,
+		"processed_at":    targetPayment.ProcessedAt,
+		"failure_reason":  targetPayment.FailureReason,
+		"attempts":        targetPayment.Attempts,
+		"next_attempt_at": targetPayment.NextAttemptAt,
+		"updated_by":      targetPayment.UserID,
+		"updated_at":      time.Now(),
+	}
+	if err := service.repository.UpdateWithMap(uow, &payment.Payment{}, updateData, repository.Filter("id = ?", targetPayment.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to update payment status")
+	}
+
+	return service.recordEvent(uow, targetPayment, fromStatus, note)
+}
+
+func (service *PaymentService) recordEvent(uow *repository.UnitOfWork, targetPayment *payment.Payment, fromStatus, note string) error {
+	event := payment.PaymentEvent{
+		PaymentID:  targetPayment.ID,
+		TimeStamp:  time.Now(),
+		FromStatus: fromStatus,
+		ToStatus:   targetPayment.Status,
+		Note:       note,
+	}
+	event.CreatedBy = targetPayment.UserID
+	if err := service.repository.Add(uow, &event); err != nil {
+		return errors.NewDatabaseError("Failed to record payment event")
+	}
+	return nil
+}
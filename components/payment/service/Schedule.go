@@ -0,0 +1,97 @@
+package service
+
+import (
+	"banking-app-be/components/config"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// BusinessCalendar tells which days are business days for a bank, its own holidays and the
+// system holidays included. The batch and high-value rails only operate on them.
+type BusinessCalendar interface {
+	IsBusinessDay(bankID *uuid.UUID, day time.Time) (bool, error)
+	NextBusinessDay(bankID *uuid.UUID, day time.Time) (time.Time, error)
+}
+
+// railSchedule holds the cut-off times of the rails that do not run around the clock.
+type railSchedule struct {
+	calendar           BusinessCalendar
+	batchWindow        time.Duration
+	batchStartHour     int
+	batchEndHour       int
+	highValueOpenHour  int
+	highValueCloseHour int
+}
+
+func newRailSchedule(calendar BusinessCalendar) *railSchedule {
+	return &railSchedule{
+		calendar:           calendar,
+		batchWindow:        time.Duration(config.BatchWindowMinutes.GetInt64ValueOrDefault(30)) * time.Minute,
+		batchStartHour:     int(config.BatchStartHour.GetInt64ValueOrDefault(8)),
+		batchEndHour:       int(config.BatchEndHour.GetInt64ValueOrDefault(19)),
+		highValueOpenHour:  int(config.HighValueOpenHour.GetInt64ValueOrDefault(7)),
+		highValueCloseHour: int(config.HighValueCloseHour.GetInt64ValueOrDefault(18)),
+	}
+}
+
+// nextBatchWindow returns the first batch window of the bank strictly after t. Windows run every
+// batchWindow from batchStartHour up to and including batchEndHour on the bank's business days.
+func (schedule *railSchedule) nextBatchWindow(bankID uuid.UUID, t time.Time) (time.Time, error) {
+
+	isBusinessDay, err := schedule.calendar.IsBusinessDay(&bankID, t)
+	if err != nil {
+		return t, err
+	}
+	if isBusinessDay {
+		day := startOfDay(t)
+		lastWindow := day.Add(time.Duration(schedule.batchEndHour) * time.Hour)
+		for window := day.Add(time.Duration(schedule.batchStartHour) * time.Hour); !window.After(lastWindow); window = window.Add(schedule.batchWindow) {
+			if window.After(t) {
+				return window, nil
+			}
+		}
+	}
+
+	next, err := schedule.calendar.NextBusinessDay(&bankID, t)
+	if err != nil {
+		return t, err
+	}
+	return startOfDay(next).Add(time.Duration(schedule.batchStartHour) * time.Hour), nil
+}
+
+func (schedule *railSchedule) isBatchOpen(bankID uuid.UUID, t time.Time) (bool, error) {
+	if t.Hour() < schedule.batchStartHour || t.Hour() > schedule.batchEndHour {
+		return false, nil
+	}
+	return schedule.calendar.IsBusinessDay(&bankID, t)
+}
+
+func (schedule *railSchedule) isHighValueOpen(bankID uuid.UUID, t time.Time) (bool, error) {
+	if t.Hour() < schedule.highValueOpenHour || t.Hour() >= schedule.highValueCloseHour {
+		return false, nil
+	}
+	return schedule.calendar.IsBusinessDay(&bankID, t)
+}
+
+// nextHighValueOpening returns the next time the high-value rail opens for the bank after t.
+func (schedule *railSchedule) nextHighValueOpening(bankID uuid.UUID, t time.Time) (time.Time, error) {
+
+	opening := startOfDay(t).Add(time.Duration(schedule.highValueOpenHour) * time.Hour)
+	if opening.After(t) {
+		isBusinessDay, err := schedule.calendar.IsBusinessDay(&bankID, t)
+		if err != nil || isBusinessDay {
+			return opening, err
+		}
+	}
+
+	next, err := schedule.calendar.NextBusinessDay(&bankID, t)
+	if err != nil {
+		return t, err
+	}
+	return startOfDay(next).Add(time.Duration(schedule.highValueOpenHour) * time.Hour), nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"context"
+	"time"
+)
+
+// PaymentScheduler periodically processes batch windows, scheduled high-value payments
+// and payments held back by exposure limits.
+type PaymentScheduler struct {
+	log      log.Logger
+	service  *PaymentService
+	interval time.Duration
+}
+
+func NewPaymentScheduler(service *PaymentService, log log.Logger) *PaymentScheduler {
+	return &PaymentScheduler{
+		log:      log,
+		service:  service,
+		interval: time.Duration(config.PaymentSchedulerInterval.GetInt64ValueOrDefault(60)) * time.Second,
+	}
+}
+
+func (scheduler *PaymentScheduler) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(scheduler.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			processed, err := scheduler.service.ProcessDue(context.Background(), now)
+			if err != nil {
+				scheduler.log.Error("Payment scheduler run failed: ", err.Error())
+				continue
+			}
+			if len(processed) > 0 {
+				scheduler.log.Info("Payment scheduler processed ", len(processed), " payments")
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package pii
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Personal data is encrypted with one of the keys of the keyfile, a JSON document:
+//
+//	{"active": 2, "keys": {"1": "<base64>", "2": "<base64>"}, "index": "<base64>"}
+//
+// New values are encrypted with the active key; every key listed decrypts the values written
+// with it. The index key computes the blind indexes lookups go through. It is not rotated, as
+// rotating it would mean recomputing every index at once.
+const keySize = 32
+
+// Keyring is the content of the keyfile.
+type Keyring struct {
+	Active int            `json:"active"`
+	Keys   map[int][]byte `json:"-"`
+	Index  []byte         `json:"-"`
+}
+
+type keyfile struct {
+	Active int               `json:"active"`
+	Keys   map[string]string `json:"keys"`
+	Index  string            `json:"index"`
+}
+
+var (
+	keyringMutex sync.RWMutex
+	keyring      *Keyring
+)
+
+// KeyFile returns the configured keyfile path.
+func KeyFile() string {
+	if path := config.PIIKeyFile.GetStringValue(); path != "" {
+		return path
+	}
+	return filepath.Join("keys", "pii.json")
+}
+
+// InitializeKeys loads the keyfile. When there is none and PII_GENERATE_MISSING_KEYFILE is set,
+// one is generated, which is meant for local runs.
+func InitializeKeys() error {
+	path := KeyFile()
+	if _, err := os.Stat(path); os.IsNotExist(err) && config.PIIGenerateMissingKeyfile.GetBoolValueOrDefault(false) {
+		log.GetLogger().Warn("No PII keyfile at ", path, ", generating one")
+		if _, err := RotateKey(path); err != nil {
+			return err
+		}
+	}
+
+	loaded, err := LoadKeyring(path)
+	if err != nil {
+		return err
+	}
+	UseKeyring(loaded)
+	return nil
+}
+
+// UseKeyring makes ring the keys values are encrypted and decrypted with.
+func UseKeyring(ring *Keyring) {
+	keyringMutex.Lock()
+	defer keyringMutex.Unlock()
+	keyring = ring
+}
+
+func currentKeyring() (*Keyring, error) {
+	keyringMutex.RLock()
+	defer keyringMutex.RUnlock()
+	if keyring == nil {
+		return nil, fmt.Errorf("pii: keys are not loaded")
+	}
+	return keyring, nil
+}
+
+// LoadKeyring reads and checks a keyfile.
+func LoadKeyring(path string) (*Keyring, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	stored := keyfile{}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("pii: reading %s: %w", path, err)
+	}
+
+	ring := &Keyring{Active: stored.Active, Keys: map[int][]byte{}}
+	for version, encoded := range stored.Keys {
+		number, err := strconv.Atoi(version)
+		if err != nil || number <= 0 {
+			return nil, fmt.Errorf("pii: key version %q is not a positive number", version)
+		}
+		if ring.Keys[number], err = decodeKey(encoded); err != nil {
+			return nil, fmt.Errorf("pii: key %d: %w", number, err)
+		}
+	}
+	if ring.Index, err = decodeKey(stored.Index); err != nil {
+		return nil, fmt.Errorf("pii: index key: %w", err)
+	}
+	if _, exists := ring.Keys[ring.Active]; !exists {
+		return nil, fmt.Errorf("pii: active key %d is not in %s", ring.Active, path)
+	}
+	return ring, nil
+}
+
+// Versions returns the versions of the keys, oldest first.
+func (ring *Keyring) Versions() []int {
+	versions := []int{}
+	for version := range ring.Keys {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// RotateKey adds a key to the keyfile, creating it if needed, and makes the new key active.
+// Values keep their old key until they are written again or re-encrypted.
+func RotateKey(path string) (int, error) {
+	ring, err := LoadKeyring(path)
+	if os.IsNotExist(err) {
+		ring = &Keyring{Keys: map[int][]byte{}}
+		if ring.Index, err = newKey(); err != nil {
+			return 0, err
+		}
+	} else if err != nil {
+		return 0, err
+	}
+
+	version := 1
+	if versions := ring.Versions(); len(versions) > 0 {
+		version = versions[len(versions)-1] + 1
+	}
+	if ring.Keys[version], err = newKey(); err != nil {
+		return 0, err
+	}
+	ring.Active = version
+	return version, SaveKeyring(path, ring)
+}
+
+// RemoveKey drops a key no value is encrypted with any more. The active key can not be removed.
+func RemoveKey(path string, version int) error {
+	ring, err := LoadKeyring(path)
+	if err != nil {
+		return err
+	}
+	if version == ring.Active {
+		return fmt.Errorf("pii: key %d is active; rotate first", version)
+	}
+	if _, exists := ring.Keys[version]; !exists {
+		return fmt.Errorf("pii: key %d does not exist", version)
+	}
+	delete(ring.Keys, version)
+	return SaveKeyring(path, ring)
+}
+
+// SaveKeyring writes ring to path, readable by its owner only.
+func SaveKeyring(path string, ring *Keyring) error {
+	stored := keyfile{Active: ring.Active, Keys: map[string]string{}, Index: base64.StdEncoding.EncodeToString(ring.Index)}
+	for version, key := range ring.Keys {
+		stored.Keys[strconv.Itoa(version)] = base64.StdEncoding.EncodeToString(key)
+	}
+	content, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("keys must be %d bytes", keySize)
+	}
+	return key, nil
+}
@@ -0,0 +1,80 @@
+package pii
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Column is a column holding personal data.
+type Column struct {
+	Table string
+	Name  string
+}
+
+// Columns lists every column holding a String, which rotation has to go through.
+var Columns = []Column{
+	{Table: "users", Name: "first_name"},
+	{Table: "users", Name: "last_name"},
+	{Table: "users", Name: "phone_no"},
+	{Table: "credentials", Name: "email"},
+	{Table: "login_history", Name: "email"},
+	{Table: "mfa_factors", Name: "secret"},
+}
+
+// DefaultBatchSize is how many rows Reencrypt is given at a time unless told otherwise.
+const DefaultBatchSize = 500
+
+type storedValue struct {
+	ID    string
+	Value string
+}
+
+// Reencrypt rewrites the values of column not encrypted with the active key, plain ones
+// included, batchSize rows at a time. It returns how many values it rewrote. A value changed
+// by someone else meanwhile is left to them, as they wrote it with the active key.
+func Reencrypt(db *gorm.DB, column Column, batchSize int) (int, error) {
+	ring, err := currentKeyring()
+	if err != nil {
+		return 0, err
+	}
+
+	rewritten := 0
+	lastID := ""
+	for {
+		values := []storedValue{}
+		err := db.Raw(fmt.Sprintf("SELECT id, %[2]s AS value FROM %[1]s WHERE id > ? AND %[2]s <> '' AND %[2]s NOT LIKE ? ORDER BY id LIMIT ?",
+			column.Table, column.Name), lastID, Pattern(ring.Active), batchSize).Scan(&values).Error
+		if err != nil {
+			return rewritten, err
+		}
+		if len(values) == 0 {
+			return rewritten, nil
+		}
+
+		for _, stored := range values {
+			plain, err := Decrypt(stored.Value)
+			if err != nil {
+				return rewritten, fmt.Errorf("%s.%s of %s: %w", column.Table, column.Name, stored.ID, err)
+			}
+			encrypted, err := ring.encrypt(plain, ring.Active)
+			if err != nil {
+				return rewritten, err
+			}
+			result := db.Exec(fmt.Sprintf("UPDATE %[1]s SET %[2]s = ? WHERE id = ? AND %[2]s = ?", column.Table, column.Name),
+				encrypted, stored.ID, stored.Value)
+			if result.Error != nil {
+				return rewritten, result.Error
+			}
+			rewritten += int(result.RowsAffected)
+		}
+		lastID = values[len(values)-1].ID
+	}
+}
+
+// CountEncryptedWith returns how many values of column are still encrypted with a key.
+func CountEncryptedWith(db *gorm.DB, column Column, version int) (int, error) {
+	count := 0
+	err := db.Table(column.Table).Where(column.Name+" LIKE ?", Pattern(version)).Count(&count).Error
+	return count, err
+}
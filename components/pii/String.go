@@ -0,0 +1,152 @@
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// prefix marks encrypted values, which are stored as "pii:v<key version>:<base64 nonce and sealed value>".
+const prefix = "pii:v"
+
+// String is personal data stored encrypted. It is a plain string everywhere else, in JSON as
+// well; only the database sees the ciphertext. Values written before encryption was introduced
+// are read as they are until the migration encrypts them.
+type String string
+
+// Value encrypts s with the active key. Empty strings are stored as they are.
+func (s String) Value() (driver.Value, error) {
+	if s == "" {
+		return "", nil
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return ring.encrypt(string(s), ring.Active)
+}
+
+// Scan decrypts a column with whichever key it was encrypted with.
+func (s *String) Scan(value interface{}) error {
+	var stored string
+	switch typed := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case []byte:
+		stored = string(typed)
+	case string:
+		stored = typed
+	default:
+		return fmt.Errorf("pii: can not scan %T", value)
+	}
+
+	plain, err := Decrypt(stored)
+	if err != nil {
+		return err
+	}
+	*s = String(plain)
+	return nil
+}
+
+// Decrypt returns the plain text of a stored value. Values without the prefix are returned as
+// they are.
+func Decrypt(stored string) (string, error) {
+	version, payload, ok := parse(stored)
+	if !ok {
+		return stored, nil
+	}
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	key, exists := ring.Keys[version]
+	if !exists {
+		return "", fmt.Errorf("pii: key %d is not in the keyfile", version)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("pii: malformed value: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("pii: malformed value")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(strconv.Itoa(version)))
+	if err != nil {
+		return "", fmt.Errorf("pii: value encrypted with key %d can not be decrypted: %w", version, err)
+	}
+	return string(plain), nil
+}
+
+// KeyVersion returns the version of the key a stored value is encrypted with, and false for
+// values stored in plain text.
+func KeyVersion(stored string) (int, bool) {
+	version, _, ok := parse(stored)
+	return version, ok
+}
+
+func parse(stored string) (int, string, bool) {
+	if !strings.HasPrefix(stored, prefix) {
+		return 0, "", false
+	}
+	parts := strings.SplitN(stored[len(prefix):], ":", 2)
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version <= 0 {
+		return 0, "", false
+	}
+	return version, parts[1], true
+}
+
+// Pattern returns the SQL LIKE pattern of the values encrypted with a key.
+func Pattern(version int) string {
+	return prefix + strconv.Itoa(version) + ":%"
+}
+
+// BlindIndex returns the keyed hash lookups of an email go through, as the encrypted column
+// can not be searched. Emails are compared regardless of case and surrounding spaces.
+func BlindIndex(email string) (string, error) {
+	ring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, ring.Index)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (ring *Keyring) encrypt(plain string, version int) (string, error) {
+	aead, err := newAEAD(ring.Keys[version])
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(strconv.Itoa(version)))
+	return prefix + strconv.Itoa(version) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
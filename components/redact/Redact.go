@@ -0,0 +1,189 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Mask replaces secrets outright; partly masked values keep just enough to be recognised.
+const Mask = "[REDACTED]"
+
+var (
+	// secretFields matches a secret written as a field, such as password=..., "token":"..." or
+	// `pin` = '...', and keeps the field name.
+	secretFields = regexp.MustCompile(`(?i)((?:^|[^A-Za-z])["'` + "`" + `]?(?:password|passwd|token|secret|pin|sig|signature|authorization)["'` + "`" + `]?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;&)}]+)`)
+	bearerTokens = regexp.MustCompile(`(?i)\b(bearer|signature|apikey)\s+[A-Za-z0-9._~+/=-]+`)
+	jwts         = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bcryptHashes = regexp.MustCompile(`\$2[aby]?\$\d{2}\$[./A-Za-z0-9]{53}`)
+	// hexSecrets are hashed tokens, which are as good as the token for a lookup.
+	hexSecrets = regexp.MustCompile(`\b[0-9a-fA-F]{64}\b`)
+	emails     = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// numbers are phone and account numbers: 8 to 20 digits, possibly with a country code and
+	// separators. Dates have as many digits but never six in a row.
+	numbers  = regexp.MustCompile(`\+?\d[\d -]{6,22}\d`)
+	digitRun = regexp.MustCompile(`\d{6}`)
+	uuids    = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	// sqlInserts are logged INSERT statements up to their values, which are not written next to
+	// their column like those of an UPDATE.
+	sqlInserts = regexp.MustCompile(`(?i)\bINSERT\s+INTO\s+\S+\s*\(([^)]*)\)\s*VALUES\s*\(`)
+	// secretColumns hold secrets or their hashes, such as password, secret or signing_secret.
+	secretColumns = regexp.MustCompile(`(?i)(?:^|_)(?:password|passwd|token|secret|pin|sig|signature|authorization|hash)(?:_|$)`)
+)
+
+// Text masks the tokens, passwords, emails, phone numbers and account numbers in text, which
+// is meant to be logged.
+func Text(text string) string {
+	text = sqlInsert(text)
+	text = bearerTokens.ReplaceAllString(text, "${1} "+Mask)
+	text = secretFields.ReplaceAllString(text, "${1}"+Mask)
+	text = jwts.ReplaceAllString(text, Mask)
+	text = bcryptHashes.ReplaceAllString(text, Mask)
+	text = hexSecrets.ReplaceAllString(text, Mask)
+	text = emails.ReplaceAllStringFunc(text, Email)
+	return Numbers(text)
+}
+
+// sqlInsert masks the values a logged INSERT statement gives to secret columns. When values
+// and columns can not be paired up, every value is masked.
+func sqlInsert(statement string) string {
+	match := sqlInserts.FindStringSubmatchIndex(statement)
+	if match == nil {
+		return statement
+	}
+
+	columns := strings.Split(statement[match[2]:match[3]], ",")
+	values, end := sqlValues(statement, match[1])
+	maskAll := len(values) != len(columns)
+
+	var masked strings.Builder
+	masked.WriteString(statement[:match[1]])
+	for i, value := range values {
+		if i > 0 {
+			masked.WriteString(",")
+		}
+		if maskAll || secretColumns.MatchString(strings.Trim(columns[i], " `\"'")) {
+			masked.WriteString("'" + Mask + "'")
+			continue
+		}
+		masked.WriteString(statement[value[0]:value[1]])
+	}
+	masked.WriteString(statement[end:])
+	return masked.String()
+}
+
+// sqlValues returns the spans of the comma separated values starting at start, and where they
+// end: at the closing parenthesis, or at the end of the statement when it is missing.
+func sqlValues(statement string, start int) ([][]int, int) {
+	values := [][]int{}
+	valueStart, depth := start, 0
+	var quote byte
+	for i := start; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case c == ')':
+			return append(values, []int{valueStart, i}), i
+		case c == ',' && depth == 0:
+			values = append(values, []int{valueStart, i})
+			valueStart = i + 1
+		}
+	}
+	return append(values, []int{valueStart, len(statement)}), len(statement)
+}
+
+// Email keeps the first letter and the domain of an email: r***@example.com.
+func Email(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return Mask
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// AccountNumber keeps the last four digits of an account or phone number: XXXXXXXX1234.
+func AccountNumber(number string) string {
+	if len(number) <= 4 {
+		return strings.Repeat("X", len(number))
+	}
+	return strings.Repeat("X", len(number)-4) + number[len(number)-4:]
+}
+
+// Numbers masks the phone and account numbers of text, such as a passbook note, leaving alone
+// the digits of IDs, times and other words they are part of.
+func Numbers(text string) string {
+	protected := uuids.FindAllStringIndex(text, -1)
+	matches := numbers.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+
+	var masked strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		digits := countDigits(text[start:end])
+		if digits < 8 || digits > 20 || !digitRun.MatchString(text[start:end]) || partOfWord(text, start, end) || overlaps(protected, start, end) {
+			continue
+		}
+		masked.WriteString(text[last:start])
+		masked.WriteString(maskDigits(text[start:end]))
+		last = end
+	}
+	masked.WriteString(text[last:])
+	return masked.String()
+}
+
+// maskDigits replaces every digit but the last four, keeping separators.
+func maskDigits(number string) string {
+	keep := 4
+	masked := []byte(number)
+	for i := len(masked) - 1; i >= 0; i-- {
+		if masked[i] < '0' || masked[i] > '9' {
+			continue
+		}
+		if keep > 0 {
+			keep--
+			continue
+		}
+		masked[i] = 'X'
+	}
+	return string(masked)
+}
+
+func countDigits(text string) int {
+	count := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] >= '0' && text[i] <= '9' {
+			count++
+		}
+	}
+	return count
+}
+
+func partOfWord(text string, start, end int) bool {
+	return (start > 0 && isWordByte(text[start-1])) || (end < len(text) && isWordByte(text[end]))
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b == '.' || b == ':' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+func overlaps(spans [][]int, start, end int) bool {
+	for _, span := range spans {
+		if start < span[1] && span[0] < end {
+			return true
+		}
+	}
+	return false
+}
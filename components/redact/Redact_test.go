@@ -0,0 +1,90 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextSQL(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		line   string
+		leaked []string
+		kept   []string
+	}{
+		{
+			name: "insert of a TOTP secret",
+			line: "[2026-10-19 10:00:00] [1.52ms] INSERT INTO `mfa_factors` (`id`,`created_at`,`user_id`,`secret`,`confirmed_at`,`last_used_counter`) " +
+				"VALUES ('6f1c2a4e-8b3d-4c5e-9f7a-1b2c3d4e5f60','2026-10-19 10:00:00','0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d','JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP',NULL,'0') [1 rows affected or returned ]",
+			leaked: []string{"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"},
+			kept:   []string{"'0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d','[REDACTED]',NULL,'0')", "[1 rows affected or returned ]"},
+		},
+		{
+			name: "insert of an API key",
+			line: "INSERT INTO `api_keys` (`id`,`prefix`,`key_hash`,`signing_secret`,`scopes`) " +
+				"VALUES ('6f1c2a4e-8b3d-4c5e-9f7a-1b2c3d4e5f60','bk_3f9a1c7e02d4','9f86d081884c7d659a2feaa0c55ad015','q83vEjRWeJq83vEjRWeJ-_q83vEjRWeJq83vEjRWeJ','account:transact')",
+			leaked: []string{"q83vEjRWeJq83vEjRWeJ-_q83vEjRWeJq83vEjRWeJ", "9f86d081884c7d659a2feaa0c55ad015"},
+			kept:   []string{"'bk_3f9a1c7e02d4','[REDACTED]','[REDACTED]','account:transact')"},
+		},
+		{
+			name: "insert of a password",
+			line: "INSERT INTO `credentials` (`user_id`,`password`,`email_index`) " +
+				"VALUES ('0a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d','$2a$10$abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0','ab12')",
+			leaked: []string{"$2a$10$abcdefghijklmnopqrstuvwxyz"},
+			kept:   []string{"'[REDACTED]','ab12')"},
+		},
+		{
+			name:   "insert with quoted commas and parentheses",
+			line:   "INSERT INTO `mfa_factors` (`name`,`secret`) VALUES ('Acme, (Ltd)','it''s, a secret') [1 rows affected or returned ]",
+			leaked: []string{"a secret"},
+			kept:   []string{"VALUES ('Acme, (Ltd)','[REDACTED]') [1 rows"},
+		},
+		{
+			name:   "insert whose values do not pair with the columns",
+			line:   "INSERT INTO `api_keys` (`name`,`signing_secret`) VALUES ('payroll')",
+			leaked: []string{"payroll"},
+			kept:   []string{"VALUES ('[REDACTED]')"},
+		},
+		{
+			name:   "insert without secrets",
+			line:   "INSERT INTO `banks` (`name`,`abbreviation`) VALUES ('Acme, Ltd','ACME')",
+			leaked: []string{Mask},
+			kept:   []string{"VALUES ('Acme, Ltd','ACME')"},
+		},
+		{
+			name:   "update of a signing secret",
+			line:   "UPDATE `api_keys` SET `signing_secret` = 'q83vEjRWeJq83vEjRWeJ-_q83vEjRWeJ', `updated_at` = '2026-10-19 10:00:00' WHERE (id = 'x')",
+			leaked: []string{"q83vEjRWeJq83vEjRWeJ-_q83vEjRWeJ"},
+			kept:   []string{"`signing_secret` = [REDACTED]", "`updated_at` = '2026-10-19 10:00:00'"},
+		},
+		{
+			name:   "update of a TOTP secret",
+			line:   "UPDATE `mfa_factors` SET `secret` = 'JBSWY3DPEHPK3PXP' WHERE (id = 'x')",
+			leaked: []string{"JBSWY3DPEHPK3PXP"},
+			kept:   []string{"`secret` = [REDACTED] WHERE"},
+		},
+		{
+			name:   "update of a password",
+			line:   "UPDATE `credentials` SET `password` = '$2a$10$abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0' WHERE (user_id = 'x')",
+			leaked: []string{"$2a$10$"},
+			kept:   []string{"`password` = [REDACTED] WHERE"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Text(test.line)
+			for _, leaked := range test.leaked {
+				if strings.Contains(got, leaked) {
+					t.Errorf("%q is still in %q", leaked, got)
+				}
+			}
+			for _, kept := range test.kept {
+				if !strings.Contains(got, kept) {
+					t.Errorf("%q is missing from %q", kept, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package security
+
+import (
+	"banking-app-be/components/errors"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// APIKeyPrefix starts every API key, so the middleware can tell keys from access tokens. Keys are
+// sent as "Authorization: ApiKey <key>" or as a bearer token.
+const APIKeyPrefix = "bk_"
+
+// apiKeyIDLength is the length of the random part of the prefix that identifies a key.
+const apiKeyIDLength = 12
+
+// APIKeyIdentity is what an API key authenticates: a service account acting for its owner,
+// limited to some operations on some accounts.
+type APIKeyIdentity struct {
+	KeyID            uuid.UUID
+	ServiceAccountID uuid.UUID
+	OwnerID          uuid.UUID
+	Scopes           []string
+	AccountIDs       []uuid.UUID
+	MaxAmount        float32
+}
+
+// APIKeyRequest is a request made with an API key, as written to the key's usage log.
+type APIKeyRequest struct {
+	UsedAt     time.Time
+	Method     string
+	Path       string
+	IPAddress  string
+	StatusCode int
+}
+
+// APIKeyAuthenticator looks up API keys and keeps their usage logs. Lookups return the identity
+// along with the error when a known key is refused, so the refusal is logged too.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(key string) (*APIKeyIdentity, error)
+	// APIKeySigningSecret returns the secret requests signed with the key of the prefix are
+	// verified with.
+	APIKeySigningSecret(prefix string) (*APIKeyIdentity, []byte, error)
+	// UseAPIKeyNonce fails when the nonce was already used with the key.
+	UseAPIKeyNonce(keyID uuid.UUID, nonce string, usedAt time.Time) error
+	RecordAPIKeyUsage(keyID uuid.UUID, request APIKeyRequest)
+}
+
+var apiKeyAuthenticator APIKeyAuthenticator
+
+// RegisterAPIKeyAuthenticator lets the middleware accept API keys alongside access tokens.
+func RegisterAPIKeyAuthenticator(authenticator APIKeyAuthenticator) {
+	apiKeyAuthenticator = authenticator
+}
+
+// GenerateAPIKey returns a new API key and the prefix identifying it.
+func GenerateAPIKey() (key, prefix string, err error) {
+	id := make([]byte, apiKeyIDLength/2)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + secret, prefix, nil
+}
+
+// APIKeyPrefixOf returns the identifying prefix of key.
+func APIKeyPrefixOf(key string) (string, bool) {
+	length := len(APIKeyPrefix) + apiKeyIDLength
+	if !strings.HasPrefix(key, APIKeyPrefix) || len(key) <= length+1 || key[length] != '_' {
+		return "", false
+	}
+	return key[:length], true
+}
+
+// apiKeyOf returns the API key the request was made with, if any.
+func apiKeyOf(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	for _, scheme := range []string{"ApiKey ", "Bearer "} {
+		if key := strings.TrimPrefix(authHeader, scheme); key != authHeader && strings.HasPrefix(key, APIKeyPrefix) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func authenticateAPIKey(key string, r *http.Request) (*Principal, error) {
+
+	if apiKeyAuthenticator == nil {
+		return nil, errors.NewUnauthorizedError("API keys are not accepted")
+	}
+
+	identity, err := apiKeyAuthenticator.AuthenticateAPIKey(key)
+	if err != nil {
+		if identity != nil {
+			recordAPIKeyUsage(identity, r, http.StatusUnauthorized)
+		}
+		return nil, err
+	}
+	return apiKeyPrincipal(identity, r)
+}
+
+// apiKeyPrincipal resolves the owner of the key's service account and narrows them down to the
+// key's scopes. The owner's current permissions still apply, so a key never grants more than
+// its owner holds.
+func apiKeyPrincipal(identity *APIKeyIdentity, r *http.Request) (*Principal, error) {
+
+	if principalResolver == nil {
+		return nil, errors.NewUnauthorizedError("API keys are not accepted")
+	}
+	owner, err := cachedPrincipalOf(identity.OwnerID)
+	if err != nil {
+		recordAPIKeyUsage(identity, r, http.StatusUnauthorized)
+		return nil, err
+	}
+
+	permissions := map[string]bool{}
+	for _, scope := range identity.Scopes {
+		if owner.Permissions[scope] {
+			permissions[scope] = true
+		}
+	}
+
+	return &Principal{
+		UserID:      owner.UserID,
+		IsActive:    owner.IsActive,
+		Roles:       owner.Roles,
+		Permissions: permissions,
+		BankScope:   owner.BankScope,
+		APIKey:      identity,
+	}, nil
+}
+
+func recordAPIKeyUsage(identity *APIKeyIdentity, r *http.Request, statusCode int) {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+	path := r.URL.Path
+	if len(path) > 255 {
+		path = path[:255]
+	}
+	apiKeyAuthenticator.RecordAPIKeyUsage(identity.KeyID, APIKeyRequest{
+		UsedAt:     time.Now(),
+		Method:     r.Method,
+		Path:       path,
+		IPAddress:  ipAddress,
+		StatusCode: statusCode,
+	})
+}
+
+// statusRecorder remembers the status a handler answered with, for the usage log.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
@@ -1,7 +1,6 @@
 package security
 
 import (
-	"banking-app-be/components/config"
 	"banking-app-be/components/errors"
 	"fmt"
 	"net/http"
@@ -22,8 +21,6 @@ func ValidateToken(_ http.ResponseWriter, r *http.Request, claim *Claims) error
 		return errors.NewUnauthorizedError("empty token in Authorization header")
 	}
 
-	// fmt.Println("token string ============>>", tokenString)
-
 	token, err := checkToken(tokenString, claim)
 	if err != nil {
 		return errors.NewUnauthorizedError("invalid token: " + err.Error())
@@ -33,15 +30,27 @@ func ValidateToken(_ http.ResponseWriter, r *http.Request, claim *Claims) error
 		return errors.NewUnauthorizedError("invalid token")
 	}
 
+	if isSessionRevoked(claim) {
+		return errors.NewUnauthorizedError("token has been revoked")
+	}
+
 	return nil
 }
 
-// Checks Token String
+// Checks Token String. The token is verified with the key its kid header names, and only with
+// the algorithm of that key.
 func checkToken(tokenString string, claim *Claims) (*jwt.Token, error) {
 
 	token, err := jwt.ParseWithClaims(tokenString, claim, func(t *jwt.Token) (interface{}, error) {
-		return []byte(config.JWTKey.GetStringValue()), nil
+		kid, _ := t.Header["kid"].(string)
+		key := currentKeys().verificationKey(kid)
+		if key == nil {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if t.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return key.Public, nil
 	})
-	fmt.Println("token err==> ", err)
 	return token, err
 }
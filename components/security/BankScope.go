@@ -0,0 +1,104 @@
+package security
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/web"
+	"banking-app-be/module/repository"
+	"net/http"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// BankScope limits which banks a principal may manage. Super-admins and customers are not
+// restricted; every other staff member only reaches the banks assigned to them.
+type BankScope struct {
+	Restricted bool
+	BankIDs    []uuid.UUID
+}
+
+// Unrestricted is the scope of callers that may reach every bank.
+var Unrestricted = BankScope{}
+
+// Allows reports whether bankID is inside the scope.
+func (s BankScope) Allows(bankID uuid.UUID) bool {
+	if !s.Restricted {
+		return true
+	}
+	for _, allowed := range s.BankIDs {
+		if allowed == bankID {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns a forbidden error when bankID is outside the scope.
+func (s BankScope) Check(bankID uuid.UUID) error {
+	if !s.Allows(bankID) {
+		return errors.NewOutOfScopeError("Bank " + bankID.String() + " is outside your scope")
+	}
+	return nil
+}
+
+// CheckGlobal rejects restricted callers from actions that affect every bank at once.
+func (s BankScope) CheckGlobal() error {
+	if s.Restricted {
+		return errors.NewOutOfScopeError("Only administrators of every bank can perform this action")
+	}
+	return nil
+}
+
+// Filter keeps rows where any of the bank ID columns is inside the scope.
+func (s BankScope) Filter(columns ...string) repository.QueryProcessor {
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = column + " IN (?)"
+	}
+	return s.Where("(" + strings.Join(conditions, " OR ") + ")")
+}
+
+// Where applies condition, in which every ? stands for the scope's bank IDs, to restricted
+// callers. It lets related tables be scoped through a sub-query on their bank.
+func (s BankScope) Where(condition string) repository.QueryProcessor {
+	if !s.Restricted {
+		return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+			return db, nil
+		}
+	}
+	args := make([]interface{}, strings.Count(condition, "?"))
+	for i := range args {
+		args[i] = s.BankIDs
+	}
+	return repository.Filter(condition, args...)
+}
+
+// CurrentBankScope returns the bank scope of the principal the middleware attached.
+func CurrentBankScope(r *http.Request) BankScope {
+	principal, err := CurrentPrincipal(r)
+	if err != nil {
+		return BankScope{Restricted: true}
+	}
+	return principal.BankScope
+}
+
+// AuthorizeBank is Authorize for routes addressing a bank through the path parameter param;
+// the bank must also be inside the caller's scope.
+func AuthorizeBank(handler http.HandlerFunc, param string, permissions ...string) http.HandlerFunc {
+	return Authorize(func(w http.ResponseWriter, r *http.Request) {
+
+		bankID, err := web.NewParser(r).GetUUID(param)
+		if err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid bank ID format"))
+			return
+		}
+
+		if err := CurrentBankScope(r).Check(bankID); err != nil {
+			web.RespondError(w, err)
+			return
+		}
+
+		handler(w, r)
+	}, permissions...)
+}
@@ -0,0 +1,37 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"time"
+)
+
+// KeyReloader periodically rereads the keys directory, so a key rotated with the jwtkeys
+// command signs new tokens and appears in the JWKS without a restart.
+type KeyReloader struct {
+	log      log.Logger
+	interval time.Duration
+}
+
+func NewKeyReloader(log log.Logger) *KeyReloader {
+	return &KeyReloader{
+		log:      log,
+		interval: time.Duration(config.JWTKeyReloadSeconds.GetInt64ValueOrDefault(60)) * time.Second,
+	}
+}
+
+func (reloader *KeyReloader) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(reloader.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			if err := ReloadKeys(); err != nil {
+				reloader.log.Error("Signing key reload failed, keeping the loaded keys: ", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Tokens are signed with one of the keys kept in the keys directory:
+//
+//	<kid>.key  PKCS#8 private key, present while the key may sign
+//	<kid>.pub  public key, kept until every token signed with it has expired
+//	active     the kid new tokens are signed with
+//
+// Rotating adds a key, makes it active and drops the private half of the previous one, which
+// goes on verifying the tokens it signed until it is removed.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+
+	privateKeyExtension = ".key"
+	publicKeyExtension  = ".pub"
+	activeKeyFile       = "active"
+	rsaKeyBits          = 2048
+)
+
+// SigningKey is one key of the key set. Private is nil for retired keys.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// KeySet holds the key tokens are signed with and every key they are verified with.
+type KeySet struct {
+	Active *SigningKey
+	Keys   map[string]*SigningKey
+}
+
+// JSONWebKey is the public half of a signing key as published in the JWKS document.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+var (
+	keysMutex sync.RWMutex
+	keys      *KeySet
+)
+
+// InitializeKeys loads the signing keys from the configured directory. When it holds no keys
+// and JWT_GENERATE_MISSING_KEYS is set, a first key is generated, which is meant for local runs.
+func InitializeKeys() error {
+	dir := KeysDir()
+	if config.JWTGenerateMissingKeys.GetBoolValueOrDefault(false) {
+		if _, err := os.Stat(filepath.Join(dir, activeKeyFile)); os.IsNotExist(err) {
+			kid, err := RotateKeys(dir, KeyAlgorithm())
+			if err != nil {
+				return err
+			}
+			log.GetLogger().Info("Generated signing key ", kid, " in ", dir)
+		}
+	}
+	return ReloadKeys()
+}
+
+// ReloadKeys reads the keys directory again so keys rotated by the CLI are picked up without a
+// restart. The keys in use are kept when the directory can not be read.
+func ReloadKeys() error {
+	keySet, err := LoadKeySet(KeysDir())
+	if err != nil {
+		return err
+	}
+	keysMutex.Lock()
+	keys = keySet
+	keysMutex.Unlock()
+	return nil
+}
+
+func currentKeys() *KeySet {
+	keysMutex.RLock()
+	defer keysMutex.RUnlock()
+	return keys
+}
+
+// KeysDir returns the configured keys directory.
+func KeysDir() string {
+	if dir := config.JWTKeysDir.GetStringValue(); dir != "" {
+		return dir
+	}
+	return filepath.Join("keys", "jwt")
+}
+
+// KeyAlgorithm returns the configured algorithm for new keys.
+func KeyAlgorithm() string {
+	if algorithm := config.JWTAlgorithm.GetStringValue(); algorithm != "" {
+		return algorithm
+	}
+	return AlgorithmEdDSA
+}
+
+// CurrentJWKS returns the public keys tokens are currently verified with.
+func CurrentJWKS() JSONWebKeySet {
+	keySet := currentKeys()
+	if keySet == nil {
+		return JSONWebKeySet{Keys: []JSONWebKey{}}
+	}
+	return keySet.JWKS()
+}
+
+// LoadKeySet reads the keys in dir. The active key must have its private half.
+func LoadKeySet(dir string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read keys directory: %w", err)
+	}
+
+	keySet := &KeySet{Keys: map[string]*SigningKey{}}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case privateKeyExtension:
+			key, err := readPrivateKey(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			key.ID = strings.TrimSuffix(name, privateKeyExtension)
+			keySet.Keys[key.ID] = key
+		case publicKeyExtension:
+			kid := strings.TrimSuffix(name, publicKeyExtension)
+			if _, ok := keySet.Keys[kid]; ok {
+				continue
+			}
+			key, err := readPublicKey(filepath.Join(dir, name))
+			if err != nil {
+				return nil, err
+			}
+			key.ID = kid
+			keySet.Keys[kid] = key
+		}
+	}
+
+	activeID, err := readActiveKeyID(dir)
+	if err != nil {
+		return nil, err
+	}
+	active, ok := keySet.Keys[activeID]
+	if !ok || active.Private == nil {
+		return nil, fmt.Errorf("active key %q has no private key in %s", activeID, dir)
+	}
+	keySet.Active = active
+	return keySet, nil
+}
+
+// verificationKey returns the key a token with the given kid is verified with, or nil.
+func (keySet *KeySet) verificationKey(kid string) *SigningKey {
+	if keySet == nil {
+		return nil
+	}
+	return keySet.Keys[kid]
+}
+
+// IDs lists the kids of the set in order.
+func (keySet *KeySet) IDs() []string {
+	ids := make([]string, 0, len(keySet.Keys))
+	for kid := range keySet.Keys {
+		ids = append(ids, kid)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (keySet *KeySet) JWKS() JSONWebKeySet {
+	jwks := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, kid := range keySet.IDs() {
+		key := keySet.Keys[kid]
+		jwk := JSONWebKey{Use: "sig", Algorithm: key.Method.Alg(), KeyID: kid}
+		switch public := key.Public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
+
+// GenerateKey writes a new key pair for algorithm to dir and returns its kid. The key does not
+// sign until it is activated.
+func GenerateKey(dir, algorithm string) (string, error) {
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+	switch algorithm {
+	case AlgorithmEdDSA:
+		edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return "", err
+		}
+		private, public = edPrivate, edPublic
+	case AlgorithmRS256:
+		rsaPrivate, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return "", err
+		}
+		private, public = rsaPrivate, &rsaPrivate.PublicKey
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q, use %s or %s", algorithm, AlgorithmRS256, AlgorithmEdDSA)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+privateKeyExtension),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+publicKeyExtension),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// ActivateKey makes kid the key new tokens are signed with.
+func ActivateKey(dir, kid string) error {
+	if _, err := os.Stat(filepath.Join(dir, kid+privateKeyExtension)); err != nil {
+		return fmt.Errorf("key %q has no private key in %s", kid, dir)
+	}
+	return os.WriteFile(filepath.Join(dir, activeKeyFile), []byte(kid+"\n"), 0600)
+}
+
+// RotateKeys generates a key, activates it and retires the key that was active before.
+func RotateKeys(dir, algorithm string) (string, error) {
+	previousID, err := readActiveKeyID(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	kid, err := GenerateKey(dir, algorithm)
+	if err != nil {
+		return "", err
+	}
+	if err := ActivateKey(dir, kid); err != nil {
+		return "", err
+	}
+	if previousID != "" {
+		if err := RetireKey(dir, previousID); err != nil {
+			return "", err
+		}
+	}
+	return kid, nil
+}
+
+// RetireKey drops the private half of kid, which then only verifies tokens it signed before.
+func RetireKey(dir, kid string) error {
+	if err := refuseActive(dir, kid); err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(dir, kid+publicKeyExtension)); err != nil {
+		return fmt.Errorf("key %q has no public key in %s", kid, dir)
+	}
+	if err := os.Remove(filepath.Join(dir, kid+privateKeyExtension)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// RemoveKey deletes kid, after which tokens it signed are refused.
+func RemoveKey(dir, kid string) error {
+	if err := refuseActive(dir, kid); err != nil {
+		return err
+	}
+	removed := false
+	for _, extension := range []string{privateKeyExtension, publicKeyExtension} {
+		err := os.Remove(filepath.Join(dir, kid+extension))
+		if err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if !removed {
+		return fmt.Errorf("key %q not found in %s", kid, dir)
+	}
+	return nil
+}
+
+//=======================================================================================
+
+func refuseActive(dir, kid string) error {
+	activeID, err := readActiveKeyID(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if activeID == kid {
+		return fmt.Errorf("key %q is active, rotate to another key first", kid)
+	}
+	return nil
+}
+
+func readActiveKeyID(dir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, activeKeyFile))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func readPrivateKey(path string) (*SigningKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	private, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	switch private := private.(type) {
+	case ed25519.PrivateKey:
+		return &SigningKey{Method: jwt.SigningMethodEdDSA, Private: private, Public: private.Public()}, nil
+	case *rsa.PrivateKey:
+		return &SigningKey{Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type in %s", path)
+	}
+}
+
+func readPublicKey(path string) (*SigningKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	public, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	switch public := public.(type) {
+	case ed25519.PublicKey:
+		return &SigningKey{Method: jwt.SigningMethodEdDSA, Public: public}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{Method: jwt.SigningMethodRS256, Public: public}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type in %s", path)
+	}
+}
+
+func readPEM(path, blockType string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not hold a PEM %s", path, blockType)
+	}
+	return block.Bytes, nil
+}
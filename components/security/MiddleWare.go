@@ -1,89 +1,110 @@
 package security
 
 import (
+	"banking-app-be/components/audit"
 	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
 	"banking-app-be/components/web"
-	"fmt"
 	"net/http"
 )
 
-func MiddlewareAdmin(next http.Handler) http.Handler {
-
+// MiddlewareActive authenticates the request and lets any active user through. Routes behind it
+// declare what they additionally need with Authorize.
+func MiddlewareActive(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		claim := Claims{}
-
-		err := ValidateToken(w, r, &claim)
+		principal, r, err := authenticate(w, r)
 		if err != nil {
-			fmt.Println("err=> ", err)
-			web.RespondError(w, errors.NewValidationError("Invalid or missing token"))
+			log.GetLogger().Info("Authentication failed: ", err)
+			web.RespondError(w, errors.NewUnauthorizedError("Invalid or missing token"))
 			return
 		}
+		r = audit.WithActor(r, principal.UserID, principal.ImpersonatorID)
+
+		if principal.ImpersonatorID != nil {
+			w.Header().Set(ImpersonatedByHeader, principal.ImpersonatorID.String())
+			if principal.ReadOnly && !isReadOnlyMethod(r.Method) {
+				web.RespondError(w, errors.NewOutOfScopeError("Read-only impersonation can not change anything"))
+				return
+			}
+		}
 
-		if !claim.IsAdmin {
-			fmt.Println("User is not Admin")
-			web.RespondError(w, errors.NewUnauthorizedError("Current user not an admin"))
-			return
+		if principal.APIKey != nil {
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() { recordAPIKeyUsage(principal.APIKey, r, recorder.statusCode) }()
+			w = recorder
 		}
 
-		if !claim.IsActive {
-			fmt.Println("User is not Active")
-			web.RespondError(w, errors.NewInActiveUserError("current user is not active"))
+		if !principal.IsActive {
+			web.RespondError(w, errors.NewInActiveUserError("Current user is not active"))
 			return
 		}
 
+		// Active user (admin or non-admin) passes
 		next.ServeHTTP(w, r)
-
 	})
 }
 
-func MiddlewareUser(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// ImpersonatedByHeader is set on every response to an admin acting as a user, naming the admin,
+// so clients can show that the session is impersonated.
+const ImpersonatedByHeader = "X-Impersonated-By"
+
+func isReadOnlyMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// errAPIKeyRoute refuses API keys on routes not addressing a single account, as keys are
+// restricted to their accounts.
+var errAPIKeyRoute = errors.NewOutOfScopeError("API keys can only be used on routes addressing one of their accounts")
 
-		claim := Claims{}
+// Authorize wraps a handler so it only runs for principals holding every listed permission.
+// It must sit behind MiddlewareActive, which attaches the principal.
+func Authorize(handler http.HandlerFunc, permissions ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 
-		err := ValidateToken(w, r, &claim)
+		principal, err := CurrentPrincipal(r)
 		if err != nil {
-			fmt.Println("err=> ", err)
-			web.RespondError(w, errors.NewValidationError("Invalid or missing token"))
+			web.RespondError(w, err)
 			return
 		}
-
-		if claim.IsAdmin {
-			fmt.Println("User is not Admin")
-			web.RespondError(w, errors.NewUnauthorizedError("Current user not an admin"))
+		if principal.APIKey != nil {
+			web.RespondError(w, errAPIKeyRoute)
 			return
 		}
 
-		if !claim.IsActive {
-			fmt.Println("User is not Active")
-			web.RespondError(w, errors.NewInActiveUserError("current user is not active"))
-			return
+		for _, permission := range permissions {
+			if !principal.HasPermission(permission) {
+				web.RespondError(w, errors.NewForbiddenError(permission))
+				return
+			}
 		}
 
-		next.ServeHTTP(w, r)
-	})
+		handler(w, r)
+	}
 }
 
-func MiddlewareActive(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		claim := Claims{}
+// AuthorizeAny is Authorize for routes open to principals holding at least one of the listed
+// permissions, typically a holder's own permission and its staff counterpart.
+func AuthorizeAny(handler http.HandlerFunc, permissions ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 
-		err := ValidateToken(w, r, &claim)
+		principal, err := CurrentPrincipal(r)
 		if err != nil {
-			fmt.Println("err =>", err)
-			web.RespondError(w, errors.NewValidationError("Invalid or missing token"))
+			web.RespondError(w, err)
 			return
 		}
-
-		if !claim.IsActive {
-			fmt.Println("User is not Active")
-			web.RespondError(w, errors.NewInActiveUserError("Current user is not active"))
+		if principal.APIKey != nil {
+			web.RespondError(w, errAPIKeyRoute)
 			return
 		}
 
-		// Active user (admin or non-admin) passes
-		next.ServeHTTP(w, r)
-	})
+		for _, permission := range permissions {
+			if principal.HasPermission(permission) {
+				handler(w, r)
+				return
+			}
+		}
+
+		web.RespondError(w, errors.NewForbiddenError(permissions[0]))
+	}
 }
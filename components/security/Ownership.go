@@ -0,0 +1,88 @@
+package security
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/web"
+	"context"
+	"net/http"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// AccountOwnerResolver looks up who holds an account and with which bank.
+type AccountOwnerResolver interface {
+	AccountOwner(accountID uuid.UUID) (holderID, bankID uuid.UUID, err error)
+}
+
+type accountHolderContextKey struct{}
+
+var accountOwnerResolver AccountOwnerResolver
+
+// RegisterAccountOwnerResolver lets AuthorizeAccount find the holder of the addressed account.
+func RegisterAccountOwnerResolver(resolver AccountOwnerResolver) {
+	accountOwnerResolver = resolver
+}
+
+// AuthorizeAccount guards routes addressing an account through the path parameter param. The
+// holder passes with ownPermission; staff pass with staffPermission for accounts with banks in
+// their scope, and an empty staffPermission keeps the route to the holder. Anyone else is told
+// the account does not exist, so account IDs can not be probed. API keys are also told so for
+// accounts they are not restricted to.
+func AuthorizeAccount(handler http.HandlerFunc, param, ownPermission, staffPermission string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		principal, err := CurrentPrincipal(r)
+		if err != nil {
+			web.RespondError(w, err)
+			return
+		}
+
+		accountID, err := web.NewParser(r).GetUUID(param)
+		if err != nil {
+			web.RespondError(w, errors.NewValidationError("Invalid Account ID format"))
+			return
+		}
+
+		if accountOwnerResolver == nil {
+			web.RespondError(w, errors.NewHTTPError("Account ownership can not be verified", http.StatusInternalServerError))
+			return
+		}
+		holderID, bankID, err := accountOwnerResolver.AccountOwner(accountID)
+		if err != nil {
+			web.RespondError(w, errors.NewHTTPError("Account not found with given Id", http.StatusNotFound))
+			return
+		}
+
+		if !principal.AllowsAccount(accountID) {
+			web.RespondError(w, errors.NewHTTPError("Account not found with given Id", http.StatusNotFound))
+			return
+		}
+
+		switch {
+		case holderID == principal.UserID:
+			if !principal.HasPermission(ownPermission) {
+				web.RespondError(w, errors.NewForbiddenError(ownPermission))
+				return
+			}
+		case staffPermission != "" && principal.HasPermission(staffPermission):
+			if err := principal.BankScope.Check(bankID); err != nil {
+				web.RespondError(w, err)
+				return
+			}
+		default:
+			web.RespondError(w, errors.NewHTTPError("Account not found with given Id", http.StatusNotFound))
+			return
+		}
+
+		handler(w, r.WithContext(context.WithValue(r.Context(), accountHolderContextKey{}, holderID)))
+	}
+}
+
+// CurrentAccountHolder returns the holder of the account AuthorizeAccount let the request reach.
+func CurrentAccountHolder(r *http.Request) (uuid.UUID, error) {
+	holderID, ok := r.Context().Value(accountHolderContextKey{}).(uuid.UUID)
+	if !ok {
+		return uuid.Nil, errors.NewUnauthorizedError("account access was not verified")
+	}
+	return holderID, nil
+}
@@ -0,0 +1,148 @@
+package security
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/model/role"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+)
+
+type stubPrincipals map[uuid.UUID]*Principal
+
+func (s stubPrincipals) ResolvePrincipal(userID uuid.UUID) (*Principal, error) {
+	principal, ok := s[userID]
+	if !ok {
+		return nil, errors.NewNotFoundError("User not found")
+	}
+	return principal, nil
+}
+
+type stubOwners map[uuid.UUID][2]uuid.UUID
+
+func (s stubOwners) AccountOwner(accountID uuid.UUID) (uuid.UUID, uuid.UUID, error) {
+	owner, ok := s[accountID]
+	if !ok {
+		return uuid.Nil, uuid.Nil, errors.NewNotFoundError("Account not found")
+	}
+	return owner[0], owner[1], nil
+}
+
+func stubPrincipal(userID uuid.UUID, roles ...string) *Principal {
+	return &Principal{UserID: userID, IsActive: true, Roles: roles, Permissions: role.PermissionsOf(roles...)}
+}
+
+// useTestKeys signs and verifies tokens with a key generated for the test.
+func useTestKeys(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	kid, err := GenerateKey(dir, AlgorithmEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ActivateKey(dir, kid); err != nil {
+		t.Fatal(err)
+	}
+	keySet, err := LoadKeySet(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keysMutex.Lock()
+	previous := keys
+	keys = keySet
+	keysMutex.Unlock()
+	t.Cleanup(func() {
+		keysMutex.Lock()
+		keys = previous
+		keysMutex.Unlock()
+	})
+}
+
+func testToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+
+	claim := Claims{
+		UserID:   userID,
+		IsActive: true,
+		StandardClaims: jwt.StandardClaims{
+			Id:        uuid.NewV4().String(),
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	}
+	token, err := claim.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthorizeAccount(t *testing.T) {
+
+	useTestKeys(t)
+
+	ownerID, otherCustomerID, adminID := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	accountID, bankID := uuid.NewV4(), uuid.NewV4()
+
+	previousResolver, previousOwners := principalResolver, accountOwnerResolver
+	RegisterPrincipalResolver(stubPrincipals{
+		ownerID:         stubPrincipal(ownerID, role.Customer),
+		otherCustomerID: stubPrincipal(otherCustomerID, role.Customer),
+		adminID:         stubPrincipal(adminID, role.SuperAdmin),
+	})
+	RegisterAccountOwnerResolver(stubOwners{accountID: {ownerID, bankID}})
+	t.Cleanup(func() {
+		RegisterPrincipalResolver(previousResolver)
+		RegisterAccountOwnerResolver(previousOwners)
+		for _, userID := range []uuid.UUID{ownerID, otherCustomerID, adminID} {
+			InvalidatePrincipal(userID)
+		}
+	})
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	router := mux.NewRouter()
+	accountRouter := router.PathPrefix("/account").Subrouter()
+	accountRouter.Use(MiddlewareActive)
+	accountRouter.HandleFunc("/{id}", AuthorizeAccount(ok, "id", role.AccountManageOwn, role.AccountReadAll)).Methods(http.MethodGet)
+	passbookRouter := router.PathPrefix("/passbook").Subrouter()
+	passbookRouter.Use(MiddlewareActive)
+	passbookRouter.HandleFunc("/{accountId}", AuthorizeAccount(ok, "accountId", role.PassbookReadOwn, role.PassbookReadAll)).Methods(http.MethodGet)
+
+	routes := []string{"/account/", "/passbook/"}
+	tests := []struct {
+		name   string
+		userID *uuid.UUID
+		status int
+	}{
+		{name: "owner", userID: &ownerID, status: http.StatusOK},
+		{name: "another customer", userID: &otherCustomerID, status: http.StatusNotFound},
+		{name: "admin", userID: &adminID, status: http.StatusOK},
+		{name: "anonymous", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		for _, route := range routes {
+			t.Run(test.name+" "+route, func(t *testing.T) {
+				r := httptest.NewRequest(http.MethodGet, route+accountID.String(), nil)
+				if test.userID != nil {
+					r.Header.Set("Authorization", "Bearer "+testToken(t, *test.userID))
+				}
+				w := httptest.NewRecorder()
+
+				router.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Errorf("got status %d, want %d: %s", w.Code, test.status, w.Body.String())
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/model/role"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Principal is the user a request acts for, as currently stored rather than as the token claims.
+type Principal struct {
+	UserID      uuid.UUID
+	SessionID   uuid.UUID
+	IsAdmin     bool
+	IsActive    bool
+	Roles       []string
+	Permissions map[string]bool
+	BankScope   BankScope
+	// APIKey is set when the request was made with an API key rather than an access token.
+	APIKey *APIKeyIdentity
+	// ImpersonatorID is set when an admin is acting as the user; ReadOnly limits them to reading.
+	ImpersonatorID *uuid.UUID
+	ReadOnly       bool
+}
+
+// HasPermission reports whether any role of the principal grants the permission.
+func (p *Principal) HasPermission(permission string) bool {
+	return p.Permissions[permission]
+}
+
+// AllowsAccount reports whether the principal may address the account. Only API keys are
+// restricted to some accounts.
+func (p *Principal) AllowsAccount(accountID uuid.UUID) bool {
+	if p.APIKey == nil {
+		return true
+	}
+	for _, allowed := range p.APIKey.AccountIDs {
+		if allowed == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalResolver loads the current state of a user for the middleware.
+type PrincipalResolver interface {
+	ResolvePrincipal(userID uuid.UUID) (*Principal, error)
+}
+
+type principalContextKey struct{}
+
+type cachedPrincipal struct {
+	principal Principal
+	expiresAt time.Time
+}
+
+var (
+	principalResolver PrincipalResolver
+	principalCache    = make(map[uuid.UUID]cachedPrincipal)
+	principalMutex    sync.RWMutex
+)
+
+// RegisterPrincipalResolver makes the middleware decide from the stored user instead of the token claims.
+func RegisterPrincipalResolver(resolver PrincipalResolver) {
+	principalResolver = resolver
+}
+
+// InvalidatePrincipal drops the cached state of a user so the next request sees their changes.
+func InvalidatePrincipal(userID uuid.UUID) {
+	principalMutex.Lock()
+	defer principalMutex.Unlock()
+	delete(principalCache, userID)
+}
+
+// CurrentPrincipal returns the principal the security middleware attached to the request.
+func CurrentPrincipal(r *http.Request) (*Principal, error) {
+	principal, ok := r.Context().Value(principalContextKey{}).(*Principal)
+	if !ok {
+		return nil, errors.NewUnauthorizedError("request is not authenticated")
+	}
+	return principal, nil
+}
+
+// CurrentUserID returns the ID of the user the request acts for.
+func CurrentUserID(r *http.Request) (uuid.UUID, error) {
+	principal, err := CurrentPrincipal(r)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return principal.UserID, nil
+}
+
+// authenticate validates the token, API key or request signature, resolves its user and attaches
+// the principal to the request.
+func authenticate(w http.ResponseWriter, r *http.Request) (*Principal, *http.Request, error) {
+
+	var principal *Principal
+	if identity, ok := signedKeyOf(r); ok {
+		keyPrincipal, err := apiKeyPrincipal(identity, r)
+		if err != nil {
+			return nil, r, err
+		}
+		principal = keyPrincipal
+	} else if key, ok := apiKeyOf(r); ok {
+		keyPrincipal, err := authenticateAPIKey(key, r)
+		if err != nil {
+			return nil, r, err
+		}
+		principal = keyPrincipal
+	} else {
+		claim := Claims{}
+		if err := ValidateToken(w, r, &claim); err != nil {
+			return nil, r, err
+		}
+
+		tokenPrincipal, err := resolvePrincipal(&claim)
+		if err != nil {
+			return nil, r, err
+		}
+		principal = tokenPrincipal
+	}
+
+	return principal, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal)), nil
+}
+
+func resolvePrincipal(claim *Claims) (*Principal, error) {
+
+	sessionID, _ := uuid.FromString(claim.Id)
+	principal := &Principal{UserID: claim.UserID, IsAdmin: claim.IsAdmin, IsActive: claim.IsActive}
+	if principalResolver != nil {
+		resolved, err := cachedPrincipalOf(claim.UserID)
+		if err != nil {
+			return nil, err
+		}
+		principal = resolved
+	}
+	principal.SessionID = sessionID
+
+	if claim.ImpersonatorID != nil {
+		principal.ImpersonatorID = claim.ImpersonatorID
+		principal.ReadOnly = claim.ReadOnly
+		permissions := make(map[string]bool, len(principal.Permissions))
+		for permission, granted := range principal.Permissions {
+			permissions[permission] = granted
+		}
+		for _, withheld := range role.WithheldWhileImpersonating {
+			delete(permissions, withheld)
+		}
+		principal.Permissions = permissions
+	}
+	return principal, nil
+}
+
+// cachedPrincipalOf returns a copy of the stored state of a user, resolving it again once the
+// cached one has expired.
+func cachedPrincipalOf(userID uuid.UUID) (*Principal, error) {
+
+	principalMutex.RLock()
+	cached, found := principalCache[userID]
+	principalMutex.RUnlock()
+
+	if !found || time.Now().After(cached.expiresAt) {
+		resolved, err := principalResolver.ResolvePrincipal(userID)
+		if err != nil {
+			return nil, err
+		}
+		cached = cachedPrincipal{
+			principal: *resolved,
+			expiresAt: time.Now().Add(time.Duration(config.PrincipalCacheTTLSeconds.GetInt64ValueOrDefault(30)) * time.Second),
+		}
+		storePrincipal(userID, cached)
+	}
+
+	principal := cached.principal
+	return &principal, nil
+}
+
+// storePrincipal caches the state of a user. A full cache first drops its expired entries and,
+// when none had expired, the entry expiring soonest, so it never holds more than
+// PRINCIPAL_CACHE_MAX_ENTRIES users.
+func storePrincipal(userID uuid.UUID, cached cachedPrincipal) {
+
+	principalMutex.Lock()
+	defer principalMutex.Unlock()
+
+	maxEntries := int(config.PrincipalCacheMaxEntries.GetInt64ValueOrDefault(10000))
+	if _, found := principalCache[userID]; !found && len(principalCache) >= maxEntries {
+		now := time.Now()
+		soonestID := uuid.Nil
+		var soonest time.Time
+		for cachedID, entry := range principalCache {
+			if now.After(entry.expiresAt) {
+				delete(principalCache, cachedID)
+				continue
+			}
+			if soonestID == uuid.Nil || entry.expiresAt.Before(soonest) {
+				soonestID, soonest = cachedID, entry.expiresAt
+			}
+		}
+		if len(principalCache) >= maxEntries {
+			delete(principalCache, soonestID)
+		}
+	}
+	principalCache[userID] = cached
+}
@@ -0,0 +1,14 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"os"
+	"testing"
+)
+
+// TestMain reads the configuration from the process environment, so settings left unset fall
+// back to their defaults.
+func TestMain(m *testing.M) {
+	config.InitializeGlobalConfig(config.Environment("test"))
+	os.Exit(m.Run())
+}
@@ -0,0 +1,48 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// SessionChecker reports whether the session an access token was issued for is still live.
+type SessionChecker interface {
+	IsSessionActive(sessionID uuid.UUID) bool
+}
+
+var sessionChecker SessionChecker
+
+// RegisterSessionChecker makes ValidateToken reject access tokens of revoked or expired sessions.
+func RegisterSessionChecker(checker SessionChecker) {
+	sessionChecker = checker
+}
+
+func isSessionRevoked(claim *Claims) bool {
+	if sessionChecker == nil {
+		return false
+	}
+	sessionID, err := uuid.FromString(claim.Id)
+	if err != nil {
+		return true
+	}
+	return !sessionChecker.IsSessionActive(sessionID)
+}
+
+// GenerateOpaqueToken returns a random URL-safe token for refresh tokens and one-time links.
+func GenerateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashOpaqueToken is how opaque tokens are stored and looked up; the token itself is never kept.
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/web"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureScheme starts the Authorization header of signed requests:
+//
+//	Authorization: HMAC-SHA256 keyId=<key prefix>,timestamp=<unix seconds>,nonce=<nonce>,signature=<signature>
+//
+// The signature is the base64 HMAC-SHA256, keyed with the signing secret of the API key, of
+//
+//	METHOD \n path with query \n timestamp \n nonce \n hex SHA-256 of the body
+const SignatureScheme = "HMAC-SHA256"
+
+var nonceFormat = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+type signedKeyContextKey struct{}
+
+// MiddlewareSignature verifies signed requests and leaves every other request to the token and
+// API key checks. A signed request must be fresh and its nonce unused, so it can not be replayed.
+func MiddlewareSignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if !strings.HasPrefix(r.Header.Get("Authorization"), SignatureScheme+" ") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		identity, err := verifySignature(r)
+		if err != nil {
+			if identity != nil {
+				recordAPIKeyUsage(identity, r, http.StatusUnauthorized)
+			}
+			web.RespondError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), signedKeyContextKey{}, identity)))
+	})
+}
+
+// StringToSign returns what the signature of a request covers.
+func StringToSign(method, requestURI, timestamp, nonce string, body []byte) string {
+	digest := sha256.Sum256(body)
+	return strings.Join([]string{strings.ToUpper(method), requestURI, timestamp, nonce, hex.EncodeToString(digest[:])}, "\n")
+}
+
+// SignRequest computes the signature of a request with secret.
+func SignRequest(secret []byte, method, requestURI, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(StringToSign(method, requestURI, timestamp, nonce, body)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signedKeyOf returns the API key a signed request was verified for by MiddlewareSignature.
+func signedKeyOf(r *http.Request) (*APIKeyIdentity, bool) {
+	identity, ok := r.Context().Value(signedKeyContextKey{}).(*APIKeyIdentity)
+	return identity, ok
+}
+
+// verifySignature checks a signed request. The identity is returned with the error once the key
+// is known, so refusals show in its usage log.
+func verifySignature(r *http.Request) (*APIKeyIdentity, error) {
+
+	invalidSignature := errors.NewUnauthorizedError("Invalid request signature")
+
+	if apiKeyAuthenticator == nil {
+		return nil, errors.NewUnauthorizedError("Signed requests are not accepted")
+	}
+
+	parameters := signatureParameters(strings.TrimPrefix(r.Header.Get("Authorization"), SignatureScheme+" "))
+	keyID, timestamp, nonce, signature := parameters["keyId"], parameters["timestamp"], parameters["nonce"], parameters["signature"]
+	if keyID == "" || timestamp == "" || signature == "" || !nonceFormat.MatchString(nonce) {
+		return nil, errors.NewUnauthorizedError("Signed requests need keyId, timestamp, nonce and signature")
+	}
+
+	identity, secret, err := apiKeyAuthenticator.APIKeySigningSecret(keyID)
+	if err != nil {
+		return identity, err
+	}
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return identity, invalidSignature
+	}
+	now := time.Now()
+	maxSkew := time.Duration(config.SignatureMaxSkewSeconds.GetInt64ValueOrDefault(300)) * time.Second
+	if skew := now.Sub(time.Unix(signedAt, 0)); skew > maxSkew || skew < -maxSkew {
+		return identity, errors.NewUnauthorizedError("Request timestamp is outside the allowed clock skew")
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, config.SignatureMaxBodyBytes.GetInt64ValueOrDefault(1<<20)))
+	if err != nil {
+		return identity, errors.NewHTTPError("Request body is too large to be signed", http.StatusRequestEntityTooLarge)
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return identity, invalidSignature
+	}
+
+	// The nonce is only spent on a valid signature, so nobody else can burn the nonces of a key.
+	if err := apiKeyAuthenticator.UseAPIKeyNonce(identity.KeyID, nonce, now); err != nil {
+		return identity, err
+	}
+	return identity, nil
+}
+
+// signatureParameters parses the comma separated name=value pairs of a signed Authorization header.
+func signatureParameters(header string) map[string]string {
+	parameters := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if found {
+			parameters[name] = strings.Trim(value, `"`)
+		}
+	}
+	return parameters
+}
@@ -0,0 +1,138 @@
+package security
+
+import (
+	"banking-app-be/components/errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func TestStringToSign(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   string
+	}{
+		{
+			name:   "body",
+			method: "post",
+			body:   `{"amount":100}`,
+			want: "POST\n/api/v1/banking-app/account/1/transfer?dry=1\n1700000000\nabcdefghijklmnop\n" +
+				"4d4bbe59c6aad22442cde199a6a8a5f034405fcd78fb5a81c24ef249de1c45f1",
+		},
+		{
+			name:   "no body",
+			method: "GET",
+			want: "GET\n/api/v1/banking-app/account/1/transfer?dry=1\n1700000000\nabcdefghijklmnop\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := StringToSign(test.method, "/api/v1/banking-app/account/1/transfer?dry=1", "1700000000", "abcdefghijklmnop", []byte(test.body))
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+
+	got := SignRequest([]byte("signing-secret"), "POST", "/api/v1/banking-app/account/1/transfer?dry=1",
+		"1700000000", "abcdefghijklmnop", []byte(`{"amount":100}`))
+
+	if want := "e8gi9GNoyUlSDXEgXzUT7PWe+AQNcnxWQ4yFC2gWfvw="; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// stubSigningKeys knows a single key and remembers the nonces spent with it.
+type stubSigningKeys struct {
+	prefix string
+	secret []byte
+	keyID  uuid.UUID
+	used   map[string]bool
+}
+
+func (s *stubSigningKeys) AuthenticateAPIKey(key string) (*APIKeyIdentity, error) {
+	return nil, errors.NewUnauthorizedError("Invalid API key")
+}
+
+func (s *stubSigningKeys) APIKeySigningSecret(prefix string) (*APIKeyIdentity, []byte, error) {
+	if prefix != s.prefix {
+		return nil, nil, errors.NewUnauthorizedError("Invalid API key")
+	}
+	return &APIKeyIdentity{KeyID: s.keyID}, s.secret, nil
+}
+
+func (s *stubSigningKeys) UseAPIKeyNonce(keyID uuid.UUID, nonce string, usedAt time.Time) error {
+	if s.used[nonce] {
+		return errors.NewUnauthorizedError("Request nonce has already been used")
+	}
+	s.used[nonce] = true
+	return nil
+}
+
+func (s *stubSigningKeys) RecordAPIKeyUsage(keyID uuid.UUID, request APIKeyRequest) {}
+
+func TestVerifySignature(t *testing.T) {
+
+	keys := &stubSigningKeys{prefix: "bk_3f9a1c7e02d4", secret: []byte("signing-secret"), keyID: uuid.NewV4(), used: map[string]bool{}}
+	previous := apiKeyAuthenticator
+	RegisterAPIKeyAuthenticator(keys)
+	t.Cleanup(func() {
+		RegisterAPIKeyAuthenticator(previous)
+	})
+
+	const path = "/api/v1/banking-app/account/1/transfer"
+	const body = `{"amount":100}`
+	now := time.Now()
+	signed := func(signedAt time.Time, nonce string) *http.Request {
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		signature := SignRequest(keys.secret, http.MethodPost, path, timestamp, nonce, []byte(body))
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		r.Header.Set("Authorization", SignatureScheme+" keyId="+keys.prefix+",timestamp="+timestamp+
+			",nonce="+nonce+",signature="+signature)
+		return r
+	}
+	tampered := signed(now, "tampered-nonce-0001")
+	tampered.Body = http.NoBody
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		err     string
+	}{
+		{name: "fresh request", request: signed(now, "fresh-nonce-00001")},
+		{name: "replayed nonce", request: signed(now, "fresh-nonce-00001"), err: "already been used"},
+		{name: "timestamp beyond the skew", request: signed(now.Add(-301*time.Second), "stale-nonce-00001"), err: "clock skew"},
+		{name: "timestamp ahead of the skew", request: signed(now.Add(301*time.Second), "early-nonce-00001"), err: "clock skew"},
+		{name: "timestamp within the skew", request: signed(now.Add(-290*time.Second), "late-nonce-000001")},
+		{name: "body changed after signing", request: tampered, err: "Invalid request signature"},
+		{name: "nonce too short", request: signed(now, "short"), err: "need keyId"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := verifySignature(test.request)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
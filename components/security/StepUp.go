@@ -0,0 +1,65 @@
+package security
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"context"
+	"fmt"
+	"net/http"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// StepUp is the extra proof a user sends with a high-value operation: their transaction PIN or
+// a fresh code from their authenticator.
+type StepUp struct {
+	PIN     string `json:"transactionPin,omitempty" example:"4321"`
+	MFACode string `json:"mfaCode,omitempty" example:"123456"`
+	// keyLimit is the transaction limit of the API key the operation is made with, if any.
+	keyLimit *float32
+}
+
+// StepUpVerifier checks step-up proofs against what the user has set up.
+type StepUpVerifier interface {
+	VerifyStepUp(ctx context.Context, userID uuid.UUID, proof StepUp) error
+}
+
+var stepUpVerifier StepUpVerifier
+
+// RegisterStepUpVerifier lets RequireStepUp check PINs and MFA codes.
+func RegisterStepUpVerifier(verifier StepUpVerifier) {
+	stepUpVerifier = verifier
+}
+
+// StepUpThreshold returns the amount above which operations need step-up.
+func StepUpThreshold() float32 {
+	return float32(config.StepUpThresholdAmount.GetInt64ValueOrDefault(50000))
+}
+
+// StepUpFor completes proof with what the request was authenticated with. API keys can not answer
+// step-up, so they are held to the limit set, with step-up, when the key was issued.
+func StepUpFor(r *http.Request, proof StepUp) StepUp {
+	if principal, err := CurrentPrincipal(r); err == nil && principal.APIKey != nil {
+		limit := principal.APIKey.MaxAmount
+		proof.keyLimit = &limit
+	}
+	return proof
+}
+
+// RequireStepUp lets operations of up to the threshold through and checks proof for larger ones.
+// Operations made with an API key are checked against the key's limit instead.
+func RequireStepUp(ctx context.Context, userID uuid.UUID, amount float32, proof StepUp) error {
+	if proof.keyLimit != nil {
+		if amount > *proof.keyLimit {
+			return errors.NewOutOfScopeError(fmt.Sprintf("Amount exceeds the limit of %.2f set for this API key", *proof.keyLimit))
+		}
+		return nil
+	}
+	if amount <= StepUpThreshold() {
+		return nil
+	}
+	if stepUpVerifier == nil {
+		return errors.NewHTTPError("Step-up authentication is not available", http.StatusInternalServerError)
+	}
+	return stepUpVerifier.VerifyStepUp(ctx, userID, proof)
+}
@@ -1,13 +1,9 @@
 package security
 
 import (
-	"banking-app-be/components/config"
 	"banking-app-be/components/errors"
 	"banking-app-be/components/log"
-	"banking-app-be/components/util"
-	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/golang-jwt/jwt"
 	uuid "github.com/satori/go.uuid"
@@ -17,53 +13,29 @@ type Claims struct {
 	UserID   uuid.UUID
 	IsAdmin  bool
 	IsActive bool
+	// ImpersonatorID names the admin acting as the user; ReadOnly limits them to reading.
+	ImpersonatorID *uuid.UUID `json:",omitempty"`
+	ReadOnly       bool       `json:",omitempty"`
 	jwt.StandardClaims
 }
 
+// GenerateToken signs the claims with the active key and names it in the kid header.
 func (c *Claims) GenerateToken() (string, error) {
+	keySet := currentKeys()
+	if keySet == nil {
+		log.GetLogger().Error("signing keys are not loaded")
+		return "", errors.NewHTTPError("unable to generate token", http.StatusInternalServerError)
+	}
+
 	// NewWithClaims returns token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	token := jwt.NewWithClaims(keySet.Active.Method, c)
+	token.Header["kid"] = keySet.Active.ID
 
 	// access token string based on token
-	tokenString, err := token.SignedString([]byte(config.JWTKey.GetStringValue()))
+	tokenString, err := token.SignedString(keySet.Active.Private)
 	if err != nil {
 		log.GetLogger().Error(err.Error())
 		return "", errors.NewHTTPError("unable to generate token", http.StatusInternalServerError)
 	}
 	return tokenString, nil
 }
-
-func ExtractUserIDFromToken(r *http.Request) (uuid.UUID, error) {
-
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		return uuid.Nil, errors.NewHTTPError("token not provided", http.StatusUnauthorized)
-	}
-
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-	tokenStr = strings.TrimSpace(tokenStr)
-
-	fmt.Println("Extracted Token: ", tokenStr)
-
-	token, err := jwt.Parse(tokenStr,
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, errors.NewHTTPError(errors.ErrorCodeInternalError, http.StatusInternalServerError)
-			}
-			return []byte(config.JWTKey.GetStringValue()), nil
-		})
-	if err != nil {
-		return uuid.Nil, errors.NewHTTPError(err.Error(), http.StatusUnauthorized)
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		id, _ := claims["UserID"].(string)
-		userID, err := util.ParseUUID(id)
-		if err != nil {
-			return uuid.Nil, err
-		}
-		return userID, nil
-	}
-
-	return uuid.Nil, errors.NewHTTPError("Invalid token.", http.StatusUnauthorized)
-}
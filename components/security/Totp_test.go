@@ -0,0 +1,47 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPVerifier(t *testing.T) {
+
+	// The RFC 6238 test key "12345678901234567890", base32 encoded.
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	now := time.Date(2024, time.March, 1, 12, 0, 15, 0, time.UTC)
+	current := TOTPCounter(now)
+	verifier := NewTOTPVerifier(func() time.Time { return now })
+
+	tests := []struct {
+		name     string
+		counter  int64
+		lastUsed int64
+		ok       bool
+	}{
+		{name: "current step", counter: current, ok: true},
+		{name: "previous step", counter: current - TOTPSkew, ok: true},
+		{name: "next step", counter: current + TOTPSkew, ok: true},
+		{name: "outside the window", counter: current - TOTPSkew - 1, ok: false},
+		{name: "replayed code", counter: current, lastUsed: current, ok: false},
+		{name: "code older than the last used one", counter: current - TOTPSkew, lastUsed: current, ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := GenerateTOTP(secret, test.counter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			counter, ok := verifier.Verify(secret, code, test.lastUsed)
+
+			if ok != test.ok {
+				t.Fatalf("got ok %v, want %v", ok, test.ok)
+			}
+			if ok && counter != test.counter {
+				t.Errorf("got step %d, want %d", counter, test.counter)
+			}
+		})
+	}
+}
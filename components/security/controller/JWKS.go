@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// JWKSController publishes the public token keys so other services can verify access tokens.
+type JWKSController struct {
+	log log.Logger
+}
+
+func NewJWKSController(log log.Logger) *JWKSController {
+	return &JWKSController{
+		log: log,
+	}
+}
+
+func (controller *JWKSController) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/.well-known/jwks.json", controller.getJWKS).Methods(http.MethodGet)
+}
+
+func (controller *JWKSController) getJWKS(w http.ResponseWriter, r *http.Request) {
+	// Short enough for verifiers to pick up a rotated key before tokens signed with it arrive.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	web.RespondJSON(w, http.StatusOK, security.CurrentJWKS())
+}
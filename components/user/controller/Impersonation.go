@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/session"
+	"net/http"
+)
+
+func (controller *UserController) impersonateUser(w http.ResponseWriter, r *http.Request) {
+
+	requestData := session.ImpersonationRequest{}
+	impersonation := session.Impersonation{}
+	parser := web.NewParser(r)
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	impersonatorID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SessionService.Impersonate(r.Context(), impersonatorID, userID, &requestData, newClientSession(r), &impersonation); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, impersonation)
+}
+
+func (controller *UserController) getImpersonations(w http.ResponseWriter, r *http.Request) {
+
+	allSessions := []session.Session{}
+
+	if err := controller.SessionService.GetImpersonations(&allSessions); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(allSessions), allSessions)
+}
+
+func (controller *UserController) endImpersonation(w http.ResponseWriter, r *http.Request) {
+
+	parser := web.NewParser(r)
+
+	sessionID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid session ID format"))
+		return
+	}
+
+	endedBy, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SessionService.EndImpersonation(r.Context(), endedBy, sessionID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Impersonation ended"})
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/login"
+	"banking-app-be/model/user"
+	"net/http"
+	"strconv"
+)
+
+func (controller *UserController) getLoginHistory(w http.ResponseWriter, r *http.Request) {
+
+	history := []login.Attempt{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.LoginGuard.GetLoginHistory(userID, &history, &totalCount, limit, offset); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, history)
+}
+
+func (controller *UserController) unlockUser(w http.ResponseWriter, r *http.Request) {
+
+	userToUnlock := user.User{}
+	parser := web.NewParser(r)
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+	userToUnlock.ID = userID
+
+	userToUnlock.UpdatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.UserService.Unlock(r.Context(), &userToUnlock, security.CurrentBankScope(r)); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "User unlocked"})
+}
@@ -0,0 +1,221 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/mfa"
+	"banking-app-be/model/session"
+	"net/http"
+)
+
+type mfaCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfaToken"`
+	Code     string `json:"code" example:"123456"`
+}
+
+func (controller *UserController) getMFAStatus(w http.ResponseWriter, r *http.Request) {
+
+	status := mfa.Status{}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.GetStatus(userID, &status); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, status)
+}
+
+func (controller *UserController) beginMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+
+	enrollment := mfa.Enrollment{}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.BeginEnrollment(r.Context(), userID, &enrollment); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, enrollment)
+}
+
+func (controller *UserController) confirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+
+	requestData := mfaCodeRequest{}
+	backupCodes := []string{}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.ConfirmEnrollment(r.Context(), userID, requestData.Code, &backupCodes); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":     "MFA enabled; store the backup codes somewhere safe, they are not shown again",
+		"backupCodes": backupCodes,
+	})
+}
+
+func (controller *UserController) regenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+
+	requestData := mfaCodeRequest{}
+	backupCodes := []string{}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.RegenerateBackupCodes(r.Context(), userID, requestData.Code, &backupCodes); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"backupCodes": backupCodes,
+	})
+}
+
+func (controller *UserController) disableMFA(w http.ResponseWriter, r *http.Request) {
+
+	requestData := mfaCodeRequest{}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.Disable(r.Context(), userID, requestData.Code); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "MFA disabled"})
+}
+
+func (controller *UserController) getMFAPolicy(w http.ResponseWriter, r *http.Request) {
+
+	policy := mfa.Policy{}
+
+	if err := controller.MFAService.GetPolicy(&policy); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, policy)
+}
+
+func (controller *UserController) updateMFAPolicy(w http.ResponseWriter, r *http.Request) {
+
+	policy := mfa.Policy{}
+
+	if err := web.UnmarshalJSON(r, &policy); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	var err error
+	policy.UpdatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.MFAService.UpdatePolicy(r.Context(), &policy); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, policy)
+}
+
+// enrollMFAForLogin serves users the policy forces to use MFA but who have no authenticator
+// yet; they enroll with the token of their login challenge.
+func (controller *UserController) enrollMFAForLogin(w http.ResponseWriter, r *http.Request) {
+
+	requestData := mfaLoginRequest{}
+	enrollment := mfa.Enrollment{}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := controller.MFAService.EnrollForLogin(r.Context(), requestData.MFAToken, &enrollment); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, enrollment)
+}
+
+// completeMFALogin is the second step of login: the challenge token plus an authenticator or
+// backup code.
+func (controller *UserController) completeMFALogin(w http.ResponseWriter, r *http.Request) {
+
+	requestData := mfaLoginRequest{}
+	tokens := session.TokenPair{}
+	backupCodes := []string{}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	err := controller.MFAService.CompleteLogin(r.Context(), requestData.MFAToken, requestData.Code, newClientSession(r), &tokens, &backupCodes)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+
+	response := map[string]interface{}{
+		"message":      "Login successful",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+	}
+	if len(backupCodes) > 0 {
+		response["backupCodes"] = backupCodes
+	}
+	web.RespondJSON(w, http.StatusAccepted, response)
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/passwordpolicy"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"net/http"
+)
+
+func (controller *UserController) changePassword(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	principal, err := security.CurrentPrincipal(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	err = controller.PasswordService.ChangePassword(r.Context(), principal.UserID, principal.SessionID, requestData.CurrentPassword, requestData.NewPassword)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Password changed, other sessions have been signed out"})
+}
+
+// getPasswordPolicy lets clients show the rules before a password is submitted.
+func (controller *UserController) getPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	web.RespondJSON(w, http.StatusOK, passwordpolicy.Current())
+}
+
+func (controller *UserController) forgotPassword(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		Email string `json:"email"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := controller.PasswordService.RequestReset(r.Context(), requestData.Email, newClientSession(r).IPAddress); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If an account uses this email, a password reset link has been sent to it",
+	})
+}
+
+func (controller *UserController) resetPassword(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	if err := controller.PasswordService.ResetPassword(r.Context(), requestData.Token, requestData.Password); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Password reset, please log in again"})
+}
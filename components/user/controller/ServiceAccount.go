@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/apikey"
+	"net/http"
+	"strconv"
+)
+
+func (controller *UserController) getServiceAccounts(w http.ResponseWriter, r *http.Request) {
+
+	serviceAccounts := []apikey.ServiceAccount{}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ServiceAccountService.GetServiceAccounts(userID, &serviceAccounts); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(serviceAccounts), serviceAccounts)
+}
+
+func (controller *UserController) createServiceAccount(w http.ResponseWriter, r *http.Request) {
+
+	newServiceAccount := apikey.ServiceAccount{}
+
+	if err := web.UnmarshalJSON(r, &newServiceAccount); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ServiceAccountService.CreateServiceAccount(r.Context(), userID, &newServiceAccount); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newServiceAccount)
+}
+
+func (controller *UserController) deactivateServiceAccount(w http.ResponseWriter, r *http.Request) {
+
+	serviceAccountID, err := web.NewParser(r).GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid service account ID format"))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ServiceAccountService.DeactivateServiceAccount(r.Context(), userID, serviceAccountID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Service account deactivated"})
+}
+
+func (controller *UserController) issueAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		apikey.NewKey
+		security.StepUp
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	serviceAccountID, err := web.NewParser(r).GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid service account ID format"))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	issuedKey := apikey.IssuedKey{}
+	if err := controller.ServiceAccountService.IssueAPIKey(r.Context(), userID, serviceAccountID, &requestData.NewKey, requestData.StepUp, &issuedKey); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, issuedKey)
+}
+
+func (controller *UserController) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	parser := web.NewParser(r)
+
+	serviceAccountID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid service account ID format"))
+		return
+	}
+
+	keyID, err := parser.GetUUID("keyId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid API key ID format"))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ServiceAccountService.RevokeAPIKey(r.Context(), userID, serviceAccountID, keyID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "API key revoked"})
+}
+
+func (controller *UserController) getAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+
+	usage := []apikey.Usage{}
+	var totalCount int
+	parser := web.NewParser(r)
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	serviceAccountID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid service account ID format"))
+		return
+	}
+
+	keyID, err := parser.GetUUID("keyId")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid API key ID format"))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.ServiceAccountService.GetAPIKeyUsage(userID, serviceAccountID, keyID, &usage, &totalCount, limit, offset); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, usage)
+}
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/components/web"
+	"banking-app-be/model/pin"
+	"net/http"
+)
+
+func (controller *UserController) getTransactionPINStatus(w http.ResponseWriter, r *http.Request) {
+
+	status := pin.Status{}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.StepUpService.GetPINStatus(userID, &status); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, status)
+}
+
+func (controller *UserController) setTransactionPIN(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		Password string `json:"password"`
+		PIN      string `json:"transactionPin" example:"4321"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.StepUpService.SetPIN(r.Context(), userID, requestData.Password, requestData.PIN); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Transaction PIN set"})
+}
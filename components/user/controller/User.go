@@ -5,25 +5,53 @@ import (
 	"banking-app-be/components/log"
 	"banking-app-be/components/security"
 	"banking-app-be/components/web"
+	"banking-app-be/model/approval"
 	"banking-app-be/model/credential"
+	"banking-app-be/model/invitation"
+	"banking-app-be/model/mfa"
+	"banking-app-be/model/role"
+	"banking-app-be/model/session"
 	"banking-app-be/model/user"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 
+	approvalService "banking-app-be/components/approval/service"
 	userService "banking-app-be/components/user/service"
 
 	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
 )
 
 type UserController struct {
-	log         log.Logger
-	UserService *userService.UserService
+	log             log.Logger
+	UserService     *userService.UserService
+	SessionService  *userService.SessionService
+	MFAService      *userService.MFAService
+	StepUpService   *userService.StepUpService
+	LoginGuard      *userService.LoginGuardService
+	PasswordService *userService.PasswordService
+
+	ServiceAccountService *userService.ServiceAccountService
+	ApprovalService       *approvalService.ApprovalService
 }
 
-func NewUserController(userService *userService.UserService, log log.Logger) *UserController {
+func NewUserController(userService *userService.UserService, sessionService *userService.SessionService, mfaService *userService.MFAService,
+	stepUpService *userService.StepUpService, loginGuard *userService.LoginGuardService, passwordService *userService.PasswordService,
+	serviceAccountService *userService.ServiceAccountService, approvalService *approvalService.ApprovalService, log log.Logger) *UserController {
 	return &UserController{
-		log:         log,
-		UserService: userService,
+		log:             log,
+		UserService:     userService,
+		SessionService:  sessionService,
+		MFAService:      mfaService,
+		StepUpService:   stepUpService,
+		LoginGuard:      loginGuard,
+		PasswordService: passwordService,
+
+		ServiceAccountService: serviceAccountService,
+		ApprovalService:       approvalService,
 	}
 }
 
@@ -32,23 +60,80 @@ func (userController *UserController) RegisterRoutes(router *mux.Router) {
 	userRouter := router.PathPrefix("/user").Subrouter()
 	guardedRouter := userRouter.PathPrefix("/").Subrouter()
 	unguardedRouter := userRouter.PathPrefix("/").Subrouter()
+	sessionRouter := userRouter.PathPrefix("/").Subrouter()
 	commonRouter := userRouter.PathPrefix("/").Subrouter()
 
 	//Post
 	unguardedRouter.HandleFunc("/login", userController.login).Methods(http.MethodPost)
-	unguardedRouter.HandleFunc("/register-admin", userController.registerAdmin).Methods(http.MethodPost)
-	guardedRouter.HandleFunc("/register-user", userController.registerUser).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/login/mfa", userController.completeMFALogin).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/login/mfa/enroll", userController.enrollMFAForLogin).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/token/refresh", userController.refreshToken).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/password/policy", userController.getPasswordPolicy).Methods(http.MethodGet)
+	unguardedRouter.HandleFunc("/password/forgot", userController.forgotPassword).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/password/reset", userController.resetPassword).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/setup", userController.bootstrapAdmin).Methods(http.MethodPost)
+	unguardedRouter.HandleFunc("/invitation/accept", userController.acceptInvitation).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/register-admin", security.Authorize(userController.registerAdmin, role.AdminCreate)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/register-user", security.Authorize(userController.registerUser, role.UserCreate)).Methods(http.MethodPost)
+	//MFA policy
+	guardedRouter.HandleFunc("/mfa/policy", security.Authorize(userController.getMFAPolicy, role.MFAPolicyManage)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/mfa/policy", security.Authorize(userController.updateMFAPolicy, role.MFAPolicyManage)).Methods(http.MethodPut)
+	//Invitations
+	guardedRouter.HandleFunc("/invitation", security.Authorize(userController.inviteAdmin, role.AdminCreate)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/invitation", security.Authorize(userController.getInvitations, role.AdminCreate)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/invitation/{id}", security.Authorize(userController.revokeInvitation, role.AdminCreate)).Methods(http.MethodDelete)
 	// Get
-	guardedRouter.HandleFunc("/", userController.getAllUsers).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/", security.Authorize(userController.getAllUsers, role.UserRead)).Methods(http.MethodGet)
+	//Roles
+	guardedRouter.HandleFunc("/role", security.Authorize(userController.getRoleCatalog, role.UserRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}/role", security.Authorize(userController.getUserRoles, role.UserRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}/role", security.Authorize(userController.assignUserRoles, role.UserRoleAssign)).Methods(http.MethodPut)
+	//Bank scope
+	guardedRouter.HandleFunc("/{id}/bank-scope", security.Authorize(userController.getUserBankScope, role.UserRead)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/{id}/bank-scope", security.Authorize(userController.assignUserBankScope, role.UserRoleAssign)).Methods(http.MethodPut)
+
+	guardedRouter.HandleFunc("/{id}/unlock", security.Authorize(userController.unlockUser, role.UserUnlock)).Methods(http.MethodPost)
+	//Impersonation
+	guardedRouter.HandleFunc("/impersonation", security.Authorize(userController.getImpersonations, role.UserImpersonate)).Methods(http.MethodGet)
+	guardedRouter.HandleFunc("/impersonation/{id}/end", security.Authorize(userController.endImpersonation, role.UserImpersonate)).Methods(http.MethodPost)
+	guardedRouter.HandleFunc("/{id}/impersonate", security.Authorize(userController.impersonateUser, role.UserImpersonate)).Methods(http.MethodPost)
 	//Update
-	guardedRouter.HandleFunc("/{id}", userController.updateUserById).Methods(http.MethodPut)
+	guardedRouter.HandleFunc("/{id}", security.Authorize(userController.updateUserById, role.UserUpdate)).Methods(http.MethodPut)
 	// Delete
-	guardedRouter.HandleFunc("/{id}", userController.deleteUserById).Methods(http.MethodDelete)
-	guardedRouter.Use(security.MiddlewareAdmin)
+	guardedRouter.HandleFunc("/{id}", security.Authorize(userController.deleteUserById, role.UserDelete)).Methods(http.MethodDelete)
+	guardedRouter.Use(security.MiddlewareActive)
 
 	//===================================
 
-	commonRouter.HandleFunc("/{id}", userController.getUserById).Methods(http.MethodGet)
+	//Sessions
+	sessionRouter.HandleFunc("/logout", security.Authorize(userController.logout, role.SessionManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/logout-all", security.Authorize(userController.logoutAll, role.SessionManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/session", security.Authorize(userController.getSessions, role.SessionManageOwn)).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/login-history", security.Authorize(userController.getLoginHistory, role.SessionManageOwn)).Methods(http.MethodGet)
+	//Password
+	sessionRouter.HandleFunc("/password/change", security.Authorize(userController.changePassword, role.PasswordChangeOwn)).Methods(http.MethodPost)
+	//MFA
+	sessionRouter.HandleFunc("/mfa", security.Authorize(userController.getMFAStatus, role.MFAManageOwn)).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/mfa/enroll", security.Authorize(userController.beginMFAEnrollment, role.MFAManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/mfa/confirm", security.Authorize(userController.confirmMFAEnrollment, role.MFAManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/mfa/backup-codes", security.Authorize(userController.regenerateBackupCodes, role.MFAManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/mfa/disable", security.Authorize(userController.disableMFA, role.MFAManageOwn)).Methods(http.MethodPost)
+	//Transaction PIN
+	sessionRouter.HandleFunc("/transaction-pin", security.Authorize(userController.getTransactionPINStatus, role.AccountTransact)).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/transaction-pin", security.Authorize(userController.setTransactionPIN, role.AccountTransact)).Methods(http.MethodPost)
+	//Service Accounts
+	sessionRouter.HandleFunc("/service-account", security.Authorize(userController.getServiceAccounts, role.ServiceAccountManageOwn)).Methods(http.MethodGet)
+	sessionRouter.HandleFunc("/service-account", security.Authorize(userController.createServiceAccount, role.ServiceAccountManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/service-account/{id}/deactivate", security.Authorize(userController.deactivateServiceAccount, role.ServiceAccountManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/service-account/{id}/key", security.Authorize(userController.issueAPIKey, role.ServiceAccountManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/service-account/{id}/key/{keyId}/revoke", security.Authorize(userController.revokeAPIKey, role.ServiceAccountManageOwn)).Methods(http.MethodPost)
+	sessionRouter.HandleFunc("/service-account/{id}/key/{keyId}/usage", security.Authorize(userController.getAPIKeyUsage, role.ServiceAccountManageOwn)).Methods(http.MethodGet)
+	sessionRouter.Use(security.MiddlewareActive)
+
+	//===================================
+
+	commonRouter.HandleFunc("/{id}/export", security.Authorize(userController.exportUser, role.ProfileReadOwn)).Methods(http.MethodGet)
+	commonRouter.HandleFunc("/{id}", security.Authorize(userController.getUserById, role.ProfileReadOwn)).Methods(http.MethodGet)
 	commonRouter.Use(security.MiddlewareActive)
 }
 
@@ -62,7 +147,146 @@ func (controller *UserController) registerAdmin(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	err = controller.UserService.CreateAdmin(&newUser)
+	newUser.CreatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	if newUser.Credentials != nil {
+		newUser.Credentials.CreatedBy = newUser.CreatedBy
+	}
+
+	err = controller.UserService.CreateAdmin(r.Context(), &newUser)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newUser)
+}
+
+// bootstrapAdmin creates the first super-admin; the setup token from the configuration is
+// expected in the X-Setup-Token header.
+func (controller *UserController) bootstrapAdmin(w http.ResponseWriter, r *http.Request) {
+
+	newUser := user.User{}
+
+	err := web.UnmarshalJSON(r, &newUser)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	err = controller.UserService.Bootstrap(r.Context(), r.Header.Get("X-Setup-Token"), &newUser)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newUser)
+}
+
+func (controller *UserController) inviteAdmin(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		Email string `json:"email"`
+		Role  string `json:"role" example:"BANK_ADMIN"`
+	}
+
+	err := web.UnmarshalJSON(r, &requestData)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	newInvitation := invitation.IssuedInvitation{}
+	newInvitation.Email = requestData.Email
+	newInvitation.Role = requestData.Role
+
+	newInvitation.CreatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.UserService.CreateInvitation(r.Context(), &newInvitation); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusCreated, newInvitation)
+}
+
+func (controller *UserController) getInvitations(w http.ResponseWriter, r *http.Request) {
+	allInvitations := []invitation.Invitation{}
+	var totalCount int
+	query := r.URL.Query()
+
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 5 //default
+	}
+
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0 //default
+	}
+
+	err = controller.UserService.GetInvitations(&allInvitations, &totalCount, limit, offset)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allInvitations)
+}
+
+func (controller *UserController) revokeInvitation(w http.ResponseWriter, r *http.Request) {
+
+	invitationToRevoke := invitation.Invitation{}
+	parser := web.NewParser(r)
+
+	var err error
+	invitationToRevoke.UpdatedBy, err = security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	invitationToRevoke.ID, err = parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid invitation ID format"))
+		return
+	}
+
+	if err := controller.UserService.RevokeInvitation(r.Context(), &invitationToRevoke); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Invitation revoked successfully"})
+}
+
+// acceptInvitation creates the invited administrator from the token of the invitation link and
+// the profile and password they chose.
+func (controller *UserController) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		Token string `json:"token"`
+		user.User
+	}
+
+	err := web.UnmarshalJSON(r, &requestData)
+	if err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	newUser := requestData.User
+	err = controller.UserService.AcceptInvitation(r.Context(), requestData.Token, &newUser)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -81,7 +305,7 @@ func (controller *UserController) registerUser(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	newUser.CreatedBy, err = security.ExtractUserIDFromToken(r)
+	newUser.CreatedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -90,7 +314,7 @@ func (controller *UserController) registerUser(w http.ResponseWriter, r *http.Re
 
 	newUser.Credentials.CreatedBy = newUser.CreatedBy
 
-	err = controller.UserService.CreateUser(&newUser)
+	err = controller.UserService.CreateUser(r.Context(), &newUser)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -102,7 +326,7 @@ func (controller *UserController) registerUser(w http.ResponseWriter, r *http.Re
 func (controller *UserController) login(w http.ResponseWriter, r *http.Request) {
 
 	userCredentials := credential.Credential{}
-	claim := security.Claims{}
+	tokens := session.TokenPair{}
 
 	err := web.UnmarshalJSON(r, &userCredentials)
 	if err != nil {
@@ -110,27 +334,111 @@ func (controller *UserController) login(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = controller.UserService.Login(&userCredentials, &claim)
+	challenge := mfa.Challenge{}
+	err = controller.UserService.Login(r.Context(), &userCredentials, newClientSession(r), &tokens, &challenge)
 	if err != nil {
 		web.RespondError(w, err)
 		return
 	}
 
-	token, err := claim.GenerateToken()
-	if err != nil {
-		web.RespondError(w, err)
+	// The password was right but a second factor is still owed; see completeMFALogin.
+	if challenge.Token != "" {
+		web.RespondJSON(w, http.StatusOK, map[string]interface{}{
+			"message":            "MFA code required",
+			"mfaRequired":        true,
+			"mfaToken":           challenge.Token,
+			"enrollmentRequired": challenge.EnrollmentRequired,
+			"expiresAt":          challenge.ExpiresAt,
+		})
 		return
 	}
 
 	// w.Header().Set("token", token)
-	w.Header().Set("Authorization", "Bearer "+token)
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
 
-	web.RespondJSON(w, http.StatusAccepted, map[string]string{
-		"message": "Login successful",
-		"token":   token,
+	web.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message":      "Login successful",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
 	})
 }
 
+func (controller *UserController) refreshToken(w http.ResponseWriter, r *http.Request) {
+
+	var requestData struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	tokens := session.TokenPair{}
+
+	err := web.UnmarshalJSON(r, &requestData)
+	if err != nil || requestData.RefreshToken == "" {
+		web.RespondError(w, errors.NewHTTPError("refresh token must be specified", http.StatusBadRequest))
+		return
+	}
+
+	err = controller.SessionService.Refresh(r.Context(), requestData.RefreshToken, newClientSession(r), &tokens)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Authorization", "Bearer "+tokens.AccessToken)
+	web.RespondJSON(w, http.StatusOK, tokens)
+}
+
+func (controller *UserController) logout(w http.ResponseWriter, r *http.Request) {
+
+	principal, err := security.CurrentPrincipal(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SessionService.Logout(r.Context(), principal.UserID, principal.SessionID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
+}
+
+func (controller *UserController) logoutAll(w http.ResponseWriter, r *http.Request) {
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SessionService.LogoutAll(r.Context(), userID); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSON(w, http.StatusOK, map[string]string{"message": "Logged out of all sessions"})
+}
+
+func (controller *UserController) getSessions(w http.ResponseWriter, r *http.Request) {
+
+	allSessions := []session.Session{}
+
+	userID, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.SessionService.GetActiveSessions(userID, &allSessions); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(allSessions), allSessions)
+}
+
 func (controller *UserController) getAllUsers(w http.ResponseWriter, r *http.Request) {
 	allUsers := &[]user.UserDTO{}
 	var totalCount int
@@ -149,12 +457,17 @@ func (controller *UserController) getAllUsers(w http.ResponseWriter, r *http.Req
 		offset = 0 //default
 	}
 
-	err = controller.UserService.GetAllUsers(allUsers, &totalCount, limit, offset)
+	err = controller.UserService.GetAllUsers(security.CurrentBankScope(r), allUsers, &totalCount, limit, offset)
 	if err != nil {
 		controller.log.Print(err.Error())
 		web.RespondError(w, err)
 		return
 	}
+	if web.MaskAccountNumbers(r) {
+		for i := range *allUsers {
+			(*allUsers)[i].MaskAccountNumbers()
+		}
+	}
 	web.RespondJSONWithXTotalCount(w, http.StatusOK, totalCount, allUsers)
 }
 
@@ -170,9 +483,26 @@ func (controller *UserController) getUserById(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// Anyone may read their own profile; reading others needs user:read.
+	principal, err := security.CurrentPrincipal(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+	if principal.UserID != userIdFromURL && !principal.HasPermission(role.UserRead) {
+		web.RespondError(w, errors.NewForbiddenError(role.UserRead))
+		return
+	}
+
+	// Bank scope limits which other users staff can see, not their own profile.
+	scope := principal.BankScope
+	if principal.UserID == userIdFromURL {
+		scope = security.Unrestricted
+	}
+
 	targetUser.ID = userIdFromURL
 
-	err = controller.UserService.GetUserByID(targetUser)
+	err = controller.UserService.GetUserByID(targetUser, scope)
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -181,6 +511,51 @@ func (controller *UserController) getUserById(w http.ResponseWriter, r *http.Req
 	web.RespondJSON(w, http.StatusOK, targetUser)
 }
 
+// exportUser hands out everything held about a user as a JSON file. Users may export their own
+// data; exporting others' needs user:read across every bank, as the export spans banks.
+// Impersonating admins can not export, so impersonation can not be used to copy a user's data.
+func (controller *UserController) exportUser(w http.ResponseWriter, r *http.Request) {
+
+	export := user.Export{}
+	parser := web.NewParser(r)
+
+	userIdFromURL, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	principal, err := security.CurrentPrincipal(r)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+	if principal.ImpersonatorID != nil {
+		web.RespondError(w, errors.NewOutOfScopeError("Data can not be exported while impersonating"))
+		return
+	}
+	if principal.UserID != userIdFromURL {
+		if !principal.HasPermission(role.UserRead) {
+			web.RespondError(w, errors.NewForbiddenError(role.UserRead))
+			return
+		}
+		if err := principal.BankScope.CheckGlobal(); err != nil {
+			web.RespondError(w, err)
+			return
+		}
+	}
+
+	err = controller.UserService.Export(userIdFromURL, &export)
+	if err != nil {
+		controller.log.Print(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, userIdFromURL))
+	web.RespondJSON(w, http.StatusOK, export)
+}
+
 func (controller *UserController) updateUserById(w http.ResponseWriter, r *http.Request) {
 
 	var userToUpdate = user.User{}
@@ -188,7 +563,7 @@ func (controller *UserController) updateUserById(w http.ResponseWriter, r *http.
 
 	var err error
 
-	userToUpdate.UpdatedBy, err = security.ExtractUserIDFromToken(r)
+	userToUpdate.UpdatedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -214,7 +589,7 @@ func (controller *UserController) updateUserById(w http.ResponseWriter, r *http.
 	// 	return
 	// }
 
-	err = controller.UserService.NormalUpdate(&userToUpdate)
+	err = controller.UserService.NormalUpdate(r.Context(), &userToUpdate, security.CurrentBankScope(r))
 	if err != nil {
 		controller.log.Print(err.Error())
 		web.RespondError(w, err)
@@ -223,7 +598,7 @@ func (controller *UserController) updateUserById(w http.ResponseWriter, r *http.
 
 	updatedUser := user.UserDTO{}
 	updatedUser.ID = userToUpdate.ID
-	err = controller.UserService.GetUserByID(&updatedUser)
+	err = controller.UserService.GetUserByID(&updatedUser, security.CurrentBankScope(r))
 	if err != nil {
 		web.RespondError(w, err)
 		return
@@ -232,6 +607,154 @@ func (controller *UserController) updateUserById(w http.ResponseWriter, r *http.
 	web.RespondJSON(w, http.StatusOK, updatedUser)
 }
 
+func (controller *UserController) getRoleCatalog(w http.ResponseWriter, r *http.Request) {
+	catalog := role.Catalog()
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(catalog), catalog)
+}
+
+func (controller *UserController) getUserRoles(w http.ResponseWriter, r *http.Request) {
+
+	userRoles := []role.UserRole{}
+	parser := web.NewParser(r)
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	if err := controller.UserService.GetRoles(userID, &userRoles); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(userRoles), userRoles)
+}
+
+func (controller *UserController) assignUserRoles(w http.ResponseWriter, r *http.Request) {
+
+	userRoles := []role.UserRole{}
+	parser := web.NewParser(r)
+
+	var requestData struct {
+		Roles []string `json:"roles" example:"TELLER,AUDITOR"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	assignedBy, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	roles, err := role.ValidateAssignment(requestData.Roles)
+	if err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	request := approval.Request{Action: approval.ActionRoleAssign, TargetID: userID,
+		Summary: "Assign roles " + strings.Join(roles, ", ") + " to user " + userID.String(), ProposedBy: assignedBy}
+	if filed, err := controller.ApprovalService.Propose(r.Context(), &request, approval.RoleAssignment{Roles: roles}); err != nil {
+		web.RespondError(w, err)
+		return
+	} else if filed {
+		web.RespondJSON(w, http.StatusAccepted, request)
+		return
+	}
+
+	if err := controller.UserService.AssignRoles(r.Context(), userID, roles, assignedBy, &userRoles); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(userRoles), userRoles)
+}
+
+func (controller *UserController) getUserBankScope(w http.ResponseWriter, r *http.Request) {
+
+	userBanks := []role.UserBank{}
+	parser := web.NewParser(r)
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	if err := controller.UserService.GetBankScope(userID, &userBanks); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(userBanks), userBanks)
+}
+
+func (controller *UserController) assignUserBankScope(w http.ResponseWriter, r *http.Request) {
+
+	userBanks := []role.UserBank{}
+	parser := web.NewParser(r)
+
+	var requestData struct {
+		BankIDs []uuid.UUID `json:"bankIds"`
+	}
+
+	if err := web.UnmarshalJSON(r, &requestData); err != nil {
+		web.RespondError(w, errors.NewHTTPError("unable to parse requested data", http.StatusBadRequest))
+		return
+	}
+
+	userID, err := parser.GetUUID("id")
+	if err != nil {
+		web.RespondError(w, errors.NewValidationError("Invalid user ID format"))
+		return
+	}
+
+	assignedBy, err := security.CurrentUserID(r)
+	if err != nil {
+		controller.log.Error(err.Error())
+		web.RespondError(w, err)
+		return
+	}
+
+	if err := controller.UserService.AssignBanks(r.Context(), userID, requestData.BankIDs, assignedBy, security.CurrentBankScope(r), &userBanks); err != nil {
+		web.RespondError(w, err)
+		return
+	}
+
+	web.RespondJSONWithXTotalCount(w, http.StatusOK, len(userBanks), userBanks)
+}
+
+// newClientSession records which client a session is opened from.
+func newClientSession(r *http.Request) *session.Session {
+	ipAddress, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ipAddress = r.RemoteAddr
+	}
+	return &session.Session{
+		UserAgent: truncate(r.UserAgent(), 255),
+		IPAddress: truncate(ipAddress, 45),
+	}
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
+
 func (controller *UserController) deleteUserById(w http.ResponseWriter, r *http.Request) {
 
 	userToDelete := user.User{}
@@ -243,7 +766,7 @@ func (controller *UserController) deleteUserById(w http.ResponseWriter, r *http.
 		return
 	}
 
-	userToDelete.DeletedBy, err = security.ExtractUserIDFromToken(r)
+	userToDelete.DeletedBy, err = security.CurrentUserID(r)
 	if err != nil {
 		controller.log.Error(err.Error())
 		web.RespondError(w, err)
@@ -252,7 +775,7 @@ func (controller *UserController) deleteUserById(w http.ResponseWriter, r *http.
 
 	userToDelete.ID = userIdFromURL
 
-	err = controller.UserService.Delete(&userToDelete)
+	err = controller.UserService.Delete(r.Context(), &userToDelete, security.CurrentBankScope(r))
 	if err != nil {
 		controller.log.Print(err.Error())
 		web.RespondError(w, err)
@@ -260,6 +783,6 @@ func (controller *UserController) deleteUserById(w http.ResponseWriter, r *http.
 	}
 
 	web.RespondJSON(w, http.StatusOK, map[string]string{
-		"message": "User deleted successfully",
+		"message": "User erased successfully",
 	})
 }
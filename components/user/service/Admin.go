@@ -0,0 +1,182 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/pii"
+	"banking-app-be/components/security"
+	"banking-app-be/model/invitation"
+	"banking-app-be/model/role"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Bootstrap creates the first super-admin of a deployment. It needs the setup token from the
+// configuration and closes for good once any administrator exists.
+func (service *UserService) Bootstrap(ctx context.Context, setupToken string, newUser *user.User) error {
+
+	expectedToken := config.AdminSetupToken.GetStringValue()
+	if expectedToken == "" {
+		return errors.NewHTTPError("Admin bootstrap is disabled", http.StatusForbidden)
+	}
+	if subtle.ConstantTimeCompare([]byte(setupToken), []byte(expectedToken)) != 1 {
+		return errors.NewUnauthorizedError("Invalid setup token")
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	adminCount := 0
+	if err := service.repository.GetCount(uow, &user.User{}, &adminCount, repository.Filter("is_admin = ?", true)); err != nil {
+		return errors.NewDatabaseError("Unable to count administrators")
+	}
+	if adminCount > 0 {
+		return errors.NewHTTPError("Bootstrap has already been completed", http.StatusConflict)
+	}
+
+	newUser.CreatedBy = uuid.Nil
+	if err := service.createStaff(uow, newUser, role.SuperAdmin); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// CreateInvitation invites an administrator by email. Pending invitations to the same address
+// are revoked, so only the latest link works.
+func (service *UserService) CreateInvitation(ctx context.Context, newInvitation *invitation.IssuedInvitation) error {
+
+	newInvitation.Normalize()
+	if err := newInvitation.Validate(); err != nil {
+		return err
+	}
+	if err := service.doesEmailExists(newInvitation.Email); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &invitation.Invitation{}, map[string]interface{}{
+		"revoked_at": now,
+		"updated_by": newInvitation.CreatedBy,
+		"updated_at": now,
+	}, repository.Filter("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", newInvitation.Email)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke earlier invitations")
+	}
+
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewDatabaseError("Failed to generate invitation token")
+	}
+	newInvitation.TokenHash = security.HashOpaqueToken(token)
+	newInvitation.ExpiresAt = now.Add(time.Duration(config.AdminInviteTTLHours.GetInt64ValueOrDefault(72)) * time.Hour)
+
+	if err := service.repository.Add(uow, &newInvitation.Invitation); err != nil {
+		return errors.NewDatabaseError("Failed to create invitation")
+	}
+
+	newInvitation.Token = token
+	if inviteURL := config.AdminInviteURL.GetStringValue(); inviteURL != "" {
+		newInvitation.Link = inviteURL + "?token=" + url.QueryEscape(token)
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UserService) GetInvitations(allInvitations *[]invitation.Invitation, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetAll(uow, allInvitations, repository.OrderBy("created_at DESC"), repository.Paginate(limit, offset, totalCount))
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, allInvitations, totalCount)
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UserService) RevokeInvitation(ctx context.Context, invitationToRevoke *invitation.Invitation) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	existingInvitation := invitation.Invitation{}
+	if err := service.repository.GetRecordByID(uow, invitationToRevoke.ID, &existingInvitation); err != nil {
+		return errors.NewHTTPError("Invitation not found with given Id", http.StatusNotFound)
+	}
+	if existingInvitation.AcceptedAt != nil {
+		return errors.NewValidationError("Invitation has already been accepted")
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &invitation.Invitation{}, map[string]interface{}{
+		"revoked_at": now,
+		"updated_by": invitationToRevoke.UpdatedBy,
+		"updated_at": now,
+	}, repository.Filter("id = ?", invitationToRevoke.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke invitation")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// AcceptInvitation creates the invited administrator. The email always comes from the
+// invitation, and the token can only be used once before it expires.
+func (service *UserService) AcceptInvitation(ctx context.Context, token string, newUser *user.User) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	pendingInvitation := invitation.Invitation{}
+	if err := service.repository.GetRecord(uow, &pendingInvitation,
+		repository.Filter("token_hash = ?", security.HashOpaqueToken(token))); err != nil {
+		return errors.NewUnauthorizedError("Invitation is invalid or has expired")
+	}
+	if !pendingInvitation.IsPending(time.Now()) {
+		return errors.NewUnauthorizedError("Invitation is invalid or has expired")
+	}
+
+	if newUser.Credentials == nil {
+		return errors.NewValidationError("Credentials must be specified")
+	}
+
+	newUser.Credentials.Email = pii.String(pendingInvitation.Email)
+	newUser.CreatedBy = pendingInvitation.CreatedBy
+	newUser.Credentials.CreatedBy = pendingInvitation.CreatedBy
+
+	if err := service.createStaff(uow, newUser, pendingInvitation.Role); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &invitation.Invitation{}, map[string]interface{}{
+		"accepted_at":      now,
+		"accepted_user_id": newUser.ID,
+		"updated_by":       newUser.ID,
+		"updated_at":       now,
+	}, repository.Filter("id = ? AND accepted_at IS NULL", pendingInvitation.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to accept invitation")
+	}
+
+	uow.Commit()
+	return nil
+}
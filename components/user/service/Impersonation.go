@@ -0,0 +1,145 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/session"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	uuid "github.com/satori/go.uuid"
+)
+
+// Impersonate opens a session for an admin to act as a customer. The session is time-boxed and
+// has no refresh token, and every change made in it is attributed to the admin as well.
+// Only customers can be impersonated, so it can not be used to gain another admin's rights.
+func (service *SessionService) Impersonate(ctx context.Context, impersonatorID, userID uuid.UUID, request *session.ImpersonationRequest,
+	client *session.Session, impersonation *session.Impersonation) error {
+
+	maxMinutes := config.ImpersonationMaxMinutes.GetInt64ValueOrDefault(60)
+	if err := request.Validate(maxMinutes); err != nil {
+		return err
+	}
+	if impersonatorID == userID {
+		return errors.NewValidationError("You can not impersonate yourself")
+	}
+
+	minutes := request.Minutes
+	if minutes == 0 {
+		minutes = config.ImpersonationDefaultMinutes.GetInt64ValueOrDefault(15)
+	}
+	readOnly := request.ReadOnly == nil || *request.ReadOnly
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	target := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &target); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+	if target.IsAdmin != nil && *target.IsAdmin {
+		return errors.NewOutOfScopeError("Only customers can be impersonated")
+	}
+	if target.IsActive == nil || !*target.IsActive {
+		return errors.NewValidationError("Inactive users can not be impersonated")
+	}
+
+	// The refresh token is never handed out; it is only generated to fill the column.
+	refreshToken, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate refresh token", http.StatusInternalServerError)
+	}
+
+	now := time.Now()
+	client.ID = uuid.NewV4()
+	client.UserID = target.ID
+	client.RefreshTokenHash = security.HashOpaqueToken(refreshToken)
+	client.ExpiresAt = now.Add(time.Duration(minutes) * time.Minute)
+	client.LastUsedAt = &now
+	client.ImpersonatorID = &impersonatorID
+	client.ReadOnly = readOnly
+	client.ImpersonationReason = request.Reason
+	client.CreatedBy = impersonatorID
+	if err := service.repository.Add(uow, client); err != nil {
+		return errors.NewDatabaseError("Failed to create impersonation session")
+	}
+
+	claim := security.Claims{
+		UserID:         target.ID,
+		IsAdmin:        false,
+		IsActive:       true,
+		ImpersonatorID: &impersonatorID,
+		ReadOnly:       readOnly,
+		StandardClaims: jwt.StandardClaims{
+			Id:        client.ID.String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: client.ExpiresAt.Unix(),
+		},
+	}
+	accessToken, err := claim.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	log.GetLogger().Warn("User ", impersonatorID, " is impersonating user ", target.ID, " until ", client.ExpiresAt, ": ", request.Reason)
+
+	*impersonation = session.Impersonation{
+		SessionID:   client.ID,
+		UserID:      target.ID,
+		AccessToken: accessToken,
+		ReadOnly:    readOnly,
+		ExpiresAt:   client.ExpiresAt,
+		ExpiresIn:   int64(client.ExpiresAt.Sub(now).Seconds()),
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetImpersonations lists the impersonation sessions still running.
+func (service *SessionService) GetImpersonations(allSessions *[]session.Session) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetAll(uow, allSessions,
+		repository.Filter("impersonator_id IS NOT NULL AND revoked_at IS NULL AND expires_at > ?", time.Now()),
+		repository.OrderBy("created_at DESC"))
+	if err != nil {
+		return errors.NewDatabaseError("Unable to fetch impersonation sessions")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// EndImpersonation revokes an impersonation session, ending it before it expires.
+func (service *SessionService) EndImpersonation(ctx context.Context, endedBy, sessionID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	current := session.Session{}
+	err := service.repository.GetRecord(uow, &current,
+		repository.Filter("id = ? AND impersonator_id IS NOT NULL", sessionID))
+	if err != nil {
+		return errors.NewNotFoundError("Impersonation session not found")
+	}
+	if current.RevokedAt != nil {
+		return errors.NewValidationError("Impersonation session has already ended")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, revocation(endedBy, session.RevokedEnded),
+		repository.Filter("id = ?", sessionID)); err != nil {
+		return errors.NewDatabaseError("Failed to end impersonation session")
+	}
+
+	uow.Commit()
+	return nil
+}
@@ -0,0 +1,394 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
+	"banking-app-be/model/credential"
+	"banking-app-be/model/login"
+	"banking-app-be/model/session"
+	"banking-app-be/module/repository"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// invalidLoginMessage is the only answer a failed login gets, whether the email or the
+// password was wrong.
+const invalidLoginMessage = "Invalid email or password"
+
+var (
+	dummyPasswordHashOnce sync.Once
+	dummyPasswordHash     []byte
+)
+
+// LoginGuardService throttles failed logins per email and per client address, and keeps the
+// login history of every user.
+type LoginGuardService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewLoginGuardService(DB *gorm.DB, repo repository.Repository) *LoginGuardService {
+	return &LoginGuardService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// GetLoginHistory lists the login attempts on a user's account, newest first.
+func (service *LoginGuardService) GetLoginHistory(userID uuid.UUID, history *[]login.Attempt, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetAll(uow, history, repository.Filter("user_id = ?", userID),
+		repository.OrderBy("attempted_at DESC"), repository.Paginate(limit, offset, totalCount))
+	if err != nil {
+		return err
+	}
+
+	err = service.repository.GetCount(uow, history, totalCount, repository.Filter("user_id = ?", userID))
+	if err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+// waitBefore returns how long the email and the client address still have to wait before
+// they may try again.
+func (service *LoginGuardService) waitBefore(email, ipAddress string, now time.Time) (time.Duration, error) {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	accountSubject, err := accountSubjectOf(email)
+	if err != nil {
+		return 0, err
+	}
+	throttles := []login.Throttle{}
+	if err := service.repository.GetAll(uow, &throttles, repository.Filter("subject IN (?)",
+		[]string{accountSubject, login.IPSubject(ipAddress)})); err != nil {
+		return 0, errors.NewDatabaseError("Unable to check login attempts")
+	}
+
+	var wait time.Duration
+	for i := range throttles {
+		if until := throttles[i].BlockedUntil(now); until != nil && until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+
+	uow.Commit()
+	return wait, nil
+}
+
+// recordFailure counts a wrong password or second factor against the email and the client
+// address and adds it to the history with outcome. It commits on its own, as the login it
+// belongs to is rolled back.
+func (service *LoginGuardService) recordFailure(email string, userID *uuid.UUID, client *session.Session, outcome string, now time.Time) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	accountSubject, err := accountSubjectOf(email)
+	if err != nil {
+		return err
+	}
+	if err := service.countFailure(uow, accountSubject,
+		int(config.LoginAccountMaxFailures.GetInt64ValueOrDefault(10)), now); err != nil {
+		return err
+	}
+	if err := service.countFailure(uow, login.IPSubject(client.IPAddress),
+		int(config.LoginIPMaxFailures.GetInt64ValueOrDefault(50)), now); err != nil {
+		return err
+	}
+	if err := service.addAttempt(uow, email, userID, client, outcome, now); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// checkAccount refuses an email that still has to wait, for checks made outside of a login
+// where there is no client address to throttle.
+func (service *LoginGuardService) checkAccount(email string, now time.Time) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	accountSubject, err := accountSubjectOf(email)
+	if err != nil {
+		return err
+	}
+	throttles := []login.Throttle{}
+	if err := service.repository.GetAll(uow, &throttles, repository.Filter("subject = ?", accountSubject)); err != nil {
+		return errors.NewDatabaseError("Unable to check login attempts")
+	}
+	for i := range throttles {
+		if until := throttles[i].BlockedUntil(now); until != nil {
+			return tooManyLoginAttemptsError(until.Sub(now))
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// recordAccountFailure counts a failure against the email alone, in its own transaction.
+func (service *LoginGuardService) recordAccountFailure(email string, now time.Time) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	accountSubject, err := accountSubjectOf(email)
+	if err != nil {
+		return err
+	}
+	if err := service.countFailure(uow, accountSubject,
+		int(config.LoginAccountMaxFailures.GetInt64ValueOrDefault(10)), now); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// confirmPassword checks the password a signed-in user confirms a change with. Wrong ones count
+// against the account like failed logins, and none is checked while the account is throttled.
+func (service *LoginGuardService) confirmPassword(userCredential *credential.Credential, password string, now time.Time) error {
+	email := string(userCredential.Email)
+	if err := service.checkAccount(email, now); err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(userCredential.Password), []byte(password)) != nil {
+		if err := service.recordAccountFailure(email, now); err != nil {
+			return err
+		}
+		return errors.NewInValidPasswordError("Incorrect password")
+	}
+	return nil
+}
+
+// countResetRequest counts a password reset request against the email and the client address
+// and refuses it while either still has to wait. Every request counts, whether or not an
+// account has the email, and is committed on its own.
+func (service *LoginGuardService) countResetRequest(email, ipAddress string, now time.Time) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	resetSubject, err := login.ResetSubject(email)
+	if err != nil {
+		log.GetLogger().Error(err.Error())
+		return errors.NewHTTPError("Unable to look up the email", http.StatusInternalServerError)
+	}
+	subjects := []string{resetSubject, login.ResetIPSubject(ipAddress)}
+	throttles := []login.Throttle{}
+	if err := service.repository.GetAll(uow, &throttles, repository.Filter("subject IN (?)", subjects)); err != nil {
+		return errors.NewDatabaseError("Unable to check password reset requests")
+	}
+	var wait time.Duration
+	for i := range throttles {
+		if until := throttles[i].BlockedUntil(now); until != nil && until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+	if wait > 0 {
+		return errors.NewHTTPError(fmt.Sprintf("Too many password reset requests, try again in %d seconds",
+			int(math.Ceil(wait.Seconds()))), http.StatusTooManyRequests)
+	}
+
+	if err := service.countFailure(uow, resetSubject,
+		int(config.PasswordResetMaxRequests.GetInt64ValueOrDefault(5)), now); err != nil {
+		return err
+	}
+	if err := service.countFailure(uow, login.ResetIPSubject(ipAddress),
+		int(config.PasswordResetIPMaxRequests.GetInt64ValueOrDefault(20)), now); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// recordAttempt adds an attempt to the history in its own transaction.
+func (service *LoginGuardService) recordAttempt(email string, userID *uuid.UUID, client *session.Session, outcome string, now time.Time) error {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	if err := service.addAttempt(uow, email, userID, client, outcome, now); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// recordSuccess clears the failures of an email once a session is opened for it, and adds the
+// attempt to the history.
+func (service *LoginGuardService) recordSuccess(uow *repository.UnitOfWork, email string, userID uuid.UUID, client *session.Session, outcome string, now time.Time) error {
+	if err := service.reset(uow, email, userID); err != nil {
+		return err
+	}
+	return service.addAttempt(uow, email, &userID, client, outcome, now)
+}
+
+// unlock lifts the lockout of an email on behalf of an administrator.
+func (service *LoginGuardService) unlock(uow *repository.UnitOfWork, email string, userID, unlockedBy uuid.UUID, now time.Time) error {
+	if err := service.reset(uow, email, unlockedBy); err != nil {
+		return err
+	}
+	return service.addAttempt(uow, email, &userID, &session.Session{}, login.OutcomeUnlocked, now)
+}
+
+// countFailure adds a failure to subject. From the free attempts on every failure doubles the
+// wait before the next attempt, and the maximum locks the subject. The throttle row is locked
+// while it is counted, so concurrent failures are all counted.
+func (service *LoginGuardService) countFailure(uow *repository.UnitOfWork, subject string, maxFailures int, now time.Time) error {
+
+	throttles := []login.Throttle{}
+	if err := service.repository.GetAll(uow, &throttles, repository.Filter("subject = ?", subject),
+		repository.ForUpdate()); err != nil {
+		return errors.NewDatabaseError("Unable to fetch login attempts")
+	}
+
+	throttle := login.Throttle{Subject: subject}
+	if len(throttles) > 0 {
+		throttle = throttles[0]
+	}
+
+	window := time.Duration(config.LoginFailureWindowMinutes.GetInt64ValueOrDefault(15)) * time.Minute
+	if throttle.LastFailedAt != nil && now.Sub(*throttle.LastFailedAt) > window {
+		throttle.FailedAttempts = 0
+	}
+	throttle.FailedAttempts++
+	throttle.LastFailedAt = &now
+	throttle.NextAttemptAt = nil
+	throttle.LockedUntil = nil
+
+	freeAttempts := int(config.LoginFreeAttempts.GetInt64ValueOrDefault(3))
+	if throttle.FailedAttempts >= maxFailures {
+		lockedUntil := now.Add(time.Duration(config.LoginLockoutMinutes.GetInt64ValueOrDefault(15)) * time.Minute)
+		throttle.LockedUntil = &lockedUntil
+		throttle.FailedAttempts = 0
+	} else if throttle.FailedAttempts >= freeAttempts {
+		nextAttemptAt := now.Add(loginDelay(throttle.FailedAttempts - freeAttempts))
+		throttle.NextAttemptAt = &nextAttemptAt
+	}
+
+	if throttle.ID == uuid.Nil {
+		err := service.repository.Add(uow, &throttle)
+		if repository.IsDuplicateKey(err) {
+			// A concurrent failure created the row first; count on the row it created.
+			return service.countFailure(uow, subject, maxFailures, now)
+		}
+		if err != nil {
+			return errors.NewDatabaseError("Failed to record login attempt")
+		}
+		return nil
+	}
+	if err := service.repository.UpdateWithMap(uow, &login.Throttle{}, map[string]interface{}{
+		"failed_attempts": throttle.FailedAttempts,
+		"last_failed_at":  throttle.LastFailedAt,
+		"next_attempt_at": throttle.NextAttemptAt,
+		"locked_until":    throttle.LockedUntil,
+		"updated_at":      now,
+	}, repository.Filter("id = ?", throttle.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to record login attempt")
+	}
+	return nil
+}
+
+// reset clears the failures of an email. Those of the client address only run out, so logging
+// in to one account does not buy attempts on others.
+func (service *LoginGuardService) reset(uow *repository.UnitOfWork, email string, updatedBy uuid.UUID) error {
+	accountSubject, err := accountSubjectOf(email)
+	if err != nil {
+		return err
+	}
+	if err := service.repository.UpdateWithMap(uow, &login.Throttle{}, map[string]interface{}{
+		"failed_attempts": 0,
+		"last_failed_at":  nil,
+		"next_attempt_at": nil,
+		"locked_until":    nil,
+		"updated_by":      updatedBy,
+		"updated_at":      time.Now(),
+	}, repository.Filter("subject = ?", accountSubject)); err != nil {
+		return errors.NewDatabaseError("Failed to reset login attempts")
+	}
+	return nil
+}
+
+func (service *LoginGuardService) addAttempt(uow *repository.UnitOfWork, email string, userID *uuid.UUID, client *session.Session, outcome string, now time.Time) error {
+	attempt := login.Attempt{
+		UserID:      userID,
+		Email:       pii.String(email),
+		AttemptedAt: now,
+		IPAddress:   client.IPAddress,
+		UserAgent:   client.UserAgent,
+		Outcome:     outcome,
+	}
+	if userID != nil {
+		attempt.CreatedBy = *userID
+	}
+	if err := service.repository.Add(uow, &attempt); err != nil {
+		return errors.NewDatabaseError("Failed to record login history")
+	}
+	return nil
+}
+
+// accountSubjectOf returns the throttle subject of email.
+func accountSubjectOf(email string) (string, error) {
+	subject, err := login.AccountSubject(email)
+	if err != nil {
+		log.GetLogger().Error(err.Error())
+		return "", errors.NewHTTPError("Unable to look up the email", http.StatusInternalServerError)
+	}
+	return subject, nil
+}
+
+func tooManyLoginAttemptsError(wait time.Duration) error {
+	return errors.NewHTTPError(fmt.Sprintf("Too many failed login attempts, try again in %d seconds",
+		int(math.Ceil(wait.Seconds()))), http.StatusTooManyRequests)
+}
+
+// loginDelay returns the wait after the given number of failures beyond the free ones.
+func loginDelay(extraFailures int) time.Duration {
+	base := time.Duration(config.LoginDelayBaseSeconds.GetInt64ValueOrDefault(1)) * time.Second
+	maxDelay := time.Duration(config.LoginDelayMaxSeconds.GetInt64ValueOrDefault(60)) * time.Second
+	if extraFailures > 30 {
+		return maxDelay
+	}
+	delay := base << uint(extraFailures)
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// comparePassword checks password against hash. Without a hash it compares against a dummy
+// one, so unknown emails take as long to refuse as wrong passwords.
+func comparePassword(hash, password string) bool {
+	if hash == "" {
+		dummyPasswordHashOnce.Do(func() {
+			dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), cost)
+		})
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
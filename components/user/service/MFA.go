@@ -0,0 +1,593 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/pii"
+	"banking-app-be/components/security"
+	"banking-app-be/model/credential"
+	"banking-app-be/model/login"
+	"banking-app-be/model/mfa"
+	"banking-app-be/model/session"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	backupCodeCount       = 10
+	maxChallengeAttempts  = 5
+	defaultMFAIssuer      = "Banking App"
+	backupCodeGroupLength = 5
+)
+
+var backupCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAService struct {
+	db             *gorm.DB
+	repository     repository.Repository
+	sessionService *SessionService
+	loginGuard     *LoginGuardService
+	clock          security.Clock
+	totp           *security.TOTPVerifier
+}
+
+// NewMFAService reads the time from clock, or from the system when clock is nil.
+func NewMFAService(DB *gorm.DB, repo repository.Repository, sessionService *SessionService, loginGuard *LoginGuardService,
+	clock security.Clock) *MFAService {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &MFAService{
+		db:             DB,
+		repository:     repo,
+		sessionService: sessionService,
+		loginGuard:     loginGuard,
+		clock:          clock,
+		totp:           security.NewTOTPVerifier(clock),
+	}
+}
+
+func (service *MFAService) GetStatus(userID uuid.UUID, status *mfa.Status) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	factor, err := service.factorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	required, err := service.isRequired(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	remaining := 0
+	if err := service.repository.GetCount(uow, &mfa.BackupCode{}, &remaining,
+		repository.Filter("user_id = ? AND used_at IS NULL", userID)); err != nil {
+		return errors.NewDatabaseError("Unable to count backup codes")
+	}
+
+	*status = mfa.Status{
+		Enabled:              factor != nil && factor.IsEnabled(),
+		Required:             required,
+		RemainingBackupCodes: remaining,
+	}
+	return nil
+}
+
+// BeginEnrollment generates a new authenticator secret. It does not protect logins until
+// ConfirmEnrollment has seen a code from it.
+func (service *MFAService) BeginEnrollment(ctx context.Context, userID uuid.UUID, enrollment *mfa.Enrollment) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.beginEnrollment(uow, userID, enrollment); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// ConfirmEnrollment enables MFA once the user proves their authenticator works, and hands out
+// the backup codes, which are never shown again.
+func (service *MFAService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string, backupCodes *[]string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.confirmEnrollment(uow, userID, code, backupCodes); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// RegenerateBackupCodes replaces every backup code of the user.
+func (service *MFAService) RegenerateBackupCodes(ctx context.Context, userID uuid.UUID, code string, backupCodes *[]string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	factor, err := service.enabledFactorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if err := service.verifyTOTP(uow, factor, code); err != nil {
+		return err
+	}
+	if err := service.issueBackupCodes(uow, userID, backupCodes); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// Disable turns MFA off for a user who is not required to use it.
+func (service *MFAService) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	required, err := service.isRequired(uow, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.NewValidationError("MFA is required for your account and can not be disabled")
+	}
+
+	factor, err := service.enabledFactorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if err := service.verifyCode(uow, factor, code); err != nil {
+		return err
+	}
+	if err := service.removeFactor(uow, userID); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *MFAService) GetPolicy(policy *mfa.Policy) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	return service.policyOf(uow, policy)
+}
+
+// UpdatePolicy changes the MFA rules. Administrators without an authenticator are made to
+// enroll at their next login once MFA is required for them.
+func (service *MFAService) UpdatePolicy(ctx context.Context, policy *mfa.Policy) error {
+
+	if policy.RequireForAdmins == nil {
+		return errors.NewValidationError("requireForAdmins must be specified")
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	existingPolicy := mfa.Policy{}
+	if err := service.policyOf(uow, &existingPolicy); err != nil {
+		return err
+	}
+
+	if existingPolicy.ID == uuid.Nil {
+		policy.CreatedBy = policy.UpdatedBy
+		if err := service.repository.Add(uow, policy); err != nil {
+			return errors.NewDatabaseError("Failed to save MFA policy")
+		}
+	} else {
+		if err := service.repository.UpdateWithMap(uow, &mfa.Policy{}, map[string]interface{}{
+			"require_for_admins": *policy.RequireForAdmins,
+			"updated_by":         policy.UpdatedBy,
+			"updated_at":         time.Now(),
+		}, repository.Filter("id = ?", existingPolicy.ID)); err != nil {
+			return errors.NewDatabaseError("Failed to save MFA policy")
+		}
+		policy.ID = existingPolicy.ID
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// EnrollForLogin lets a user whom the policy forces to use MFA enroll during login, before they
+// hold any token.
+func (service *MFAService) EnrollForLogin(ctx context.Context, mfaToken string, enrollment *mfa.Enrollment) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	challenge, err := service.openChallenge(uow, mfaToken)
+	if err != nil {
+		return err
+	}
+	factor, err := service.factorOf(uow, challenge.UserID)
+	if err != nil {
+		return err
+	}
+	if factor != nil && factor.IsEnabled() {
+		return errors.NewValidationError("MFA is already enabled; answer the challenge with a code")
+	}
+
+	if err := service.beginEnrollment(uow, challenge.UserID, enrollment); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// CompleteLogin answers a login challenge with an authenticator or backup code and opens the
+// session. A user enrolling during login confirms the authenticator with the same code and
+// receives their backup codes. Wrong codes count against the account like wrong passwords, so
+// the account is slowed down and then locked however many challenges are opened.
+func (service *MFAService) CompleteLogin(ctx context.Context, mfaToken, code string, client *session.Session, tokens *session.TokenPair, backupCodes *[]string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	challenge, err := service.openChallenge(uow, mfaToken)
+	if err != nil {
+		return err
+	}
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", challenge.UserID)); err != nil {
+		return errors.NewUnauthorizedError("User no longer exists")
+	}
+	email := string(userCredential.Email)
+	wait, err := service.loginGuard.waitBefore(email, client.IPAddress, service.clock())
+	if err != nil {
+		return err
+	}
+	if wait > 0 {
+		return tooManyLoginAttemptsError(wait)
+	}
+
+	// Attempts are counted in their own transaction so failed answers still use them up.
+	if err := service.db.Model(&mfa.Challenge{}).Where("id = ?", challenge.ID).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+		return errors.NewDatabaseError("Failed to record MFA attempt")
+	}
+
+	factor, err := service.factorOf(uow, challenge.UserID)
+	if err != nil {
+		return err
+	}
+	switch {
+	case factor == nil:
+		return errors.NewValidationError("Enroll an authenticator before answering the challenge")
+	case factor.IsEnabled():
+		err = service.verifyCode(uow, factor, code)
+	default:
+		err = service.confirmEnrollment(uow, challenge.UserID, code, backupCodes)
+	}
+	if err != nil {
+		if recordErr := service.loginGuard.recordFailure(email, &challenge.UserID, client, login.OutcomeMFAFailed, service.clock()); recordErr != nil {
+			return recordErr
+		}
+		return err
+	}
+
+	now := service.clock()
+	if err := service.repository.UpdateWithMap(uow, &mfa.Challenge{}, map[string]interface{}{
+		"used_at":    now,
+		"updated_by": challenge.UserID,
+		"updated_at": now,
+	}, repository.Filter("id = ?", challenge.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to close MFA challenge")
+	}
+
+	sessionUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, challenge.UserID, &sessionUser); err != nil {
+		return errors.NewUnauthorizedError("User no longer exists")
+	}
+	if err := service.loginGuard.recordSuccess(uow, email, sessionUser.ID, client, login.OutcomeSuccess, now); err != nil {
+		return err
+	}
+	if err := service.sessionService.open(uow, &sessionUser, client, tokens); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+// challengeLogin decides whether a user who passed the password check still owes a second
+// factor, and if so opens a challenge for it instead of a session. Challenges opened earlier
+// are closed, so each login only has the attempts of one challenge.
+func (service *MFAService) challengeLogin(uow *repository.UnitOfWork, userID uuid.UUID, challenge *mfa.Challenge) (bool, error) {
+
+	factor, err := service.factorOf(uow, userID)
+	if err != nil {
+		return false, err
+	}
+	enabled := factor != nil && factor.IsEnabled()
+
+	required, err := service.isRequired(uow, userID)
+	if err != nil {
+		return false, err
+	}
+	if !enabled && !required {
+		return false, nil
+	}
+
+	now := service.clock()
+	if err := service.repository.UpdateWithMap(uow, &mfa.Challenge{}, map[string]interface{}{
+		"used_at":    now,
+		"updated_by": userID,
+		"updated_at": now,
+	}, repository.Filter("user_id = ? AND used_at IS NULL", userID)); err != nil {
+		return false, errors.NewDatabaseError("Failed to close earlier MFA challenges")
+	}
+
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return false, errors.NewHTTPError("Unable to generate MFA challenge", http.StatusInternalServerError)
+	}
+
+	challenge.UserID = userID
+	challenge.TokenHash = security.HashOpaqueToken(token)
+	challenge.ExpiresAt = now.Add(time.Duration(config.MFAChallengeTTLMinutes.GetInt64ValueOrDefault(5)) * time.Minute)
+	challenge.CreatedBy = userID
+	if err := service.repository.Add(uow, challenge); err != nil {
+		return false, errors.NewDatabaseError("Failed to create MFA challenge")
+	}
+
+	challenge.Token = token
+	challenge.EnrollmentRequired = !enabled
+	return true, nil
+}
+
+func (service *MFAService) openChallenge(uow *repository.UnitOfWork, mfaToken string) (*mfa.Challenge, error) {
+	challenge := mfa.Challenge{}
+	if err := service.repository.GetRecord(uow, &challenge,
+		repository.Filter("token_hash = ?", security.HashOpaqueToken(mfaToken))); err != nil {
+		return nil, errors.NewUnauthorizedError("MFA challenge is invalid or has expired")
+	}
+	if !challenge.IsOpen(service.clock(), maxChallengeAttempts) {
+		return nil, errors.NewUnauthorizedError("MFA challenge is invalid or has expired")
+	}
+	return &challenge, nil
+}
+
+func (service *MFAService) beginEnrollment(uow *repository.UnitOfWork, userID uuid.UUID, enrollment *mfa.Enrollment) error {
+
+	factor, err := service.factorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if factor != nil && factor.IsEnabled() {
+		return errors.NewValidationError("MFA is already enabled; disable it before enrolling again")
+	}
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("unable to get credentials")
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate MFA secret", http.StatusInternalServerError)
+	}
+
+	if err := service.removeFactor(uow, userID); err != nil {
+		return err
+	}
+	newFactor := mfa.Factor{UserID: userID, Secret: pii.String(secret)}
+	newFactor.CreatedBy = userID
+	if err := service.repository.Add(uow, &newFactor); err != nil {
+		return errors.NewDatabaseError("Failed to save MFA secret")
+	}
+
+	issuer := config.MFAIssuer.GetStringValue()
+	if issuer == "" {
+		issuer = defaultMFAIssuer
+	}
+	*enrollment = mfa.Enrollment{
+		Secret:          secret,
+		ProvisioningURI: security.TOTPProvisioningURI(issuer, string(userCredential.Email), secret),
+	}
+	return nil
+}
+
+func (service *MFAService) confirmEnrollment(uow *repository.UnitOfWork, userID uuid.UUID, code string, backupCodes *[]string) error {
+
+	factor, err := service.factorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if factor == nil {
+		return errors.NewValidationError("Start an enrollment before confirming it")
+	}
+	if factor.IsEnabled() {
+		return errors.NewValidationError("MFA is already enabled")
+	}
+	if err := service.verifyTOTP(uow, factor, code); err != nil {
+		return err
+	}
+
+	now := service.clock()
+	if err := service.repository.UpdateWithMap(uow, &mfa.Factor{}, map[string]interface{}{
+		"confirmed_at": now,
+		"updated_by":   userID,
+		"updated_at":   now,
+	}, repository.Filter("id = ?", factor.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to enable MFA")
+	}
+
+	return service.issueBackupCodes(uow, userID, backupCodes)
+}
+
+// verifyCode accepts an authenticator code or, failing that, an unused backup code.
+func (service *MFAService) verifyCode(uow *repository.UnitOfWork, factor *mfa.Factor, code string) error {
+
+	if err := service.verifyTOTP(uow, factor, code); err == nil {
+		return nil
+	}
+
+	backupCode := mfa.BackupCode{}
+	if err := service.repository.GetRecord(uow, &backupCode,
+		repository.Filter("user_id = ? AND code_hash = ? AND used_at IS NULL", factor.UserID, hashBackupCode(code))); err != nil {
+		return errors.NewUnauthorizedError("Invalid MFA code")
+	}
+
+	now := service.clock()
+	if err := service.repository.UpdateWithMap(uow, &mfa.BackupCode{}, map[string]interface{}{
+		"used_at":    now,
+		"updated_by": factor.UserID,
+		"updated_at": now,
+	}, repository.Filter("id = ?", backupCode.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to use backup code")
+	}
+	return nil
+}
+
+// verifyTOTP accepts each authenticator code once.
+func (service *MFAService) verifyTOTP(uow *repository.UnitOfWork, factor *mfa.Factor, code string) error {
+
+	counter, ok := service.totp.Verify(string(factor.Secret), code, factor.LastUsedCounter)
+	if !ok {
+		return errors.NewUnauthorizedError("Invalid MFA code")
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &mfa.Factor{}, map[string]interface{}{
+		"last_used_counter": counter,
+	}, repository.Filter("id = ?", factor.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to record MFA code")
+	}
+	factor.LastUsedCounter = counter
+	return nil
+}
+
+func (service *MFAService) issueBackupCodes(uow *repository.UnitOfWork, userID uuid.UUID, backupCodes *[]string) error {
+
+	now := service.clock()
+	if err := service.repository.UpdateWithMap(uow, &mfa.BackupCode{}, map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": userID,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to clear backup codes")
+	}
+
+	*backupCodes = []string{}
+	for i := 0; i < backupCodeCount; i++ {
+		code, err := generateBackupCode()
+		if err != nil {
+			return errors.NewHTTPError("Unable to generate backup codes", http.StatusInternalServerError)
+		}
+		backupCode := mfa.BackupCode{UserID: userID, CodeHash: hashBackupCode(code)}
+		backupCode.CreatedBy = userID
+		if err := service.repository.Add(uow, &backupCode); err != nil {
+			return errors.NewDatabaseError("Failed to save backup code")
+		}
+		*backupCodes = append(*backupCodes, code)
+	}
+	return nil
+}
+
+func (service *MFAService) removeFactor(uow *repository.UnitOfWork, userID uuid.UUID) error {
+	now := service.clock()
+	removal := map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": userID,
+	}
+	if err := service.repository.UpdateWithMap(uow, &mfa.Factor{}, removal, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to remove MFA factor")
+	}
+	if err := service.repository.UpdateWithMap(uow, &mfa.BackupCode{}, removal, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to remove backup codes")
+	}
+	return nil
+}
+
+// factorOf returns the authenticator of a user, or nil when they have none.
+func (service *MFAService) factorOf(uow *repository.UnitOfWork, userID uuid.UUID) (*mfa.Factor, error) {
+	factors := []mfa.Factor{}
+	if err := service.repository.GetAll(uow, &factors, repository.Filter("user_id = ?", userID)); err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch MFA factor")
+	}
+	if len(factors) == 0 {
+		return nil, nil
+	}
+	return &factors[0], nil
+}
+
+func (service *MFAService) enabledFactorOf(uow *repository.UnitOfWork, userID uuid.UUID) (*mfa.Factor, error) {
+	factor, err := service.factorOf(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.IsEnabled() {
+		return nil, errors.NewValidationError("MFA is not enabled")
+	}
+	return factor, nil
+}
+
+// isRequired reports whether the policy makes the user use MFA.
+func (service *MFAService) isRequired(uow *repository.UnitOfWork, userID uuid.UUID) (bool, error) {
+
+	policy := mfa.Policy{}
+	if err := service.policyOf(uow, &policy); err != nil {
+		return false, err
+	}
+	if policy.RequireForAdmins == nil || !*policy.RequireForAdmins {
+		return false, nil
+	}
+
+	policyUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &policyUser); err != nil {
+		return false, errors.NewDatabaseError("Unable to fetch user")
+	}
+	return policyUser.IsAdmin != nil && *policyUser.IsAdmin, nil
+}
+
+// policyOf loads the policy row, leaving the defaults in place when none has been saved.
+func (service *MFAService) policyOf(uow *repository.UnitOfWork, policy *mfa.Policy) error {
+	policies := []mfa.Policy{}
+	if err := service.repository.GetAll(uow, &policies); err != nil {
+		return errors.NewDatabaseError("Unable to fetch MFA policy")
+	}
+	if len(policies) > 0 {
+		*policy = policies[0]
+	} else {
+		policy.RequireForAdmins = new(bool)
+	}
+	return nil
+}
+
+// generateBackupCode returns a code such as "k3j9f-2hq7d".
+func generateBackupCode() (string, error) {
+	random := make([]byte, 7)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(backupCodeEncoding.EncodeToString(random))[:2*backupCodeGroupLength]
+	return code[:backupCodeGroupLength] + "-" + code[backupCodeGroupLength:], nil
+}
+
+// hashBackupCode ignores case and separators, as users retype the codes by hand.
+func hashBackupCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return security.HashOpaqueToken(normalized)
+}
@@ -0,0 +1,39 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/log"
+	"time"
+)
+
+// APIKeyNoncePurger deletes the nonces of signed requests once they are too old to be
+// replayed, so the table only holds those still needed to refuse replays.
+type APIKeyNoncePurger struct {
+	log      log.Logger
+	service  *ServiceAccountService
+	interval time.Duration
+}
+
+func NewAPIKeyNoncePurger(service *ServiceAccountService, log log.Logger) *APIKeyNoncePurger {
+	return &APIKeyNoncePurger{
+		log:      log,
+		service:  service,
+		interval: time.Duration(config.SignatureMaxSkewSeconds.GetInt64ValueOrDefault(300)) * time.Second,
+	}
+}
+
+func (purger *APIKeyNoncePurger) Run(quit <-chan struct{}) {
+	ticker := time.NewTicker(purger.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			return
+		case now := <-ticker.C:
+			if _, err := purger.service.PurgeAPIKeyNonces(now); err != nil {
+				purger.log.Error("API key nonce purge failed: ", err.Error())
+			}
+		}
+	}
+}
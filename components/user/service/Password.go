@@ -0,0 +1,227 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/notification"
+	"banking-app-be/components/security"
+	"banking-app-be/model/credential"
+	"banking-app-be/model/password"
+	"banking-app-be/model/session"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordService struct {
+	db             *gorm.DB
+	repository     repository.Repository
+	sessionService *SessionService
+	loginGuard     *LoginGuardService
+	notifier       notification.Notifier
+}
+
+func NewPasswordService(DB *gorm.DB, repo repository.Repository, sessionService *SessionService, loginGuard *LoginGuardService, notifier notification.Notifier) *PasswordService {
+	return &PasswordService{
+		db:             DB,
+		repository:     repo,
+		sessionService: sessionService,
+		loginGuard:     loginGuard,
+		notifier:       notifier,
+	}
+}
+
+// ChangePassword replaces the password of a signed-in user, who confirms with the current one.
+// Every other session of the user is signed out; the one making the change stays. Wrong
+// current passwords are throttled like failed logins.
+func (service *PasswordService) ChangePassword(ctx context.Context, userID, sessionID uuid.UUID, currentPassword, newPassword string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Could not retrieve credentials")
+	}
+	if err := service.loginGuard.confirmPassword(&userCredential, currentPassword, time.Now()); err != nil {
+		return err
+	}
+
+	if err := service.setPassword(uow, userID, &userCredential, newPassword); err != nil {
+		return err
+	}
+	if err := service.sessionService.revokeAllExcept(uow, userID, sessionID, session.RevokedPassword); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// RequestReset sends a reset link to the email if an account has it. It succeeds either way,
+// so the answer does not reveal which emails are registered; for the same reason a message
+// that can not be sent is only logged. Earlier links stop working. Requests are throttled per
+// email and per client address.
+func (service *PasswordService) RequestReset(ctx context.Context, email, ipAddress string) error {
+
+	now := time.Now()
+	if err := service.loginGuard.countResetRequest(email, ipAddress, now); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	emailIndex, err := emailIndexOf(email)
+	if err != nil {
+		return err
+	}
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("`email_index` = ?", emailIndex)); err != nil {
+		return nil
+	}
+	userID := uuid.UUID(userCredential.UserID)
+
+	if err := service.repository.UpdateWithMap(uow, &password.ResetToken{}, map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": userID,
+	}, repository.Filter("user_id = ? AND used_at IS NULL", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke earlier reset tokens")
+	}
+
+	token, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate reset token", http.StatusInternalServerError)
+	}
+	resetToken := password.ResetToken{
+		UserID:    userID,
+		TokenHash: security.HashOpaqueToken(token),
+		ExpiresAt: now.Add(time.Duration(config.PasswordResetTTLMinutes.GetInt64ValueOrDefault(30)) * time.Minute),
+	}
+	resetToken.CreatedBy = userID
+	if err := service.repository.Add(uow, &resetToken); err != nil {
+		return errors.NewDatabaseError("Failed to create reset token")
+	}
+
+	uow.Commit()
+
+	body := "Use this token to reset your password: " + token
+	if resetURL := config.PasswordResetURL.GetStringValue(); resetURL != "" {
+		body = "Reset your password here: " + resetURL + "?token=" + url.QueryEscape(token)
+	}
+	body += "\nThe link expires at " + resetToken.ExpiresAt.Format(time.RFC1123) + ". Ignore this message if you did not ask for it."
+
+	if err := service.notifier.Notify(notification.Message{
+		To:      string(userCredential.Email),
+		Subject: "Password reset",
+		Body:    body,
+		SentAt:  now,
+	}); err != nil {
+		log.GetLogger().Error("Unable to send password reset message: " + err.Error())
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and signs the user out everywhere. The
+// token is locked while it is used, so concurrent resets with one token can not both succeed.
+func (service *PasswordService) ResetPassword(ctx context.Context, token, newPassword string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	resetToken := password.ResetToken{}
+	if err := service.repository.GetRecord(uow, &resetToken,
+		repository.Filter("token_hash = ?", security.HashOpaqueToken(token)), repository.ForUpdate()); err != nil {
+		return errors.NewUnauthorizedError("Reset token is invalid or has expired")
+	}
+	now := time.Now()
+	if !resetToken.IsUsable(now) {
+		return errors.NewUnauthorizedError("Reset token is invalid or has expired")
+	}
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", resetToken.UserID)); err != nil {
+		return errors.NewUnauthorizedError("Reset token is invalid or has expired")
+	}
+
+	if err := service.setPassword(uow, resetToken.UserID, &userCredential, newPassword); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &password.ResetToken{}, map[string]interface{}{
+		"used_at":    now,
+		"updated_by": resetToken.UserID,
+		"updated_at": now,
+	}, repository.Filter("id = ? AND used_at IS NULL", resetToken.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to use reset token")
+	}
+
+	if err := service.sessionService.revokeAll(uow, resetToken.UserID, session.RevokedPassword); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+//=======================================================================================
+
+// setPassword stores newPassword if it follows the password policy and is neither the current
+// one nor among the recent ones, and remembers it for later checks.
+func (service *PasswordService) setPassword(uow *repository.UnitOfWork, userID uuid.UUID, userCredential *credential.Credential, newPassword string) error {
+
+	passwordUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &passwordUser); err != nil {
+		return errors.NewDatabaseError("Could not retrieve user")
+	}
+	if err := credential.ValidatePassword(newPassword, string(userCredential.Email), string(passwordUser.FirstName), string(passwordUser.LastName)); err != nil {
+		return err
+	}
+
+	recentPasswords := []password.History{}
+	historySize := int(config.PasswordHistorySize.GetInt64ValueOrDefault(5))
+	if err := service.repository.GetAll(uow, &recentPasswords, repository.Filter("user_id = ?", userID),
+		repository.OrderBy("created_at DESC"), repository.Paginate(historySize, 0, nil)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch password history")
+	}
+
+	usedHashes := []string{userCredential.Password}
+	for _, recentPassword := range recentPasswords {
+		usedHashes = append(usedHashes, recentPassword.PasswordHash)
+	}
+	for _, usedHash := range usedHashes {
+		if bcrypt.CompareHashAndPassword([]byte(usedHash), []byte(newPassword)) == nil {
+			return errors.NewValidationError("Password has been used recently, choose a different one")
+		}
+	}
+
+	hashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return errors.NewValidationError("Failed to hash password")
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &credential.Credential{}, map[string]interface{}{
+		"password":            string(hashedPassword),
+		"password_changed_at": now,
+		"updated_by":          userID,
+		"updated_at":          now,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to update password")
+	}
+
+	history := password.History{UserID: userID, PasswordHash: string(hashedPassword)}
+	history.CreatedBy = userID
+	if err := service.repository.Add(uow, &history); err != nil {
+		return errors.NewDatabaseError("Failed to record password history")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package user
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/pii"
+	"banking-app-be/model/account"
+	"banking-app-be/model/apikey"
+	"banking-app-be/model/audit"
+	"banking-app-be/model/credential"
+	"banking-app-be/model/login"
+	"banking-app-be/model/password"
+	"banking-app-be/model/pin"
+	"banking-app-be/model/role"
+	"banking-app-be/model/session"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"fmt"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Erased users keep a row, as their accounts and payments still refer to it, with these names.
+const (
+	erasedFirstName = "Erased"
+	erasedLastName  = "User"
+)
+
+// Export gathers everything held about a user: the profile, credential metadata, roles,
+// accounts with their passbooks, login history, the audit entries about their records and
+// what they changed elsewhere.
+func (service *UserService) Export(userID uuid.UUID, export *user.Export) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	exportedUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &exportedUser); err != nil {
+		return errors.NewNotFoundError("User not found")
+	}
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch credentials")
+	}
+
+	factor, err := service.mfaService.factorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	pins := 0
+	if err := service.repository.GetCount(uow, &pin.TransactionPIN{}, &pins, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch transaction PIN")
+	}
+
+	userRoles := []role.UserRole{}
+	if err := service.repository.GetAll(uow, &userRoles, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch roles")
+	}
+
+	accounts := []account.Account{}
+	if err := service.repository.GetAll(uow, &accounts, repository.Filter("user_id = ?", userID),
+		repository.PreloadAssociations([]string{"PassBook"}), repository.OrderBy("created_at")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch accounts")
+	}
+
+	history := []login.Attempt{}
+	if err := service.repository.GetAll(uow, &history, repository.Filter("user_id = ?", userID),
+		repository.OrderBy("attempted_at")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch login history")
+	}
+
+	// Entries about the user's own records are handed over whole. Of the changes they made to
+	// other records only their own part is, as the records and values belong to others.
+	entityIDs := []string{userID.String(), userCredential.ID.String()}
+	for _, userRole := range userRoles {
+		entityIDs = append(entityIDs, userRole.ID.String())
+	}
+	for _, userAccount := range accounts {
+		entityIDs = append(entityIDs, userAccount.ID.String())
+	}
+	entries := []audit.Entry{}
+	if err := service.repository.GetAll(uow, &entries, repository.Filter("entity_id IN (?)", entityIDs),
+		repository.OrderBy("occurred_at, id")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch audit entries")
+	}
+	othersEntries := []audit.Entry{}
+	if err := service.repository.GetAll(uow, &othersEntries,
+		repository.Filter("(actor_id = ? OR impersonator_id = ?) AND entity_id NOT IN (?)", userID, userID, entityIDs),
+		repository.OrderBy("occurred_at, id")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch audit entries")
+	}
+
+	*export = user.Export{
+		ExportedAt: time.Now(),
+		Profile: user.ExportProfile{
+			ID:           exportedUser.ID,
+			FirstName:    exportedUser.FirstName,
+			LastName:     exportedUser.LastName,
+			PhoneNo:      exportedUser.PhoneNo,
+			IsAdmin:      exportedUser.IsAdmin,
+			IsActive:     exportedUser.IsActive,
+			TotalBalance: exportedUser.TotalBalance,
+			CreatedAt:    exportedUser.CreatedAt,
+			UpdatedAt:    exportedUser.UpdatedAt,
+		},
+		Credential: user.ExportCredential{
+			Email:             userCredential.Email,
+			CreatedAt:         userCredential.CreatedAt,
+			PasswordChangedAt: userCredential.PasswordChangedAt,
+			MFAEnabled:        factor != nil && factor.IsEnabled(),
+			TransactionPINSet: pins > 0,
+		},
+		Roles:        []string{},
+		Accounts:     accounts,
+		LoginHistory: history,
+		AuditEntries: entries,
+		Actions:      []user.ExportAction{},
+	}
+	for _, userRole := range userRoles {
+		export.Roles = append(export.Roles, userRole.Role)
+	}
+	for _, entry := range othersEntries {
+		export.Actions = append(export.Actions, user.ExportAction{
+			OccurredAt:    entry.OccurredAt,
+			Action:        entry.Action,
+			EntityType:    entry.EntityType,
+			Impersonating: entry.ImpersonatorID != nil && *entry.ImpersonatorID == userID,
+			RequestID:     entry.RequestID,
+			IPAddress:     entry.IPAddress,
+		})
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// erase anonymizes a user in place of deleting them. Their names, phone number, email and the
+// addresses they signed in from are overwritten and every way of signing in is removed. Their
+// accounts, passbooks and payments are kept, frozen, as banks must retain financial records;
+// they now belong to an anonymous user. Audit entries are append-only and are kept as well;
+// those written since personal data is encrypted only record it redacted.
+func (service *UserService) erase(uow *repository.UnitOfWork, userID, erasedBy uuid.UUID) error {
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+		"first_name": pii.String(erasedFirstName),
+		"last_name":  pii.String(erasedLastName),
+		"phone_no":   pii.String(""),
+		"is_active":  false,
+		"erased_at":  now,
+		"deleted_at": now,
+		"deleted_by": erasedBy,
+	}, repository.Filter("id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to erase user")
+	}
+
+	// The email is replaced by one nobody can hold, keeping its blind index unique.
+	if err := service.repository.UpdateWithMap(uow, &credential.Credential{}, map[string]interface{}{
+		"email":      pii.String(fmt.Sprintf("erased-%s@erased.invalid", userID)),
+		"password":   "",
+		"deleted_at": now,
+		"deleted_by": erasedBy,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to erase credentials")
+	}
+
+	if err := service.sessionService.revokeAll(uow, userID, session.RevokedUser); err != nil {
+		return err
+	}
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, map[string]interface{}{
+		"ip_address": "",
+		"user_agent": "",
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to erase sessions")
+	}
+	if err := service.repository.UpdateWithMap(uow, &login.Attempt{}, map[string]interface{}{
+		"email":      pii.String(""),
+		"ip_address": "",
+		"user_agent": "",
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to erase login history")
+	}
+
+	if err := service.mfaService.removeFactor(uow, userID); err != nil {
+		return err
+	}
+	removal := map[string]interface{}{
+		"deleted_at": now,
+		"deleted_by": erasedBy,
+	}
+	for _, secret := range []interface{}{&pin.TransactionPIN{}, &password.History{}, &password.ResetToken{}} {
+		if err := service.repository.UpdateWithMap(uow, secret, removal, repository.Filter("user_id = ?", userID)); err != nil {
+			return errors.NewDatabaseError("Failed to remove credentials")
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &apikey.ServiceAccount{}, map[string]interface{}{
+		"is_active":  false,
+		"updated_by": erasedBy,
+		"updated_at": now,
+	}, repository.Filter("owner_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to deactivate service accounts")
+	}
+	if err := service.repository.UpdateWithMap(uow, &account.Account{}, map[string]interface{}{
+		"is_active":  false,
+		"updated_by": erasedBy,
+		"updated_at": now,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to freeze accounts")
+	}
+	return nil
+}
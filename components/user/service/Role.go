@@ -0,0 +1,249 @@
+package user
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/model/bank"
+	"banking-app-be/model/role"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+func (service *UserService) GetRoles(userID uuid.UUID, userRoles *[]role.UserRole) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.doesUserExist(userID); err != nil {
+		return err
+	}
+
+	roleNames, err := service.rolesOf(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	*userRoles = []role.UserRole{}
+	for _, name := range roleNames {
+		*userRoles = append(*userRoles, role.UserRole{UserID: userID, Role: name})
+	}
+	return nil
+}
+
+// AssignRoles replaces the roles of a user. The user's admin flag follows the roles: holding any
+// staff role makes the user an admin.
+func (service *UserService) AssignRoles(ctx context.Context, userID uuid.UUID, roles []string, assignedBy uuid.UUID, userRoles *[]role.UserRole) error {
+
+	roles, err := role.ValidateAssignment(roles)
+	if err != nil {
+		return err
+	}
+
+	if err := service.doesUserExist(userID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	currentRoles, err := service.rolesOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if containsRole(currentRoles, role.SuperAdmin) && !containsRole(roles, role.SuperAdmin) {
+		if err := service.ensureAnotherSuperAdmin(uow, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &role.UserRole{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": assignedBy,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to clear existing roles")
+	}
+
+	*userRoles = []role.UserRole{}
+	isAdmin := false
+	for _, name := range roles {
+		userRole := role.UserRole{UserID: userID, Role: name}
+		userRole.CreatedBy = assignedBy
+		if err := service.repository.Add(uow, &userRole); err != nil {
+			return errors.NewDatabaseError("Failed to assign role")
+		}
+		*userRoles = append(*userRoles, userRole)
+		isAdmin = isAdmin || role.IsStaff(name)
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
+		"is_admin":   isAdmin,
+		"updated_by": assignedBy,
+		"updated_at": time.Now(),
+	}, repository.Filter("id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to update user")
+	}
+
+	uow.Commit()
+	security.InvalidatePrincipal(userID)
+	return nil
+}
+
+// GetBankScope returns the banks a staff member is assigned to.
+func (service *UserService) GetBankScope(userID uuid.UUID, userBanks *[]role.UserBank) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.doesUserExist(userID); err != nil {
+		return err
+	}
+
+	if err := service.repository.GetAll(uow, userBanks, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Unable to fetch assigned banks")
+	}
+	return nil
+}
+
+// AssignBanks replaces the banks a staff member is assigned to. Only staff can hold banks, and
+// only an administrator of every bank may hand them out.
+func (service *UserService) AssignBanks(ctx context.Context, userID uuid.UUID, bankIDs []uuid.UUID, assignedBy uuid.UUID, scope security.BankScope, userBanks *[]role.UserBank) error {
+
+	if err := scope.CheckGlobal(); err != nil {
+		return err
+	}
+
+	if err := service.doesUserExist(userID); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	roles, err := service.rolesOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	if !role.IsBankScoped(roles) {
+		return errors.NewValidationError("Banks can only be assigned to staff other than super-admins")
+	}
+
+	for _, bankID := range bankIDs {
+		exists, err := repository.DoesRecordExistForUser(service.db, bankID, bank.Bank{},
+			repository.Filter("`id` = ?", bankID))
+		if !exists || err != nil {
+			return errors.NewValidationError("Bank " + bankID.String() + " does not exist")
+		}
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &role.UserBank{}, map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": assignedBy,
+	}, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to clear assigned banks")
+	}
+
+	*userBanks = []role.UserBank{}
+	assigned := map[uuid.UUID]bool{}
+	for _, bankID := range bankIDs {
+		if assigned[bankID] {
+			continue
+		}
+		assigned[bankID] = true
+
+		userBank := role.UserBank{UserID: userID, BankID: bankID}
+		userBank.CreatedBy = assignedBy
+		if err := service.repository.Add(uow, &userBank); err != nil {
+			return errors.NewDatabaseError("Failed to assign bank")
+		}
+		*userBanks = append(*userBanks, userBank)
+	}
+
+	uow.Commit()
+	security.InvalidatePrincipal(userID)
+	return nil
+}
+
+//=======================================================================================
+
+// bankScopeOf returns the banks a user holding roles may manage. Staff without assigned banks
+// manage none.
+func (service *UserService) bankScopeOf(uow *repository.UnitOfWork, userID uuid.UUID, roles []string) (security.BankScope, error) {
+
+	if !role.IsBankScoped(roles) {
+		return security.Unrestricted, nil
+	}
+
+	userBanks := []role.UserBank{}
+	if err := service.repository.GetAll(uow, &userBanks, repository.Filter("user_id = ?", userID)); err != nil {
+		return security.BankScope{}, errors.NewDatabaseError("Unable to fetch assigned banks")
+	}
+
+	scope := security.BankScope{Restricted: true, BankIDs: []uuid.UUID{}}
+	for _, userBank := range userBanks {
+		scope.BankIDs = append(scope.BankIDs, userBank.BankID)
+	}
+	return scope, nil
+}
+
+// rolesOf returns the roles of a user. Users created before roles existed hold the role their
+// admin flag implies.
+func (service *UserService) rolesOf(uow *repository.UnitOfWork, userID uuid.UUID) ([]string, error) {
+
+	userRoles := []role.UserRole{}
+	if err := service.repository.GetAll(uow, &userRoles, repository.Filter("user_id = ?", userID)); err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch user roles")
+	}
+
+	roleNames := []string{}
+	for _, userRole := range userRoles {
+		roleNames = append(roleNames, userRole.Role)
+	}
+	if len(roleNames) > 0 {
+		return roleNames, nil
+	}
+
+	roleUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &roleUser); err != nil {
+		return nil, errors.NewNotFoundError("User not found")
+	}
+	if roleUser.IsAdmin != nil && *roleUser.IsAdmin {
+		return []string{role.SuperAdmin}, nil
+	}
+	return []string{role.Customer}, nil
+}
+
+// grantRole adds a role to a user created in the same unit of work.
+func (service *UserService) grantRole(uow *repository.UnitOfWork, userID uuid.UUID, name string, grantedBy uuid.UUID) error {
+	userRole := role.UserRole{UserID: userID, Role: name}
+	userRole.CreatedBy = grantedBy
+	if err := service.repository.Add(uow, &userRole); err != nil {
+		return errors.NewDatabaseError("Failed to assign role")
+	}
+	return nil
+}
+
+// ensureAnotherSuperAdmin keeps the deployment from losing its last super-admin.
+func (service *UserService) ensureAnotherSuperAdmin(uow *repository.UnitOfWork, userID uuid.UUID) error {
+	count := 0
+	if err := service.repository.GetCount(uow, &role.UserRole{}, &count,
+		repository.Filter("role = ? AND user_id <> ?", role.SuperAdmin, userID)); err != nil {
+		return errors.NewDatabaseError("Unable to count super-admins")
+	}
+	if count == 0 {
+		return errors.NewValidationError("Can not remove the last super-admin")
+	}
+	return nil
+}
+
+func containsRole(roles []string, name string) bool {
+	for _, candidate := range roles {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
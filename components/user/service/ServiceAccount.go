@@ -0,0 +1,404 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/account"
+	"banking-app-be/model/apikey"
+	"banking-app-be/module/repository"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+// ServiceAccountService manages the service accounts of users and authenticates their API keys.
+type ServiceAccountService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewServiceAccountService(DB *gorm.DB, repo repository.Repository) *ServiceAccountService {
+	return &ServiceAccountService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+func (service *ServiceAccountService) CreateServiceAccount(ctx context.Context, ownerID uuid.UUID, newServiceAccount *apikey.ServiceAccount) error {
+
+	if err := newServiceAccount.Validate(); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	isActive := true
+	newServiceAccount.ID = uuid.Nil
+	newServiceAccount.OwnerID = ownerID
+	newServiceAccount.IsActive = &isActive
+	newServiceAccount.Keys = nil
+	newServiceAccount.CreatedBy = ownerID
+	if err := service.repository.Add(uow, newServiceAccount); err != nil {
+		return errors.NewDatabaseError("Failed to create service account")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetServiceAccounts lists the service accounts of a user with their keys.
+func (service *ServiceAccountService) GetServiceAccounts(ownerID uuid.UUID, serviceAccounts *[]apikey.ServiceAccount) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if err := service.repository.GetAll(uow, serviceAccounts, repository.Filter("owner_id = ?", ownerID),
+		repository.PreloadAssociations([]string{"Keys", "Keys.Accounts"}), repository.OrderBy("created_at")); err != nil {
+		return errors.NewDatabaseError("Unable to fetch service accounts")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// DeactivateServiceAccount stops every key of a service account from authenticating.
+func (service *ServiceAccountService) DeactivateServiceAccount(ctx context.Context, ownerID, serviceAccountID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if _, err := service.serviceAccountOf(uow, ownerID, serviceAccountID); err != nil {
+		return err
+	}
+	if err := service.repository.UpdateWithMap(uow, &apikey.ServiceAccount{}, map[string]interface{}{
+		"is_active":  false,
+		"updated_by": ownerID,
+		"updated_at": time.Now(),
+	}, repository.Filter("id = ?", serviceAccountID)); err != nil {
+		return errors.NewDatabaseError("Failed to deactivate service account")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// IssueAPIKey creates a key for a service account, restricted to accounts of its owner. A limit
+// above the step-up threshold is confirmed with the owner's PIN or MFA code, as the key can move
+// up to it without further proof. The key and its signing secret are only returned here.
+func (service *ServiceAccountService) IssueAPIKey(ctx context.Context, ownerID, serviceAccountID uuid.UUID, newKey *apikey.NewKey, proof security.StepUp, issuedKey *apikey.IssuedKey) error {
+
+	if err := newKey.Validate(); err != nil {
+		return err
+	}
+
+	maxAmount := newKey.MaxAmount
+	if maxAmount == 0 {
+		maxAmount = security.StepUpThreshold()
+	}
+	if err := security.RequireStepUp(ctx, ownerID, maxAmount, proof); err != nil {
+		return err
+	}
+
+	ttlDays := newKey.ExpiresInDays
+	if ttlDays == 0 {
+		ttlDays = int(config.APIKeyDefaultTTLDays.GetInt64ValueOrDefault(90))
+	}
+	if maxTTLDays := int(config.APIKeyMaxTTLDays.GetInt64ValueOrDefault(365)); ttlDays > maxTTLDays {
+		return errors.NewValidationError(fmt.Sprintf("API keys can not be valid for more than %d days", maxTTLDays))
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	serviceAccount, err := service.serviceAccountOf(uow, ownerID, serviceAccountID)
+	if err != nil {
+		return err
+	}
+	if serviceAccount.IsActive == nil || !*serviceAccount.IsActive {
+		return errors.NewValidationError("Service account is deactivated")
+	}
+
+	ownedCount := 0
+	if err := service.repository.GetCount(uow, &account.Account{}, &ownedCount,
+		repository.Filter("id IN (?) AND user_id = ?", newKey.AccountIDs, ownerID)); err != nil {
+		return errors.NewDatabaseError("Unable to check accounts")
+	}
+	keyAccountIDs := distinctIDs(newKey.AccountIDs)
+	if ownedCount != len(keyAccountIDs) {
+		return errors.NewValidationError("API keys can only be restricted to your own accounts")
+	}
+
+	key, prefix, err := security.GenerateAPIKey()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate API key", http.StatusInternalServerError)
+	}
+	signingSecret, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate signing secret", http.StatusInternalServerError)
+	}
+
+	issuedKey.APIKey = apikey.APIKey{
+		ServiceAccountID: serviceAccountID,
+		Name:             newKey.Name,
+		Prefix:           prefix,
+		KeyHash:          security.HashOpaqueToken(key),
+		SigningSecret:    signingSecret,
+		RequireSignature: &newKey.RequireSignature,
+		Scopes:           strings.Join(newKey.Scopes, " "),
+		MaxAmount:        maxAmount,
+		ExpiresAt:        time.Now().AddDate(0, 0, ttlDays),
+	}
+	issuedKey.CreatedBy = ownerID
+	if err := service.repository.Add(uow, &issuedKey.APIKey); err != nil {
+		return errors.NewDatabaseError("Failed to create API key")
+	}
+
+	for _, accountID := range keyAccountIDs {
+		keyAccount := apikey.KeyAccount{APIKeyID: issuedKey.ID, AccountID: accountID}
+		keyAccount.CreatedBy = ownerID
+		if err := service.repository.Add(uow, &keyAccount); err != nil {
+			return errors.NewDatabaseError("Failed to restrict API key to accounts")
+		}
+		issuedKey.Accounts = append(issuedKey.Accounts, keyAccount)
+	}
+	issuedKey.Key = key
+	issuedKey.SigningSecret = signingSecret
+
+	uow.Commit()
+	return nil
+}
+
+// RevokeAPIKey stops a key from authenticating. Its usage log is kept.
+func (service *ServiceAccountService) RevokeAPIKey(ctx context.Context, ownerID, serviceAccountID, keyID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	key, err := service.keyOf(uow, ownerID, serviceAccountID, keyID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return errors.NewValidationError("API key is already revoked")
+	}
+
+	now := time.Now()
+	if err := service.repository.UpdateWithMap(uow, &apikey.APIKey{}, map[string]interface{}{
+		"revoked_at": now,
+		"updated_by": ownerID,
+		"updated_at": now,
+	}, repository.Filter("id = ?", keyID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke API key")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// GetAPIKeyUsage lists the requests made with a key, newest first.
+func (service *ServiceAccountService) GetAPIKeyUsage(ownerID, serviceAccountID, keyID uuid.UUID, usage *[]apikey.Usage, totalCount *int, limit, offset int) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	if _, err := service.keyOf(uow, ownerID, serviceAccountID, keyID); err != nil {
+		return err
+	}
+
+	err := service.repository.GetAll(uow, usage, repository.Filter("api_key_id = ?", keyID),
+		repository.OrderBy("used_at DESC"), repository.Paginate(limit, offset, totalCount))
+	if err != nil {
+		return errors.NewDatabaseError("Unable to fetch API key usage")
+	}
+
+	err = service.repository.GetCount(uow, usage, totalCount, repository.Filter("api_key_id = ?", keyID))
+	if err != nil {
+		return errors.NewDatabaseError("Unable to count API key usage")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// AuthenticateAPIKey finds the key by its prefix and checks the rest against the stored hash.
+func (service *ServiceAccountService) AuthenticateAPIKey(key string) (*security.APIKeyIdentity, error) {
+
+	prefix, ok := security.APIKeyPrefixOf(key)
+	if !ok {
+		return nil, errInvalidAPIKey
+	}
+
+	storedKey, identity, err := service.usableKey(prefix)
+	if err != nil {
+		return identity, err
+	}
+	if subtle.ConstantTimeCompare([]byte(security.HashOpaqueToken(key)), []byte(storedKey.KeyHash)) != 1 {
+		return identity, errInvalidAPIKey
+	}
+	if storedKey.RequireSignature != nil && *storedKey.RequireSignature {
+		return identity, errors.NewUnauthorizedError("This API key only accepts signed requests")
+	}
+	return identity, nil
+}
+
+// APIKeySigningSecret returns the signing secret of the key with the prefix.
+func (service *ServiceAccountService) APIKeySigningSecret(prefix string) (*security.APIKeyIdentity, []byte, error) {
+
+	storedKey, identity, err := service.usableKey(prefix)
+	if err != nil {
+		return identity, nil, err
+	}
+	return identity, []byte(storedKey.SigningSecret), nil
+}
+
+// UseAPIKeyNonce spends a nonce of a key. The unique index on key and nonce refuses it when a
+// concurrent request spent it first.
+func (service *ServiceAccountService) UseAPIKeyNonce(keyID uuid.UUID, nonce string, usedAt time.Time) error {
+
+	replayed := errors.NewUnauthorizedError("Request nonce has already been used")
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	usedCount := 0
+	if err := service.repository.GetCount(uow, &apikey.Nonce{}, &usedCount,
+		repository.Filter("api_key_id = ? AND nonce = ?", keyID, nonce)); err != nil {
+		return errors.NewDatabaseError("Unable to check request nonce")
+	}
+	if usedCount > 0 {
+		return replayed
+	}
+
+	usedNonce := apikey.Nonce{APIKeyID: keyID, Nonce: nonce, UsedAt: usedAt}
+	if err := service.repository.Add(uow, &usedNonce); err != nil {
+		return replayed
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// PurgeAPIKeyNonces deletes the nonces spent more than twice the allowed clock skew before now.
+// A request signed that long ago is refused for its timestamp before its nonce is looked at, so
+// the nonce is no longer needed. It returns how many nonces were deleted.
+func (service *ServiceAccountService) PurgeAPIKeyNonces(now time.Time) (int64, error) {
+	maxSkew := time.Duration(config.SignatureMaxSkewSeconds.GetInt64ValueOrDefault(300)) * time.Second
+	result := service.db.Unscoped().Where("used_at < ?", now.Add(-2*maxSkew)).Delete(&apikey.Nonce{})
+	if result.Error != nil {
+		return 0, errors.NewDatabaseError("Failed to purge request nonces")
+	}
+	return result.RowsAffected, nil
+}
+
+// RecordAPIKeyUsage adds a request to the usage log of a key. Failures are only logged, so a
+// request is not refused because its usage could not be written.
+func (service *ServiceAccountService) RecordAPIKeyUsage(keyID uuid.UUID, request security.APIKeyRequest) {
+
+	uow := repository.NewUnitOfWork(service.db, false)
+	defer uow.RollBack()
+
+	usage := apikey.Usage{
+		APIKeyID:   keyID,
+		UsedAt:     request.UsedAt,
+		Method:     request.Method,
+		Path:       request.Path,
+		IPAddress:  request.IPAddress,
+		StatusCode: request.StatusCode,
+	}
+	if err := service.repository.Add(uow, &usage); err != nil {
+		log.GetLogger().Error("Failed to record API key usage: ", err.Error())
+		return
+	}
+	if err := service.repository.UpdateWithMap(uow, &apikey.APIKey{}, map[string]interface{}{
+		"last_used_at": request.UsedAt,
+	}, repository.Filter("id = ?", keyID)); err != nil {
+		log.GetLogger().Error("Failed to record API key usage: ", err.Error())
+		return
+	}
+
+	uow.Commit()
+}
+
+//=======================================================================================
+
+var errInvalidAPIKey = errors.NewUnauthorizedError("Invalid or expired API key")
+
+// usableKey returns the key with the prefix if it and its service account may authenticate.
+func (service *ServiceAccountService) usableKey(prefix string) (*apikey.APIKey, *security.APIKeyIdentity, error) {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	keys := []apikey.APIKey{}
+	if err := service.repository.GetAll(uow, &keys, repository.Filter("prefix = ?", prefix),
+		repository.PreloadAssociations([]string{"Accounts"})); err != nil {
+		return nil, nil, errors.NewDatabaseError("Unable to check API key")
+	}
+	if len(keys) == 0 {
+		return nil, nil, errInvalidAPIKey
+	}
+	storedKey := keys[0]
+
+	serviceAccount := apikey.ServiceAccount{}
+	if err := service.repository.GetRecordByID(uow, storedKey.ServiceAccountID, &serviceAccount); err != nil {
+		return nil, nil, errInvalidAPIKey
+	}
+
+	identity := &security.APIKeyIdentity{
+		KeyID:            storedKey.ID,
+		ServiceAccountID: serviceAccount.ID,
+		OwnerID:          serviceAccount.OwnerID,
+		Scopes:           storedKey.ScopeList(),
+		AccountIDs:       storedKey.AccountIDs(),
+		MaxAmount:        storedKey.MaxAmount,
+	}
+	if !storedKey.IsUsable(time.Now()) || serviceAccount.IsActive == nil || !*serviceAccount.IsActive {
+		return nil, identity, errInvalidAPIKey
+	}
+
+	uow.Commit()
+	return &storedKey, identity, nil
+}
+
+// serviceAccountOf returns a service account of the owner, answering not found for anyone else's.
+func (service *ServiceAccountService) serviceAccountOf(uow *repository.UnitOfWork, ownerID, serviceAccountID uuid.UUID) (*apikey.ServiceAccount, error) {
+	serviceAccount := apikey.ServiceAccount{}
+	if err := service.repository.GetRecord(uow, &serviceAccount,
+		repository.Filter("id = ? AND owner_id = ?", serviceAccountID, ownerID)); err != nil {
+		return nil, errors.NewHTTPError("Service account not found with given Id", http.StatusNotFound)
+	}
+	return &serviceAccount, nil
+}
+
+func (service *ServiceAccountService) keyOf(uow *repository.UnitOfWork, ownerID, serviceAccountID, keyID uuid.UUID) (*apikey.APIKey, error) {
+	if _, err := service.serviceAccountOf(uow, ownerID, serviceAccountID); err != nil {
+		return nil, err
+	}
+	key := apikey.APIKey{}
+	if err := service.repository.GetRecord(uow, &key,
+		repository.Filter("id = ? AND service_account_id = ?", keyID, serviceAccountID)); err != nil {
+		return nil, errors.NewHTTPError("API key not found with given Id", http.StatusNotFound)
+	}
+	return &key, nil
+}
+
+func distinctIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	distinct := []uuid.UUID{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			distinct = append(distinct, id)
+		}
+	}
+	return distinct
+}
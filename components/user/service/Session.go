@@ -0,0 +1,216 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/log"
+	"banking-app-be/components/security"
+	"banking-app-be/model/session"
+	"banking-app-be/model/user"
+	"banking-app-be/module/repository"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+type SessionService struct {
+	db         *gorm.DB
+	repository repository.Repository
+}
+
+func NewSessionService(DB *gorm.DB, repo repository.Repository) *SessionService {
+	return &SessionService{
+		db:         DB,
+		repository: repo,
+	}
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token is retired; if a
+// retired token is ever presented again every session of its user is revoked, since one of the
+// two parties holding it must be an attacker. The session is locked while it is rotated, so of
+// two refreshes with the same token the second sees it rotated and is treated as reuse.
+func (service *SessionService) Refresh(ctx context.Context, refreshToken string, client *session.Session, tokens *session.TokenPair) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	current := session.Session{}
+	err := service.repository.GetRecord(uow, &current, repository.Filter("refresh_token_hash = ?", security.HashOpaqueToken(refreshToken)),
+		repository.ForUpdate())
+	if err != nil {
+		return errors.NewUnauthorizedError("Invalid refresh token")
+	}
+
+	now := time.Now()
+	if current.RevokedAt != nil && current.RevokedReason == session.RevokedRotated {
+		log.GetLogger().Warn("Refresh token reused for user ", current.UserID, ", revoking all sessions")
+		if err := service.revokeAll(uow, current.UserID, session.RevokedReuse); err != nil {
+			return err
+		}
+		uow.Commit()
+		return errors.NewUnauthorizedError("Refresh token has already been used")
+	}
+	if !current.IsUsable(now) {
+		return errors.NewUnauthorizedError("Session has expired or been revoked")
+	}
+	if current.ImpersonatorID != nil {
+		return errors.NewUnauthorizedError("Impersonation sessions can not be refreshed")
+	}
+
+	sessionUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, current.UserID, &sessionUser); err != nil {
+		return errors.NewUnauthorizedError("Session user no longer exists")
+	}
+
+	client.UserID = current.UserID
+	if err := service.open(uow, &sessionUser, client, tokens); err != nil {
+		return err
+	}
+
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, map[string]interface{}{
+		"revoked_at":     now,
+		"revoked_reason": session.RevokedRotated,
+		"replaced_by_id": client.ID,
+		"last_used_at":   now,
+		"updated_by":     current.UserID,
+		"updated_at":     now,
+	}, repository.Filter("id = ?", current.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to rotate session")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// Logout revokes a single session of the user.
+func (service *SessionService) Logout(ctx context.Context, userID, sessionID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, revocation(userID, session.RevokedLogout),
+		repository.Filter("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke session")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// LogoutAll revokes every session of the user, signing out all of their devices.
+func (service *SessionService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	if err := service.revokeAll(uow, userID, session.RevokedLogoutAll); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *SessionService) GetActiveSessions(userID uuid.UUID, allSessions *[]session.Session) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	err := service.repository.GetAll(uow, allSessions,
+		repository.Filter("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()),
+		repository.OrderBy("created_at DESC"))
+	if err != nil {
+		return errors.NewDatabaseError("Unable to fetch sessions")
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// IsSessionActive implements security.SessionChecker.
+func (service *SessionService) IsSessionActive(sessionID uuid.UUID) bool {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	current := session.Session{}
+	if err := service.repository.GetRecordByID(uow, sessionID, &current); err != nil {
+		return false
+	}
+	return current.IsUsable(time.Now())
+}
+
+//=======================================================================================
+
+// open starts a session for sessionUser on the client and issues its first token pair.
+func (service *SessionService) open(uow *repository.UnitOfWork, sessionUser *user.User, client *session.Session, tokens *session.TokenPair) error {
+
+	refreshToken, err := security.GenerateOpaqueToken()
+	if err != nil {
+		return errors.NewHTTPError("Unable to generate refresh token", http.StatusInternalServerError)
+	}
+
+	now := time.Now()
+	client.ID = uuid.NewV4()
+	client.UserID = sessionUser.ID
+	client.RefreshTokenHash = security.HashOpaqueToken(refreshToken)
+	client.ExpiresAt = now.Add(time.Duration(config.RefreshTokenTTLHours.GetInt64ValueOrDefault(168)) * time.Hour)
+	client.LastUsedAt = &now
+	client.CreatedBy = sessionUser.ID
+	if err := service.repository.Add(uow, client); err != nil {
+		return errors.NewDatabaseError("Failed to create session")
+	}
+
+	accessTTL := time.Duration(config.AccessTokenTTLMinutes.GetInt64ValueOrDefault(15)) * time.Minute
+	claim := security.Claims{
+		UserID:   sessionUser.ID,
+		IsAdmin:  sessionUser.IsAdmin != nil && *sessionUser.IsAdmin,
+		IsActive: sessionUser.IsActive != nil && *sessionUser.IsActive,
+		StandardClaims: jwt.StandardClaims{
+			Id:        client.ID.String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTTL).Unix(),
+		},
+	}
+	accessToken, err := claim.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	*tokens = session.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(accessTTL.Seconds()),
+	}
+	return nil
+}
+
+func (service *SessionService) revokeAll(uow *repository.UnitOfWork, userID uuid.UUID, reason string) error {
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, revocation(userID, reason),
+		repository.Filter("user_id = ? AND revoked_at IS NULL", userID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke sessions")
+	}
+	return nil
+}
+
+// revokeAllExcept revokes every session of the user but keepSessionID.
+func (service *SessionService) revokeAllExcept(uow *repository.UnitOfWork, userID, keepSessionID uuid.UUID, reason string) error {
+	if err := service.repository.UpdateWithMap(uow, &session.Session{}, revocation(userID, reason),
+		repository.Filter("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepSessionID)); err != nil {
+		return errors.NewDatabaseError("Failed to revoke sessions")
+	}
+	return nil
+}
+
+func revocation(revokedBy uuid.UUID, reason string) map[string]interface{} {
+	return map[string]interface{}{
+		"revoked_at":     time.Now(),
+		"revoked_reason": reason,
+		"updated_by":     revokedBy,
+		"updated_at":     time.Now(),
+	}
+}
@@ -0,0 +1,274 @@
+package user
+
+import (
+	"banking-app-be/components/config"
+	"banking-app-be/components/errors"
+	"banking-app-be/components/security"
+	"banking-app-be/model/credential"
+	"banking-app-be/model/pin"
+	"banking-app-be/module/repository"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type StepUpService struct {
+	db         *gorm.DB
+	repository repository.Repository
+	mfaService *MFAService
+	loginGuard *LoginGuardService
+	clock      security.Clock
+}
+
+// NewStepUpService reads the time from clock, or from the system when clock is nil.
+func NewStepUpService(DB *gorm.DB, repo repository.Repository, mfaService *MFAService, loginGuard *LoginGuardService, clock security.Clock) *StepUpService {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &StepUpService{
+		db:         DB,
+		repository: repo,
+		mfaService: mfaService,
+		loginGuard: loginGuard,
+		clock:      clock,
+	}
+}
+
+func (service *StepUpService) GetPINStatus(userID uuid.UUID, status *pin.Status) error {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	transactionPIN, err := service.pinOf(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	status.ThresholdAmount = security.StepUpThreshold()
+	if transactionPIN != nil {
+		status.IsSet = true
+		if transactionPIN.IsLocked(service.clock()) {
+			status.LockedUntil = transactionPIN.LockedUntil
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// SetPIN sets or replaces the transaction PIN of a user, who confirms with their password. A
+// locked PIN can not be replaced until the lock runs out, and wrong passwords are throttled
+// like failed logins.
+func (service *StepUpService) SetPIN(ctx context.Context, userID uuid.UUID, password, newPIN string) error {
+
+	if err := pin.Validate(newPIN); err != nil {
+		return err
+	}
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+		return errors.NewDatabaseError("Could not retrieve credentials")
+	}
+	if err := service.loginGuard.confirmPassword(&userCredential, password, service.clock()); err != nil {
+		return err
+	}
+
+	hashedPIN, err := hashPassword(newPIN)
+	if err != nil {
+		return errors.NewHTTPError("Unable to hash transaction PIN", http.StatusInternalServerError)
+	}
+
+	existingPIN, err := service.pinOf(uow, userID)
+	if err != nil {
+		return err
+	}
+
+	now := service.clock()
+	if existingPIN == nil {
+		newTransactionPIN := pin.TransactionPIN{UserID: userID, PINHash: string(hashedPIN)}
+		newTransactionPIN.CreatedBy = userID
+		if err := service.repository.Add(uow, &newTransactionPIN); err != nil {
+			return errors.NewDatabaseError("Failed to save transaction PIN")
+		}
+	} else {
+		if existingPIN.IsLocked(now) {
+			return lockedPINError(existingPIN)
+		}
+		if err := service.repository.UpdateWithMap(uow, &pin.TransactionPIN{}, map[string]interface{}{
+			"pin_hash":        string(hashedPIN),
+			"failed_attempts": 0,
+			"locked_until":    nil,
+			"updated_by":      userID,
+			"updated_at":      now,
+		}, repository.Filter("id = ?", existingPIN.ID)); err != nil {
+			return errors.NewDatabaseError("Failed to update transaction PIN")
+		}
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// VerifyStepUp accepts a fresh authenticator code or the transaction PIN. Authenticator codes
+// are preferred when both are sent, and backup codes are not accepted here.
+func (service *StepUpService) VerifyStepUp(ctx context.Context, userID uuid.UUID, proof security.StepUp) error {
+
+	switch {
+	case proof.MFACode != "":
+		return service.verifyMFACode(ctx, userID, proof.MFACode)
+	case proof.PIN != "":
+		return service.verifyPIN(ctx, userID, proof.PIN)
+	default:
+		return errors.NewStepUpRequiredError(fmt.Sprintf(
+			"Transaction PIN or MFA code required for amounts above %.2f", security.StepUpThreshold()))
+	}
+}
+
+//=======================================================================================
+
+// verifyMFACode counts wrong codes on the transaction PIN like wrong PINs, so both proofs share
+// one lock. Users without a PIN have wrong codes counted against their account instead.
+func (service *StepUpService) verifyMFACode(ctx context.Context, userID uuid.UUID, code string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	factor, err := service.mfaService.enabledFactorOf(uow, userID)
+	if err != nil {
+		return err
+	}
+	transactionPIN, err := service.pinOf(uow, userID, repository.ForUpdate())
+	if err != nil {
+		return err
+	}
+
+	now := service.clock()
+	if transactionPIN == nil {
+		userCredential := credential.Credential{}
+		if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userID)); err != nil {
+			return errors.NewDatabaseError("Could not retrieve credentials")
+		}
+		if err := service.loginGuard.checkAccount(string(userCredential.Email), now); err != nil {
+			return err
+		}
+		if err := service.mfaService.verifyTOTP(uow, factor, code); err != nil {
+			if recordErr := service.loginGuard.recordAccountFailure(string(userCredential.Email), now); recordErr != nil {
+				return recordErr
+			}
+			return err
+		}
+		uow.Commit()
+		return nil
+	}
+
+	if transactionPIN.IsLocked(now) {
+		return lockedPINError(transactionPIN)
+	}
+	if err := service.mfaService.verifyTOTP(uow, factor, code); err != nil {
+		return service.countPINFailure(uow, transactionPIN, now, "Invalid MFA code")
+	}
+	if err := service.resetPINFailures(uow, transactionPIN); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+// verifyPIN counts wrong entries and locks the PIN once they reach the configured maximum. The
+// count is committed even when the PIN is wrong. The row is locked while it is checked, so
+// concurrent guesses are all counted.
+func (service *StepUpService) verifyPIN(ctx context.Context, userID uuid.UUID, value string) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	transactionPIN, err := service.pinOf(uow, userID, repository.ForUpdate())
+	if err != nil {
+		return err
+	}
+	if transactionPIN == nil {
+		return errors.NewStepUpRequiredError("Set a transaction PIN or enable MFA to confirm this operation")
+	}
+
+	now := service.clock()
+	if transactionPIN.IsLocked(now) {
+		return lockedPINError(transactionPIN)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(transactionPIN.PINHash), []byte(value)) == nil {
+		if err := service.resetPINFailures(uow, transactionPIN); err != nil {
+			return err
+		}
+		uow.Commit()
+		return nil
+	}
+	return service.countPINFailure(uow, transactionPIN, now, "Incorrect transaction PIN")
+}
+
+// resetPINFailures clears the wrong entries of a PIN once a step-up succeeds.
+func (service *StepUpService) resetPINFailures(uow *repository.UnitOfWork, transactionPIN *pin.TransactionPIN) error {
+	if transactionPIN.FailedAttempts == 0 && transactionPIN.LockedUntil == nil {
+		return nil
+	}
+	if err := service.repository.UpdateWithMap(uow, &pin.TransactionPIN{}, map[string]interface{}{
+		"failed_attempts": 0,
+		"locked_until":    nil,
+	}, repository.Filter("id = ?", transactionPIN.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to reset transaction PIN attempts")
+	}
+	return nil
+}
+
+// countPINFailure commits a wrong step-up on the PIN, locking it at the configured maximum, and
+// returns the error to answer with.
+func (service *StepUpService) countPINFailure(uow *repository.UnitOfWork, transactionPIN *pin.TransactionPIN, now time.Time, message string) error {
+
+	failure := map[string]interface{}{
+		"failed_attempts": transactionPIN.FailedAttempts + 1,
+	}
+	maxAttempts := int(config.TransactionPINMaxAttempts.GetInt64ValueOrDefault(5))
+	locked := transactionPIN.FailedAttempts+1 >= maxAttempts
+	if locked {
+		lockedUntil := now.Add(time.Duration(config.TransactionPINLockoutMinutes.GetInt64ValueOrDefault(30)) * time.Minute)
+		failure["failed_attempts"] = 0
+		failure["locked_until"] = lockedUntil
+		transactionPIN.LockedUntil = &lockedUntil
+	}
+	if err := service.repository.UpdateWithMap(uow, &pin.TransactionPIN{}, failure,
+		repository.Filter("id = ?", transactionPIN.ID)); err != nil {
+		return errors.NewDatabaseError("Failed to record transaction PIN attempt")
+	}
+	uow.Commit()
+
+	if locked {
+		return lockedPINError(transactionPIN)
+	}
+	return errors.NewUnauthorizedError(fmt.Sprintf("%s, %d attempts left",
+		message, maxAttempts-transactionPIN.FailedAttempts-1))
+}
+
+// pinOf returns the transaction PIN of a user, or nil when they have not set one.
+func (service *StepUpService) pinOf(uow *repository.UnitOfWork, userID uuid.UUID, queryProcessors ...repository.QueryProcessor) (*pin.TransactionPIN, error) {
+	transactionPINs := []pin.TransactionPIN{}
+	queryProcessors = append([]repository.QueryProcessor{repository.Filter("user_id = ?", userID)}, queryProcessors...)
+	if err := service.repository.GetAll(uow, &transactionPINs, queryProcessors...); err != nil {
+		return nil, errors.NewDatabaseError("Unable to fetch transaction PIN")
+	}
+	if len(transactionPINs) == 0 {
+		return nil, nil
+	}
+	return &transactionPINs[0], nil
+}
+
+func lockedPINError(transactionPIN *pin.TransactionPIN) error {
+	return errors.NewHTTPError("Transaction PIN is locked until "+transactionPIN.LockedUntil.Format(time.RFC3339), http.StatusLocked)
+}
@@ -3,14 +3,23 @@ package user
 import (
 	"banking-app-be/components/errors"
 	"banking-app-be/components/log"
+	"banking-app-be/components/passwordpolicy"
+	"banking-app-be/components/pii"
 	"banking-app-be/components/security"
+	"banking-app-be/model/account"
 	"banking-app-be/model/credential"
+	"banking-app-be/model/login"
+	"banking-app-be/model/mfa"
+	"banking-app-be/model/role"
+	"banking-app-be/model/session"
 	"banking-app-be/model/user"
 	"banking-app-be/module/repository"
+	"context"
 	"fmt"
+	"net/http"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	googleuuid "github.com/google/uuid"
 	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -19,23 +28,45 @@ import (
 const cost = 10
 
 type UserService struct {
-	db         *gorm.DB
-	repository repository.Repository
+	db             *gorm.DB
+	repository     repository.Repository
+	sessionService *SessionService
+	mfaService     *MFAService
+	loginGuard     *LoginGuardService
 }
 
-func NewUserService(DB *gorm.DB, repo repository.Repository) *UserService {
+func NewUserService(DB *gorm.DB, repo repository.Repository, sessionService *SessionService, mfaService *MFAService,
+	loginGuard *LoginGuardService) *UserService {
 	return &UserService{
-		db:         DB,
-		repository: repo,
+		db:             DB,
+		repository:     repo,
+		sessionService: sessionService,
+		mfaService:     mfaService,
+		loginGuard:     loginGuard,
 	}
 }
 
-func (service *UserService) CreateAdmin(newUser *user.User) error {
+// CreateAdmin creates a super-admin on behalf of another super-admin. The first one is created
+// through Bootstrap, later ones preferably through invitations.
+func (service *UserService) CreateAdmin(ctx context.Context, newUser *user.User) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
-	err := service.doesEmailExists(newUser.Credentials.Email)
+	if err := service.createStaff(uow, newUser, role.SuperAdmin); err != nil {
+		return err
+	}
+
+	uow.Commit()
+	return nil
+}
+
+func (service *UserService) CreateUser(ctx context.Context, newUser *user.User) error {
+
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
+	defer uow.RollBack()
+
+	err := service.doesEmailExists(string(newUser.Credentials.Email))
 	if err != nil {
 		return err
 	}
@@ -46,15 +77,10 @@ func (service *UserService) CreateAdmin(newUser *user.User) error {
 		return err
 	}
 
-	if err := newUser.Credentials.Validate(); err != nil {
+	if err := newUser.Credentials.Validate(string(newUser.FirstName), string(newUser.LastName)); err != nil {
 		return err
 	}
 
-	if newUser.IsAdmin == nil {
-		newUser.IsAdmin = new(bool)
-	}
-	*newUser.IsAdmin = true
-
 	hashedPassword, err := hashPassword(newUser.Credentials.Password)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
@@ -62,136 +88,174 @@ func (service *UserService) CreateAdmin(newUser *user.User) error {
 
 	newUser.Credentials.Password = string(hashedPassword)
 
-	err = uow.DB.Create(newUser).Error
-	if err != nil {
-		return errors.NewDatabaseError("Failed to create user")
+	if err := service.addUser(uow, newUser); err != nil {
+		return err
+	}
+
+	if err := service.grantRole(uow, newUser.ID, role.Customer, newUser.CreatedBy); err != nil {
+		return err
 	}
 
 	uow.Commit()
 	return nil
 }
 
-func (service *UserService) CreateUser(newUser *user.User) error {
+// Login checks the credentials and opens a session on the client, returning a short-lived
+// access token and the session's refresh token. Users with MFA get a challenge instead of
+// tokens and finish through MFAService.CompleteLogin. Every failure gets the same answer, and
+// repeated failures per email and per client address are slowed down and then locked out.
+func (service *UserService) Login(ctx context.Context, userCredential *credential.Credential, client *session.Session, tokens *session.TokenPair, challenge *mfa.Challenge) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
-	err := service.doesEmailExists(newUser.Credentials.Email)
+	now := time.Now()
+	var userID *uuid.UUID
+	emailIndex, err := emailIndexOf(string(userCredential.Email))
 	if err != nil {
 		return err
 	}
+	foundCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &foundCredential, repository.Filter("`email_index` = ?", emailIndex)); err == nil {
+		credentialUserID := uuid.UUID(foundCredential.UserID)
+		userID = &credentialUserID
+	}
 
-	if err = newUser.Validate(); err != nil {
-		log.GetLogger().Error(err.Error())
-		uow.RollBack()
+	wait, err := service.loginGuard.waitBefore(string(userCredential.Email), client.IPAddress, now)
+	if err != nil {
 		return err
 	}
+	if wait > 0 {
+		if err := service.loginGuard.recordAttempt(string(userCredential.Email), userID, client, login.OutcomeBlocked, now); err != nil {
+			return err
+		}
+		return tooManyLoginAttemptsError(wait)
+	}
 
-	if err := newUser.Credentials.Validate(); err != nil {
-		return err
+	if !comparePassword(foundCredential.Password, userCredential.Password) || userID == nil {
+		if err := service.loginGuard.recordFailure(string(userCredential.Email), userID, client, login.OutcomeFailed, now); err != nil {
+			return err
+		}
+		return errors.NewUnauthorizedError(invalidLoginMessage)
 	}
 
-	hashedPassword, err := hashPassword(newUser.Credentials.Password)
+	foundUser := user.User{}
+	err = uow.DB.Preload("Credentials").
+		Where("id = ?", *userID).First(&foundUser).Error
+
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return errors.NewDatabaseError("Could not retrieve user")
 	}
 
-	newUser.Credentials.Password = string(hashedPassword)
+	// An expired password still proves who is logging in, but buys no session until replaced.
+	// Failures are only cleared once a session is opened, so the password alone does not buy
+	// more guesses at the second factor.
+	if passwordpolicy.Current().IsExpired(foundCredential.PasswordSetAt(), now) {
+		if err := service.loginGuard.addAttempt(uow, string(userCredential.Email), &foundUser.ID, client, login.OutcomeExpired, now); err != nil {
+			return err
+		}
+		uow.Commit()
+		return errors.NewHTTPError("Password has expired, reset it through the forgot-password flow", http.StatusForbidden)
+	}
 
-	err = uow.DB.Create(newUser).Error
+	challenged, err := service.mfaService.challengeLogin(uow, foundUser.ID, challenge)
 	if err != nil {
-		return errors.NewDatabaseError("Failed to create user")
+		return err
+	}
+
+	if challenged {
+		if err := service.loginGuard.addAttempt(uow, string(userCredential.Email), &foundUser.ID, client, login.OutcomeMFARequired, now); err != nil {
+			return err
+		}
+		uow.Commit()
+		return nil
+	}
+	if err := service.loginGuard.recordSuccess(uow, string(userCredential.Email), foundUser.ID, client, login.OutcomeSuccess, now); err != nil {
+		return err
+	}
+
+	if err := service.sessionService.open(uow, &foundUser, client, tokens); err != nil {
+		return err
 	}
 
 	uow.Commit()
 	return nil
 }
 
-func (service *UserService) Login(userCredential *credential.Credential, claim *security.Claims) error {
+// Unlock lifts the login lockout of a user. Bank-scoped staff can only unlock customers of
+// their banks.
+func (service *UserService) Unlock(ctx context.Context, userToUnlock *user.User, scope security.BankScope) error {
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
-	exists, err := repository.DoesEmailExist(service.db, userCredential.Email, credential.Credential{},
-		repository.Filter("`email` = ?", userCredential.Email))
-	if err != nil {
-		return errors.NewDatabaseError("Error checking if email exists")
-	}
-	if !exists {
-		return errors.NewNotFoundError("Email not found")
-	}
-
-	foundCredential := credential.Credential{}
-	err = uow.DB.Where("email = ?", userCredential.Email).First(&foundCredential).Error
-	if err != nil {
-		return errors.NewDatabaseError("Could not retrieve credentials")
-	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(foundCredential.Password), []byte(userCredential.Password))
-	if err != nil {
-		return errors.NewInValidPasswordError("Incorrect password")
+	if err := service.checkUserInScope(uow, userToUnlock.ID, scope); err != nil {
+		return err
 	}
 
-	foundUser := user.User{}
-	err = uow.DB.Preload("Credentials").
-		Where("id = ?", foundCredential.UserID).First(&foundUser).Error
-
-	if err != nil {
-		return errors.NewDatabaseError("Could not retrieve user")
+	userCredential := credential.Credential{}
+	if err := service.repository.GetRecord(uow, &userCredential, repository.Filter("user_id = ?", userToUnlock.ID)); err != nil {
+		return errors.NewHTTPError("User not found with given Id", http.StatusNotFound)
 	}
 
-	*claim = security.Claims{
-		UserID:   foundUser.ID,
-		IsAdmin:  *foundUser.IsAdmin,
-		IsActive: *foundUser.IsActive,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(30 * time.Hour).Unix(),
-		},
+	if err := service.loginGuard.unlock(uow, string(userCredential.Email), userToUnlock.ID, userToUnlock.UpdatedBy, time.Now()); err != nil {
+		return err
 	}
 
 	uow.Commit()
 	return nil
 }
 
-func (service *UserService) GetAllUsers(allUsers *[]user.UserDTO, totalCount *int, limit, offset int) error {
+// GetAllUsers lists users; bank-scoped staff only see customers of their banks, and only the
+// accounts held with those banks.
+func (service *UserService) GetAllUsers(scope security.BankScope, allUsers *[]user.UserDTO, totalCount *int, limit, offset int) error {
 
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	err := service.repository.GetAll(uow, allUsers, repository.PreloadAssociations([]string{"Credentials", "Accounts"}), repository.Paginate(limit, offset, totalCount))
+	err := service.repository.GetAll(uow, allUsers, scope.Where(usersWithAccountsIn), repository.PreloadAssociations([]string{"Credentials", "Accounts"}), repository.Paginate(limit, offset, totalCount))
 	if err != nil {
 		return err
 	}
 
-	err = service.repository.GetCount(uow, allUsers, totalCount)
+	err = service.repository.GetCount(uow, allUsers, totalCount, scope.Where(usersWithAccountsIn))
 	if err != nil {
 		return err
 	}
 
+	for i := range *allUsers {
+		scopeAccounts(&(*allUsers)[i], scope)
+	}
+
 	uow.Commit()
 	return nil
 }
 
-func (service *UserService) GetUserByID(targetUser *user.UserDTO) error {
+func (service *UserService) GetUserByID(targetUser *user.UserDTO, scope security.BankScope) error {
 
 	uow := repository.NewUnitOfWork(service.db, true)
 	defer uow.RollBack()
 
-	err := service.repository.GetRecordByID(uow, targetUser.ID, targetUser, repository.PreloadAssociations([]string{"Credentials", "Accounts", "Accounts.Bank"}))
+	if err := service.checkUserInScope(uow, targetUser.ID, scope); err != nil {
+		return err
+	}
+
+	err := service.repository.GetRecordByID(uow, targetUser.ID, targetUser, repository.PreloadAssociations([]string{"Credentials", "Accounts", "Accounts.Bank", "Roles"}))
 	if err != nil {
 		return err
 	}
 
+	scopeAccounts(targetUser, scope)
 	return nil
 }
 
-func (service *UserService) UpdateUser(userToUpdate *user.User) error {
+func (service *UserService) UpdateUser(ctx context.Context, userToUpdate *user.User) error {
 	err := service.doesUserExist(userToUpdate.ID)
 	if err != nil {
 		return err
 	}
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
 	existingUser := user.User{}
@@ -199,6 +263,9 @@ func (service *UserService) UpdateUser(userToUpdate *user.User) error {
 		return err
 	}
 
+	// Admin rights follow the user's roles, which are only changed through AssignRoles.
+	userToUpdate.IsAdmin = existingUser.IsAdmin
+
 	if err := service.repository.UpdateWithMap(uow, &user.User{}, map[string]interface{}{
 		"first_name": userToUpdate.FirstName,
 		"last_name":  userToUpdate.LastName,
@@ -212,10 +279,14 @@ func (service *UserService) UpdateUser(userToUpdate *user.User) error {
 		return err
 	}
 
+	if err := service.revokeSessionsOnAccessChange(uow, &existingUser, userToUpdate); err != nil {
+		return err
+	}
+
 	if userToUpdate.Credentials != nil {
 		cred := userToUpdate.Credentials
 
-		if err := cred.Validate(); err != nil {
+		if err := cred.Validate(string(userToUpdate.FirstName), string(userToUpdate.LastName)); err != nil {
 			uow.RollBack()
 			return err
 		}
@@ -233,6 +304,7 @@ func (service *UserService) UpdateUser(userToUpdate *user.User) error {
 				return errors.NewValidationError("Failed to hash password")
 			}
 			updateData["password"] = string(hashedPassword)
+			updateData["password_changed_at"] = time.Now()
 		}
 
 		if err := service.repository.UpdateWithMap(uow, &credential.Credential{}, updateData,
@@ -243,30 +315,46 @@ func (service *UserService) UpdateUser(userToUpdate *user.User) error {
 	}
 
 	uow.Commit()
+	security.InvalidatePrincipal(userToUpdate.ID)
 	return nil
 }
 
-func (service *UserService) NormalUpdate(userToUpdate *user.User) error {
+func (service *UserService) NormalUpdate(ctx context.Context, userToUpdate *user.User, scope security.BankScope) error {
 
 	err := service.doesUserExist(userToUpdate.ID)
 	if err != nil {
 		return err
 	}
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
+	if err := service.checkUserInScope(uow, userToUpdate.ID, scope); err != nil {
+		return err
+	}
+
+	existingUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userToUpdate.ID, &existingUser); err != nil {
+		return err
+	}
+
+	// Admin rights follow the user's roles, which are only changed through AssignRoles.
+	userToUpdate.IsAdmin = existingUser.IsAdmin
+
 	userToUpdate.Credentials = &credential.Credential{}
 	if err := service.repository.GetRecord(uow, &userToUpdate.Credentials, repository.Filter("user_id = ?", userToUpdate.ID)); err != nil {
 		return errors.NewDatabaseError("unable to get credentials")
 	}
 
-	fmt.Println("credentails======================>", *userToUpdate.Credentials)
 	if err := service.repository.Update(uow, userToUpdate); err != nil {
 		uow.RollBack()
 		return errors.NewDatabaseError("Unable to update user record")
 	}
 
+	if err := service.revokeSessionsOnAccessChange(uow, &existingUser, userToUpdate); err != nil {
+		return err
+	}
+
 	// if userToUpdate.Credentials != nil {
 	// 	cred := userToUpdate.Credentials
 
@@ -291,50 +379,197 @@ func (service *UserService) NormalUpdate(userToUpdate *user.User) error {
 	// }
 
 	uow.Commit()
+	security.InvalidatePrincipal(userToUpdate.ID)
 	return nil
 }
 
-func (service *UserService) Delete(userToDelete *user.User) error {
+// Delete erases a user, anonymizing their personal data while keeping their financial records.
+// Bank-scoped staff can only delete customers whose every account is held with their banks.
+func (service *UserService) Delete(ctx context.Context, userToDelete *user.User, scope security.BankScope) error {
 
 	err := service.doesUserExist(userToDelete.ID)
 	if err != nil {
 		return err
 	}
 
-	uow := repository.NewUnitOfWork(service.db, false)
+	uow := repository.NewUnitOfWorkWithContext(ctx, service.db, false)
 	defer uow.RollBack()
 
-	if err := service.repository.UpdateWithMap(uow, userToDelete, map[string]interface{}{
-		"deleted_at": time.Now(),
-		"deleted_by": userToDelete.DeletedBy,
-	},
-		repository.Filter("`id`=?", userToDelete.ID)); err != nil {
-		uow.RollBack()
+	if err := service.checkUserInScope(uow, userToDelete.ID, scope); err != nil {
 		return err
 	}
+	if scope.Restricted {
+		foreignAccounts := 0
+		if err := service.repository.GetCount(uow, &account.Account{}, &foreignAccounts,
+			repository.Filter("user_id = ? AND bank_id NOT IN (?)", userToDelete.ID, scope.BankIDs)); err != nil {
+			return errors.NewDatabaseError("Unable to count user accounts")
+		}
+		if foreignAccounts > 0 {
+			return errors.NewOutOfScopeError("User also holds accounts with banks outside your scope")
+		}
+	}
 
-	if err := service.repository.UpdateWithMap(uow, &credential.Credential{}, map[string]interface{}{
-		"deleted_at": time.Now(),
-		"deleted_by": userToDelete.DeletedBy,
-	}, repository.Filter("user_id = ?", userToDelete.ID)); err != nil {
+	if err := service.erase(uow, userToDelete.ID, userToDelete.DeletedBy); err != nil {
 		return err
 	}
 
 	uow.Commit()
+	security.InvalidatePrincipal(userToDelete.ID)
 	return nil
 }
 
+// ResolvePrincipal implements security.PrincipalResolver with the user as currently stored, so
+// deactivation, demotion and deletion take effect without waiting for tokens to expire.
+func (service *UserService) ResolvePrincipal(userID uuid.UUID) (*security.Principal, error) {
+
+	uow := repository.NewUnitOfWork(service.db, true)
+	defer uow.RollBack()
+
+	currentUser := user.User{}
+	if err := service.repository.GetRecordByID(uow, userID, &currentUser); err != nil {
+		return nil, errors.NewUnauthorizedError("User no longer exists")
+	}
+
+	roles, err := service.rolesOf(uow, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bankScope, err := service.bankScopeOf(uow, userID, roles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &security.Principal{
+		UserID:      currentUser.ID,
+		IsAdmin:     currentUser.IsAdmin != nil && *currentUser.IsAdmin,
+		IsActive:    currentUser.IsActive != nil && *currentUser.IsActive,
+		Roles:       roles,
+		Permissions: role.PermissionsOf(roles...),
+		BankScope:   bankScope,
+	}, nil
+}
+
 //==================================================================================================================================
 
+// usersWithAccountsIn matches users holding an account with a bank of the scope.
+const usersWithAccountsIn = "id IN (SELECT user_id FROM accounts WHERE bank_id IN (?) AND deleted_at IS NULL)"
+
+// checkUserInScope rejects bank-scoped staff addressing a user without accounts in their banks.
+func (service *UserService) checkUserInScope(uow *repository.UnitOfWork, userID uuid.UUID, scope security.BankScope) error {
+	if !scope.Restricted {
+		return nil
+	}
+	count := 0
+	if err := service.repository.GetCount(uow, &user.User{}, &count,
+		repository.Filter("id = ?", userID), scope.Where(usersWithAccountsIn)); err != nil {
+		return errors.NewDatabaseError("Unable to check user scope")
+	}
+	if count == 0 {
+		return errors.NewOutOfScopeError("User has no accounts with banks in your scope")
+	}
+	return nil
+}
+
+// scopeAccounts drops the accounts held with banks outside the scope.
+func scopeAccounts(targetUser *user.UserDTO, scope security.BankScope) {
+	if !scope.Restricted {
+		return
+	}
+	accounts := targetUser.Accounts[:0]
+	for _, userAccount := range targetUser.Accounts {
+		if scope.Allows(userAccount.BankID) {
+			accounts = append(accounts, userAccount)
+		}
+	}
+	targetUser.Accounts = accounts
+}
+
+// createStaff creates an administrator holding staffRole.
+func (service *UserService) createStaff(uow *repository.UnitOfWork, newUser *user.User, staffRole string) error {
+
+	if newUser.Credentials == nil {
+		return errors.NewValidationError("Credentials must be specified")
+	}
+
+	err := service.doesEmailExists(string(newUser.Credentials.Email))
+	if err != nil {
+		return err
+	}
+
+	if err = newUser.Validate(); err != nil {
+		log.GetLogger().Error(err.Error())
+		return err
+	}
+
+	if err := newUser.Credentials.Validate(string(newUser.FirstName), string(newUser.LastName)); err != nil {
+		return err
+	}
+
+	if newUser.IsAdmin == nil {
+		newUser.IsAdmin = new(bool)
+	}
+	*newUser.IsAdmin = true
+
+	hashedPassword, err := hashPassword(newUser.Credentials.Password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	newUser.Credentials.Password = string(hashedPassword)
+
+	if err := service.addUser(uow, newUser); err != nil {
+		return err
+	}
+
+	return service.grantRole(uow, newUser.ID, staffRole, newUser.CreatedBy)
+}
+
+// addUser stores a new user and then their credential, both through the repository so each
+// gets its audit entry.
+func (service *UserService) addUser(uow *repository.UnitOfWork, newUser *user.User) error {
+
+	newCredential := newUser.Credentials
+	newUser.Credentials = nil
+	if err := service.repository.Add(uow, newUser); err != nil {
+		return errors.NewDatabaseError("Failed to create user")
+	}
+
+	newCredential.UserID = googleuuid.UUID(newUser.ID)
+	newCredential.CreatedBy = newUser.CreatedBy
+	if err := service.repository.Add(uow, newCredential); err != nil {
+		return errors.NewDatabaseError("Failed to create user")
+	}
+	newUser.Credentials = newCredential
+	return nil
+}
+
 func (service *UserService) doesEmailExists(Email string) error {
-	exists, _ := repository.DoesEmailExist(service.db, Email, credential.Credential{},
-		repository.Filter("`email` = ?", Email))
+	if Email == "" {
+		return nil
+	}
+	emailIndex, err := emailIndexOf(Email)
+	if err != nil {
+		return err
+	}
+	exists, _ := repository.DoesEmailExist(service.db, emailIndex, credential.Credential{},
+		repository.Filter("`email_index` = ?", emailIndex))
 	if exists {
 		return errors.NewValidationError("Email is already registered")
 	}
 	return nil
 }
 
+// emailIndexOf returns the blind index lookups of email go through.
+func emailIndexOf(email string) (string, error) {
+	emailIndex, err := pii.BlindIndex(email)
+	if err != nil {
+		log.GetLogger().Error(err.Error())
+		return "", errors.NewHTTPError("Unable to look up the email", http.StatusInternalServerError)
+	}
+	return emailIndex, nil
+}
+
 func (service *UserService) doesUserExist(ID uuid.UUID) error {
 	exists, err := repository.DoesRecordExistForUser(service.db, ID, user.User{},
 		repository.Filter("`id` = ?", ID))
@@ -344,6 +579,23 @@ func (service *UserService) doesUserExist(ID uuid.UUID) error {
 	return nil
 }
 
+// revokeSessionsOnAccessChange signs the user out everywhere when their admin or active flag
+// changes, so tokens issued under the old rights stop working at once.
+func (service *UserService) revokeSessionsOnAccessChange(uow *repository.UnitOfWork, existingUser, updatedUser *user.User) error {
+	if flagChanged(existingUser.IsAdmin, updatedUser.IsAdmin) || flagChanged(existingUser.IsActive, updatedUser.IsActive) {
+		return service.sessionService.revokeAll(uow, existingUser.ID, session.RevokedUser)
+	}
+	return nil
+}
+
+// flagChanged treats a nil update as leaving the flag untouched.
+func flagChanged(current, updated *bool) bool {
+	if updated == nil {
+		return false
+	}
+	return current == nil || *current != *updated
+}
+
 func hashPassword(password string) ([]byte, error) {
 	return bcrypt.GenerateFromPassword([]byte(password), cost)
 }
@@ -1,10 +1,12 @@
 package web
 
 import (
+	"banking-app-be/components/config"
 	"banking-app-be/components/errors"
 	"encoding/json"
 	"io"
 	"net/http"
+	"strconv"
 )
 
 func UnmarshalJSON(request *http.Request, out interface{}) error {
@@ -28,3 +30,12 @@ func UnmarshalJSON(request *http.Request, out interface{}) error {
 
 	return nil
 }
+
+// MaskAccountNumbers tells whether a list should show account numbers masked, XXXXXXXX1234.
+// The maskAccountNumbers query parameter overrides MASK_ACCOUNT_NUMBERS for one request.
+func MaskAccountNumbers(request *http.Request) bool {
+	if mask, err := strconv.ParseBool(request.URL.Query().Get("maskAccountNumbers")); err == nil {
+		return mask
+	}
+	return config.MaskAccountNumbers.GetBoolValueOrDefault(false)
+}
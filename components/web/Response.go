@@ -11,6 +11,8 @@ func RespondError(w http.ResponseWriter, err error) {
 	switch typedErr := err.(type) {
 	case *errors.UnauthorizedError:
 		RespondJSON(w, typedErr.HTTPStatus, typedErr)
+	case *errors.ForbiddenError:
+		RespondJSON(w, typedErr.HTTPStatus, typedErr)
 	case *errors.ValidationError:
 		RespondJSON(w, typedErr.HTTPStatus, typedErr)
 	case *errors.HTTPError:
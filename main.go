@@ -4,6 +4,8 @@ import (
 	"banking-app-be/app"
 	"banking-app-be/components/config"
 	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
+	"banking-app-be/components/security"
 	"banking-app-be/docs"
 	"banking-app-be/module"
 	"banking-app-be/module/repository"
@@ -12,6 +14,8 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+
+	paymentService "banking-app-be/components/payment/service"
 )
 
 var environment = "local"
@@ -28,6 +32,16 @@ func main() {
 		docs.SwaggerInfo.Host = fmt.Sprintf("localhost:%s", config.PORT.GetStringValue())
 	}
 
+	if err := security.InitializeKeys(); err != nil {
+		log.Fatalf("Loading signing keys failed: %s", err)
+	}
+	if err := pii.InitializeKeys(); err != nil {
+		log.Fatalf("Loading personal data keys failed: %s", err)
+	}
+	if err := paymentService.ValidateRailLimits(); err != nil {
+		log.Fatalf("Invalid payment rail limits: %s", err)
+	}
+
 	db := app.NewDBConnection(log)
 	if db == nil {
 		log.Fatalf("Db connection failed.")
@@ -56,6 +70,7 @@ func main() {
 	app.Log.Print("Server Started")
 
 	module.Configure(app)
+	app.StartJobs()
 
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
@@ -2,6 +2,8 @@ package account
 
 import (
 	"banking-app-be/components/errors"
+	"banking-app-be/components/pii"
+	"banking-app-be/components/redact"
 	"banking-app-be/components/util"
 	model "banking-app-be/model/general"
 	"banking-app-be/model/passbook"
@@ -15,6 +17,7 @@ type Account struct {
 	AccountBalance float32                `json:"balance" gorm:"type:float;DEFAULT:0"`
 	IsActive       *bool                  `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	BankID         uuid.UUID              `json:"bankId" gorm:"not null;type:varchar(36)"`
+	BranchID       *uuid.UUID             `json:"branchId" gorm:"type:varchar(36)"`
 	UserID         uuid.UUID              `json:"userId" gorm:"not null;type:varchar(36)"`
 	PassBook       []passbook.Transaction `json:"passbook" gorm:"foreignKey:AccountID;references:ID"`
 }
@@ -25,6 +28,8 @@ type AccountDTO struct {
 	AccountBalance float32                `json:"balance" gorm:"type:float;DEFAULT:0"`
 	IsActive       *bool                  `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	BankID         uuid.UUID              `json:"bankId"`
+	BranchID       *uuid.UUID             `json:"branchId"`
+	Branch         *AccountBranch         `json:"branch,omitempty" gorm:"foreignKey:BranchID"`
 	UserID         uuid.UUID              `json:"userId"`
 	User           AccountUser            `json:"user" gorm:"foreignKey:UserID"`
 	PassBook       []passbook.Transaction `json:"passBook" gorm:"foreignKey:AccountID;references:ID"`
@@ -37,14 +42,16 @@ type AccontBankDTO struct {
 	IsActive       *bool                  `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	BankID         uuid.UUID              `json:"bankId"`
 	Bank           AccountBank            `json:"bank" gorm:"foreignKey:BankID"`
+	BranchID       *uuid.UUID             `json:"branchId"`
+	Branch         *AccountBranch         `json:"branch,omitempty" gorm:"foreignKey:BranchID"`
 	UserID         uuid.UUID              `json:"userId"`
 	PassBook       []passbook.Transaction `json:"passBook" gorm:"foreignKey:AccountID;references:ID"`
 	// User           AccountUser            `json:"user" gorm:"foreignKey:UserID"`
 }
 type AccountUser struct {
 	model.Base
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
+	FirstName pii.String `json:"firstName"`
+	LastName  pii.String `json:"lastName"`
 }
 
 func (*AccountUser) TableName() string {
@@ -60,6 +67,18 @@ func (*AccountBank) TableName() string {
 	return "banks"
 }
 
+type AccountBranch struct {
+	model.Base
+	Name string  `json:"name"`
+	IFSC string  `json:"ifsc" gorm:"column:ifsc"`
+	BIC  *string `json:"bic,omitempty" gorm:"column:bic"`
+	City string  `json:"city"`
+}
+
+func (*AccountBranch) TableName() string {
+	return "branches"
+}
+
 func (*AccountDTO) TableName() string {
 	return "accounts"
 }
@@ -68,6 +87,14 @@ func (*AccontBankDTO) TableName() string {
 	return "accounts"
 }
 
+// MaskAccountNumbers masks the account's number and the numbers in its passbook for a list.
+func (a *AccontBankDTO) MaskAccountNumbers() {
+	a.AccountNo = redact.AccountNumber(a.AccountNo)
+	for i := range a.PassBook {
+		a.PassBook[i].MaskAccountNumbers()
+	}
+}
+
 func (a *Account) Validate() error {
 	if util.IsEmpty(a.AccountNo) {
 		return errors.NewValidationError("Account number must not be empty")
@@ -37,4 +37,10 @@ func (c *AccountModuleConfig) MigrateTables() {
 		log.NewLog().Print("Foreign Key: Account -> Bank ==> %s", err)
 	}
 
+	// Foreign key: accounts.branch_id → branches.id
+	err = c.DB.Model(model).AddForeignKey("branch_id", "branches(id)", "SET NULL", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Account -> Branch ==> %s", err)
+	}
+
 }
@@ -0,0 +1,186 @@
+package apikey
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"banking-app-be/model/role"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Scopes lists the permissions an API key can carry. Keys only reach routes addressing one of
+// their accounts, so only operations on a single account are offered.
+var Scopes = []string{role.AccountTransact, role.AccountManageOwn, role.PassbookReadOwn}
+
+// ServiceAccount is a machine client, such as a payroll system, acting for the user who owns
+// it. It has no password and signs in with its API keys only.
+type ServiceAccount struct {
+	model.Base
+	OwnerID     uuid.UUID `json:"ownerId" gorm:"not null;type:varchar(36)"`
+	Name        string    `json:"name" example:"Payroll" gorm:"not null;type:varchar(100)"`
+	Description string    `json:"description" example:"Monthly salary transfers" gorm:"type:varchar(255)"`
+	IsActive    *bool     `json:"isActive" gorm:"type:tinyint(1);default:true"`
+	Keys        []APIKey  `json:"keys,omitempty" gorm:"foreignKey:ServiceAccountID"`
+}
+
+func (*ServiceAccount) TableName() string {
+	return "service_accounts"
+}
+
+func (s *ServiceAccount) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" || len(s.Name) > 100 {
+		return errors.NewValidationError("Service account name must be specified and have at most 100 characters")
+	}
+	if len(s.Description) > 255 {
+		return errors.NewValidationError("Service account description must have at most 255 characters")
+	}
+	return nil
+}
+
+// APIKey authenticates a service account. Only a hash of the key is stored; the prefix is kept
+// in clear so a key can be recognised in lists and logs. The signing secret has to be kept as
+// is, since verifying a signed request means computing its signature again. Keys requiring
+// signatures are refused as bearer keys.
+type APIKey struct {
+	model.Base
+	ServiceAccountID uuid.UUID    `json:"serviceAccountId" gorm:"not null;type:varchar(36)"`
+	Name             string       `json:"name" example:"production" gorm:"type:varchar(100)"`
+	Prefix           string       `json:"prefix" example:"bk_3f9a1c7e02d4" gorm:"unique;not null;type:varchar(32)"`
+	KeyHash          string       `json:"-" gorm:"not null;type:varchar(64)"`
+	SigningSecret    string       `json:"-" gorm:"not null;type:varchar(64)"`
+	RequireSignature *bool        `json:"requireSignature" gorm:"type:tinyint(1);default:false"`
+	Scopes           string       `json:"scopes" example:"account:transact passbook:read-own" gorm:"not null;type:varchar(255)"`
+	MaxAmount        float32      `json:"maxAmount" example:"200000" gorm:"type:float;not null"`
+	ExpiresAt        time.Time    `json:"expiresAt" gorm:"not null;type:timestamp"`
+	RevokedAt        *time.Time   `json:"revokedAt"`
+	LastUsedAt       *time.Time   `json:"lastUsedAt"`
+	Accounts         []KeyAccount `json:"accounts" gorm:"foreignKey:APIKeyID"`
+}
+
+func (*APIKey) TableName() string {
+	return "api_keys"
+}
+
+// IsUsable reports whether the key may authenticate at now.
+func (k *APIKey) IsUsable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
+
+// ScopeList returns the permissions the key carries.
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// AccountIDs returns the accounts the key is restricted to.
+func (k *APIKey) AccountIDs() []uuid.UUID {
+	accountIDs := []uuid.UUID{}
+	for _, keyAccount := range k.Accounts {
+		accountIDs = append(accountIDs, keyAccount.AccountID)
+	}
+	return accountIDs
+}
+
+// KeyAccount restricts an API key to an account.
+type KeyAccount struct {
+	model.Base
+	APIKeyID  uuid.UUID `json:"-" gorm:"not null;type:varchar(36)"`
+	AccountID uuid.UUID `json:"accountId" gorm:"not null;type:varchar(36)"`
+}
+
+func (*KeyAccount) TableName() string {
+	return "api_key_accounts"
+}
+
+// Nonce is a nonce spent by a signed request. The pair of key and nonce is unique, so a signed
+// request can not be replayed. Nonces are purged once their requests are too old to be accepted.
+type Nonce struct {
+	model.Base
+	APIKeyID uuid.UUID `json:"-" gorm:"not null;type:varchar(36)"`
+	Nonce    string    `json:"-" gorm:"not null;type:varchar(64)"`
+	UsedAt   time.Time `json:"-" gorm:"not null;type:timestamp"`
+}
+
+func (*Nonce) TableName() string {
+	return "api_key_nonces"
+}
+
+// Usage is one request made with an API key, including the refused ones.
+type Usage struct {
+	model.Base
+	APIKeyID   uuid.UUID `json:"apiKeyId" gorm:"not null;type:varchar(36)"`
+	UsedAt     time.Time `json:"usedAt" gorm:"not null;type:timestamp"`
+	Method     string    `json:"method" example:"POST" gorm:"type:varchar(10)"`
+	Path       string    `json:"path" example:"/api/v1/banking-app/account/cfe25758-f5fe-48f0-874d-e72cd4edd9b9/transfer" gorm:"type:varchar(255)"`
+	IPAddress  string    `json:"ipAddress" gorm:"type:varchar(45)"`
+	StatusCode int       `json:"statusCode" example:"200"`
+}
+
+func (*Usage) TableName() string {
+	return "api_key_usage"
+}
+
+// NewKey is what the owner of a service account asks for when issuing a key. MaxAmount caps
+// every withdrawal and transfer made with the key and defaults to the step-up threshold.
+type NewKey struct {
+	Name          string      `json:"name" example:"production"`
+	Scopes        []string    `json:"scopes" example:"account:transact"`
+	AccountIDs    []uuid.UUID `json:"accountIds"`
+	MaxAmount     float32     `json:"maxAmount" example:"200000"`
+	ExpiresInDays int         `json:"expiresInDays" example:"90"`
+	// RequireSignature limits the key to HMAC-signed requests.
+	RequireSignature bool `json:"requireSignature" example:"true"`
+}
+
+// Validate normalizes the scopes and checks the request is complete.
+func (k *NewKey) Validate() error {
+	k.Name = strings.TrimSpace(k.Name)
+	if len(k.Name) > 100 {
+		return errors.NewValidationError("Key name must have at most 100 characters")
+	}
+	if len(k.Scopes) == 0 {
+		return errors.NewValidationError("At least one scope must be specified, one of " + strings.Join(Scopes, ", "))
+	}
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, scope := range k.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !isScope(scope) {
+			return errors.NewValidationError("Unknown scope " + scope + ", use one of " + strings.Join(Scopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	k.Scopes = scopes
+	if len(k.AccountIDs) == 0 {
+		return errors.NewValidationError("At least one account must be specified")
+	}
+	if k.MaxAmount < 0 {
+		return errors.NewValidationError("Maximum amount can not be negative")
+	}
+	if k.ExpiresInDays < 0 {
+		return errors.NewValidationError("Expiry can not be negative")
+	}
+	return nil
+}
+
+// IssuedKey is handed out once when a key is issued. Neither the key nor its signing secret can
+// be retrieved later.
+type IssuedKey struct {
+	APIKey
+	Key           string `json:"key"`
+	SigningSecret string `json:"signingSecret"`
+}
+
+func isScope(scope string) bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
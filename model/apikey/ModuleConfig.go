@@ -0,0 +1,107 @@
+package apikey
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type APIKeyModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyModuleConfig(db *gorm.DB) *APIKeyModuleConfig {
+	return &APIKeyModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *APIKeyModuleConfig) MigrateTables() {
+
+	serviceAccount := &ServiceAccount{}
+
+	err := c.DB.AutoMigrate(serviceAccount).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating ServiceAccount ==> %s", err)
+	}
+
+	// Foreign key: service_accounts.owner_id → users.id
+	err = c.DB.Model(serviceAccount).AddForeignKey("owner_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: ServiceAccount -> User ==> %s", err)
+	}
+
+	key := &APIKey{}
+
+	err = c.DB.AutoMigrate(key).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating APIKey ==> %s", err)
+	}
+
+	// Foreign key: api_keys.service_account_id → service_accounts.id
+	err = c.DB.Model(key).AddForeignKey("service_account_id", "service_accounts(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: APIKey -> ServiceAccount ==> %s", err)
+	}
+
+	keyAccount := &KeyAccount{}
+
+	err = c.DB.AutoMigrate(keyAccount).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating KeyAccount ==> %s", err)
+	}
+
+	// Foreign key: api_key_accounts.api_key_id → api_keys.id
+	err = c.DB.Model(keyAccount).AddForeignKey("api_key_id", "api_keys(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: KeyAccount -> APIKey ==> %s", err)
+	}
+
+	// Foreign key: api_key_accounts.account_id → accounts.id
+	err = c.DB.Model(keyAccount).AddForeignKey("account_id", "accounts(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: KeyAccount -> Account ==> %s", err)
+	}
+
+	nonce := &Nonce{}
+
+	err = c.DB.AutoMigrate(nonce).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Nonce ==> %s", err)
+	}
+
+	err = c.DB.Model(nonce).AddUniqueIndex("idx_api_key_nonces_key_nonce", "api_key_id", "nonce").Error
+	if err != nil {
+		log.NewLog().Print("Index: Nonce key nonce ==> %s", err)
+	}
+
+	// Spent nonces are purged by age once they can no longer be replayed.
+	err = c.DB.Model(nonce).AddIndex("idx_api_key_nonces_used_at", "used_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Nonce used_at ==> %s", err)
+	}
+
+	// Foreign key: api_key_nonces.api_key_id → api_keys.id
+	err = c.DB.Model(nonce).AddForeignKey("api_key_id", "api_keys(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Nonce -> APIKey ==> %s", err)
+	}
+
+	usage := &Usage{}
+
+	err = c.DB.AutoMigrate(usage).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Usage ==> %s", err)
+	}
+
+	err = c.DB.Model(usage).AddIndex("idx_api_key_usage_key_used_at", "api_key_id", "used_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Usage key used_at ==> %s", err)
+	}
+
+	// Foreign key: api_key_usage.api_key_id → api_keys.id
+	err = c.DB.Model(usage).AddForeignKey("api_key_id", "api_keys(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Usage -> APIKey ==> %s", err)
+	}
+}
@@ -0,0 +1,38 @@
+package approval
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ApprovalModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewApprovalModuleConfig(db *gorm.DB) *ApprovalModuleConfig {
+	return &ApprovalModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *ApprovalModuleConfig) MigrateTables() {
+
+	model := &Request{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Approval Request ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddIndex("idx_approval_requests_status", "status", "proposed_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Approval Request status ==> %s", err)
+	}
+
+	// Foreign key: approval_requests.proposed_by → users.id
+	err = c.DB.Model(model).AddForeignKey("proposed_by", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Approval Request -> User ==> %s", err)
+	}
+}
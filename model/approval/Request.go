@@ -0,0 +1,89 @@
+package approval
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"encoding/json"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Actions that can be held for approval. APPROVAL_REQUIRED_ACTIONS names the ones that are.
+const (
+	ActionBankDelete        = "bank.delete"
+	ActionRoleAssign        = "user.roles.assign"
+	ActionReserveFund       = "reserve.fund"
+	ActionSettlementConfirm = "settlement.confirm"
+)
+
+const (
+	StatusPending   = "PENDING"
+	StatusApproved  = "APPROVED"
+	StatusRejected  = "REJECTED"
+	StatusCancelled = "CANCELLED"
+	StatusExpired   = "EXPIRED"
+	StatusExecuted  = "EXECUTED"
+	StatusFailed    = "FAILED"
+)
+
+// Request is a sensitive action one admin proposed and another must approve before it runs.
+// Who proposed it, who decided and what came of it stay on the request.
+type Request struct {
+	model.Base
+	Action          string          `json:"action" example:"bank.delete" gorm:"not null;type:varchar(50)"`
+	TargetID        uuid.UUID       `json:"targetId" gorm:"not null;type:varchar(36)"`
+	BankID          *uuid.UUID      `json:"bankId,omitempty" gorm:"type:varchar(36)"`
+	Summary         string          `json:"summary" example:"Delete bank State Bank" gorm:"type:varchar(255)"`
+	Payload         json.RawMessage `json:"payload,omitempty" gorm:"type:text"`
+	Status          string          `json:"status" example:"PENDING" gorm:"not null;type:varchar(20)"`
+	ProposedBy      uuid.UUID       `json:"proposedBy" gorm:"not null;type:varchar(36)"`
+	ProposedAt      time.Time       `json:"proposedAt" gorm:"not null;type:timestamp"`
+	ExpiresAt       time.Time       `json:"expiresAt" gorm:"not null;type:timestamp"`
+	DecidedBy       *uuid.UUID      `json:"decidedBy,omitempty" gorm:"type:varchar(36)"`
+	DecidedAt       *time.Time      `json:"decidedAt,omitempty"`
+	DecisionComment string          `json:"decisionComment,omitempty" gorm:"type:varchar(255)"`
+	ExecutedAt      *time.Time      `json:"executedAt,omitempty"`
+	FailureReason   string          `json:"failureReason,omitempty" gorm:"type:varchar(255)"`
+}
+
+func (*Request) TableName() string {
+	return "approval_requests"
+}
+
+// IsOpen reports whether the request can still be decided at now.
+func (r *Request) IsOpen(now time.Time) bool {
+	return r.Status == StatusPending && now.Before(r.ExpiresAt)
+}
+
+// DecodePayload reads the details the action was proposed with into out.
+func (r *Request) DecodePayload(out interface{}) error {
+	if err := json.Unmarshal(r.Payload, out); err != nil {
+		return errors.NewValidationError("Approval request payload is invalid")
+	}
+	return nil
+}
+
+// Decision is what an approver sends with their approval or rejection.
+type Decision struct {
+	Comment string `json:"comment" example:"Checked with the branch manager"`
+}
+
+func (d *Decision) Validate() error {
+	d.Comment = strings.TrimSpace(d.Comment)
+	if len(d.Comment) > 255 {
+		return errors.NewValidationError("Comment must have at most 255 characters")
+	}
+	return nil
+}
+
+// ReserveFunding is the payload of reserve.fund requests.
+type ReserveFunding struct {
+	Amount float32 `json:"amount"`
+}
+
+// RoleAssignment is the payload of user.roles.assign requests.
+type RoleAssignment struct {
+	Roles []string `json:"roles"`
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// GenesisHash is the previous hash of the first link of the chain.
+var GenesisHash = strings.Repeat("0", 64)
+
+// Entry records one change to one entity. Entries are only ever inserted; they do not embed
+// model.Base, as nothing updates or deletes them.
+type Entry struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:varchar(36);primary_key"`
+	OccurredAt time.Time  `json:"occurredAt" gorm:"not null;type:timestamp"`
+	ActorID    *uuid.UUID `json:"actorId,omitempty" gorm:"type:varchar(36)"`
+	// ImpersonatorID is the admin who made the change while acting as the actor.
+	ImpersonatorID *uuid.UUID      `json:"impersonatorId,omitempty" gorm:"type:varchar(36)"`
+	Action         string          `json:"action" example:"update" gorm:"not null;type:varchar(10)"`
+	EntityType     string          `json:"entityType" example:"banks" gorm:"not null;type:varchar(64)"`
+	EntityID       string          `json:"entityId" gorm:"not null;type:varchar(36)"`
+	Changes        json.RawMessage `json:"changes" gorm:"type:text"`
+	RequestID      string          `json:"requestId,omitempty" gorm:"type:varchar(64)"`
+	IPAddress      string          `json:"ipAddress,omitempty" gorm:"type:varchar(45)"`
+	Method         string          `json:"method,omitempty" gorm:"type:varchar(10)"`
+	Path           string          `json:"path,omitempty" gorm:"type:varchar(255)"`
+	Link           *ChainLink      `json:"link,omitempty" gorm:"foreignKey:EntryID"`
+}
+
+func (*Entry) TableName() string {
+	return "audit_entries"
+}
+
+// Change is the value of a column before and after an entry's change.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// ChainLink seals an entry into the hash chain. Each link's hash covers the entry and the hash
+// of the link before it, so altering, removing or reordering sealed entries breaks the chain.
+type ChainLink struct {
+	Sequence     uint64    `json:"sequence" gorm:"primary_key;auto_increment:false"`
+	EntryID      uuid.UUID `json:"-" gorm:"unique;not null;type:varchar(36)"`
+	PreviousHash string    `json:"previousHash" gorm:"not null;type:varchar(64)"`
+	Hash         string    `json:"hash" gorm:"not null;type:varchar(64)"`
+	SealedAt     time.Time `json:"sealedAt" gorm:"not null;type:timestamp"`
+}
+
+func (*ChainLink) TableName() string {
+	return "audit_chain_links"
+}
+
+// HashEntry returns the hash of the link sealing entry after previousHash.
+func HashEntry(previousHash string, entry *Entry) string {
+	actorID := ""
+	if entry.ActorID != nil {
+		actorID = entry.ActorID.String()
+	}
+	// The fields are encoded as a JSON array, so no value can run into the next one.
+	fields := []interface{}{
+		previousHash, entry.ID.String(), entry.OccurredAt.Unix(), actorID, entry.Action, entry.EntityType,
+		entry.EntityID, string(entry.Changes), entry.RequestID, entry.IPAddress, entry.Method, entry.Path,
+	}
+	// Appended only when set, so entries sealed before impersonation was recorded still verify.
+	if entry.ImpersonatorID != nil {
+		fields = append(fields, entry.ImpersonatorID.String())
+	}
+	canonical, _ := json.Marshal(fields)
+	digest := sha256.Sum256(canonical)
+	return hex.EncodeToString(digest[:])
+}
+
+// Verification is the outcome of checking the hash chain.
+type Verification struct {
+	Intact        bool    `json:"intact"`
+	SealedCount   int     `json:"sealedCount"`
+	UnsealedCount int     `json:"unsealedCount"`
+	BrokenAt      *uint64 `json:"brokenAt,omitempty"`
+	Reason        string  `json:"reason,omitempty"`
+}
+
+// Query narrows the entries listed by the audit API. Empty fields match every entry.
+type Query struct {
+	ActorID        uuid.UUID
+	ImpersonatorID uuid.UUID
+	EntityType     string
+	EntityID       string
+	Action         string
+	RequestID      string
+	From           *time.Time
+	To             *time.Time
+}
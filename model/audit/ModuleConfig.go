@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"banking-app-be/components/log"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+type AuditModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewAuditModuleConfig(db *gorm.DB) *AuditModuleConfig {
+	return &AuditModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *AuditModuleConfig) MigrateTables() {
+
+	entry := &Entry{}
+
+	err := c.DB.AutoMigrate(entry).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Audit Entry ==> %s", err)
+	}
+
+	err = c.DB.Model(entry).AddIndex("idx_audit_entries_entity", "entity_type", "entity_id").Error
+	if err != nil {
+		log.NewLog().Print("Index: Audit Entry entity ==> %s", err)
+	}
+
+	err = c.DB.Model(entry).AddIndex("idx_audit_entries_actor", "actor_id", "occurred_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Audit Entry actor ==> %s", err)
+	}
+
+	err = c.DB.Model(entry).AddIndex("idx_audit_entries_impersonator", "impersonator_id", "occurred_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Audit Entry impersonator ==> %s", err)
+	}
+
+	err = c.DB.Model(entry).AddIndex("idx_audit_entries_occurred_at", "occurred_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Audit Entry occurred_at ==> %s", err)
+	}
+
+	link := &ChainLink{}
+
+	err = c.DB.AutoMigrate(link).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Audit Chain Link ==> %s", err)
+	}
+
+	// Foreign key: audit_chain_links.entry_id → audit_entries.id
+	err = c.DB.Model(link).AddForeignKey("entry_id", "audit_entries(id)", "RESTRICT", "RESTRICT").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Audit Chain Link -> Audit Entry ==> %s", err)
+	}
+
+	// The application only inserts audit records; the database refuses anything else.
+	for _, table := range []string{"audit_entries", "audit_chain_links"} {
+		for _, event := range []string{"UPDATE", "DELETE"} {
+			err = c.DB.Exec(fmt.Sprintf("CREATE TRIGGER `%s_no_%s` BEFORE %s ON `%s` FOR EACH ROW "+
+				"SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'Audit records can not be changed'", table, strings.ToLower(event), event, table)).Error
+			if err != nil {
+				log.NewLog().Print("Trigger: %s %s ==> %s", table, event, err)
+			}
+		}
+	}
+}
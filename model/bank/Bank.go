@@ -5,7 +5,9 @@ import (
 	"banking-app-be/components/util"
 	"banking-app-be/model/account"
 	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/branch"
 	model "banking-app-be/model/general"
+	"banking-app-be/model/reserve"
 	"strings"
 )
 
@@ -16,6 +18,8 @@ type Bank struct {
 	IsActive         *bool                             `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	Accounts         []account.Account                 `json:"accounts"`
 	BankTransactions []banktransaction.BankTransaction `json:"bankTransactions"`
+	Reserve          *reserve.ReserveAccount           `json:"reserve"`
+	Branches         []branch.Branch                   `json:"branches"`
 }
 
 type BankDTO struct {
@@ -25,6 +29,8 @@ type BankDTO struct {
 	IsActive         *bool                             `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	Accounts         []account.AccountDTO              `json:"accounts," gorm:"foreignKey:BankID"`
 	BankTransactions []banktransaction.BankTransaction `json:"bankTransactions" gorm:"foreignKey:SenderBankID"`
+	Reserve          *reserve.ReserveAccount           `json:"reserve,omitempty" gorm:"foreignKey:BankID"`
+	Branches         []branch.Branch                   `json:"branches,omitempty" gorm:"foreignKey:BankID"`
 }
 
 func (*BankDTO) TableName() string {
@@ -2,15 +2,19 @@ package banktransaction
 
 import (
 	model "banking-app-be/model/general"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 )
 
 type BankTransaction struct {
 	model.Base
-	SenderBankID   uuid.UUID `json:"senderBankId" gorm:"not null;type:varchar(36)"`
-	ReceiverBankID uuid.UUID `json:"receiverBankId" gorm:"not null;type:varchar(36)"`
-	Amount         float32   `json:"amount" gorm:"type:float"`
+	SenderBankID   uuid.UUID  `json:"senderBankId" gorm:"not null;type:varchar(36)"`
+	ReceiverBankID uuid.UUID  `json:"receiverBankId" gorm:"not null;type:varchar(36)"`
+	Amount         float32    `json:"amount" gorm:"type:float"`
+	IsSettled      *bool      `json:"isSettled" gorm:"type:tinyint(1);default:false"`
+	SettledAt      *time.Time `json:"settledAt"`
+	SettledBy      uuid.UUID  `json:"-" gorm:"type:varchar(36)"`
 }
 
 type BankTransactionDTO struct {
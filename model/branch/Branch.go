@@ -0,0 +1,69 @@
+package branch
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/util"
+	model "banking-app-be/model/general"
+	"regexp"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	// IFSC: four letter bank code, a literal zero and a six character branch code, e.g. SBIN0001234.
+	ifscPattern = regexp.MustCompile(`^[A-Z]{4}0[A-Z0-9]{6}$`)
+	// BIC: bank, country and location codes with an optional three character branch code.
+	bicPattern = regexp.MustCompile(`^[A-Z]{4}[A-Z]{2}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+)
+
+type Branch struct {
+	model.Base
+	BankID     uuid.UUID `json:"bankId" gorm:"not null;type:varchar(36)"`
+	Name       string    `json:"name" example:"Fort Mumbai" gorm:"type:varchar(100);not null"`
+	IFSC       string    `json:"ifsc" example:"SBIN0000300" gorm:"column:ifsc;unique;not null;type:varchar(11)"`
+	BIC        *string   `json:"bic,omitempty" example:"SBININBB104" gorm:"column:bic;type:varchar(11)"`
+	Address    string    `json:"address" example:"Mumbai Main Branch, Fort" gorm:"type:varchar(255);not null"`
+	City       string    `json:"city" example:"Mumbai" gorm:"type:varchar(100);not null"`
+	State      string    `json:"state" example:"Maharashtra" gorm:"type:varchar(100)"`
+	PostalCode string    `json:"postalCode" example:"400001" gorm:"type:varchar(10)"`
+	IsActive   *bool     `json:"isActive" gorm:"type:tinyint(1);default:true"`
+}
+
+// Normalize upper-cases the routing codes so lookups are case-insensitive.
+func (b *Branch) Normalize() {
+	b.IFSC = strings.ToUpper(strings.TrimSpace(b.IFSC))
+	if b.BIC != nil {
+		bic := strings.ToUpper(strings.TrimSpace(*b.BIC))
+		if bic == "" {
+			b.BIC = nil
+		} else {
+			b.BIC = &bic
+		}
+	}
+}
+
+func (b *Branch) Validate() error {
+	b.Normalize()
+	if util.IsEmpty(b.Name) {
+		return errors.NewValidationError("Branch name must be specified")
+	}
+	if util.IsEmpty(b.Address) || util.IsEmpty(b.City) {
+		return errors.NewValidationError("Branch address and city must be specified")
+	}
+	if !IsValidIFSC(b.IFSC) {
+		return errors.NewValidationError("IFSC must be 11 characters: 4 letters, a zero and 6 letters or digits")
+	}
+	if b.BIC != nil && !IsValidBIC(*b.BIC) {
+		return errors.NewValidationError("BIC must be 8 or 11 characters: 6 letters followed by letters or digits")
+	}
+	return nil
+}
+
+func IsValidIFSC(code string) bool {
+	return ifscPattern.MatchString(code)
+}
+
+func IsValidBIC(code string) bool {
+	return bicPattern.MatchString(code)
+}
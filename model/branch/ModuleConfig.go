@@ -0,0 +1,38 @@
+package branch
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type BranchModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewBranchModuleConfig(db *gorm.DB) *BranchModuleConfig {
+	return &BranchModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *BranchModuleConfig) MigrateTables() {
+
+	model := &Branch{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Branch ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddIndex("idx_branch_bic", "bic").Error
+	if err != nil {
+		log.NewLog().Print("Index: Branch BIC ==> %s", err)
+	}
+
+	// Foreign key: branches.bank_id → banks.id
+	err = c.DB.Model(model).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Branch -> Bank ==> %s", err)
+	}
+}
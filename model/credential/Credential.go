@@ -2,37 +2,70 @@ package credential
 
 import (
 	"banking-app-be/components/errors"
+	"banking-app-be/components/passwordpolicy"
+	"banking-app-be/components/pii"
 	"banking-app-be/components/util"
 	model "banking-app-be/model/general"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
 )
 
 type Credential struct {
 	model.Base
-	Email    string    `json:"email" gorm:"unique;not null;type:varchar(36)"`
-	Password string    `json:"password" gorm:"not null;type:varchar(255)"`
-	UserID   uuid.UUID `json:"userId" gorm:"not null;type:varchar(36)"`
+	Email    pii.String `json:"email" gorm:"not null;type:varchar(512)"`
+	Password string     `json:"password" audit:"-" gorm:"not null;type:varchar(255)"`
+	UserID   uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	// EmailIndex is the blind index of the email, which lookups by email go through.
+	EmailIndex string `json:"-" gorm:"type:varchar(64)"`
+	// PasswordChangedAt is empty until the password is first replaced.
+	PasswordChangedAt *time.Time `json:"-"`
 }
 
 type CredentialDTO struct {
 	model.Base
-	Email    string    `json:"email" gorm:"unique;not null;type:varchar(36)"`
-	Password string    `json:"-" gorm:"not null;type:varchar(255)"`
-	UserID   uuid.UUID `json:"-" gorm:"not null;type:varchar(36)"`
+	Email    pii.String `json:"email" gorm:"not null;type:varchar(512)"`
+	Password string     `json:"-" gorm:"not null;type:varchar(255)"`
+	UserID   uuid.UUID  `json:"-" gorm:"not null;type:varchar(36)"`
 }
 
 func (*CredentialDTO) TableName() string {
 	return "credentials"
 }
 
-func (user *Credential) Validate() error {
+// BeforeSave keeps the blind index in step with the email. Updates through a map set the
+// email on the model first, so they are covered as well.
+func (user *Credential) BeforeSave(scope *gorm.Scope) error {
+	if user.Email == "" {
+		return nil
+	}
+	emailIndex, err := pii.BlindIndex(string(user.Email))
+	if err != nil {
+		return err
+	}
+	return scope.SetColumn("EmailIndex", emailIndex)
+}
+
+// Validate checks the email and holds the password to the password policy. personalInfo adds
+// the names of the user, which the password may not contain.
+func (user *Credential) Validate(personalInfo ...string) error {
 
-	if util.IsEmpty(user.Email) || !util.ValidateEmail(user.Email) {
+	if util.IsEmpty(string(user.Email)) || !util.ValidateEmail(string(user.Email)) {
 		return errors.NewValidationError("User Email must be specified and should be of the type abc@domain.com")
 	}
-	if util.IsEmpty(user.Password) || len(user.Password) < 8 {
-		return errors.NewValidationError("Password should consist of 8 or more characters")
+	return ValidatePassword(user.Password, append([]string{string(user.Email)}, personalInfo...)...)
+}
+
+// ValidatePassword checks a password a user is about to set against the password policy.
+func ValidatePassword(password string, personalInfo ...string) error {
+	return passwordpolicy.Current().Check(password, personalInfo...)
+}
+
+// PasswordSetAt returns when the password was last set.
+func (user *Credential) PasswordSetAt() time.Time {
+	if user.PasswordChangedAt != nil {
+		return *user.PasswordChangedAt
 	}
-	return nil
+	return user.CreatedAt
 }
@@ -2,6 +2,7 @@ package credential
 
 import (
 	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
 
 	"github.com/jinzhu/gorm"
 )
@@ -25,9 +26,59 @@ func (c *CredentialModuleConfig) MigrateTables() {
 		log.NewLog().Print("Auto Migrating Credential ==> %s", err)
 	}
 
+	// Emails are stored encrypted, so they are no longer unique themselves; their blind index is.
+	err = c.DB.Model(model).ModifyColumn("email", "varchar(512) NOT NULL").Error
+	if err != nil {
+		log.NewLog().Print("Widening credentials email ==> %s", err)
+	}
+
+	// Emails written before encryption are encrypted once the column can hold it.
+	_, err = pii.Reencrypt(c.DB, pii.Column{Table: "credentials", Name: "email"}, pii.DefaultBatchSize)
+	if err != nil {
+		log.NewLog().Print("Encrypting credentials email ==> %s", err)
+	}
+
+	err = c.DB.Model(model).RemoveIndex("email").Error
+	if err != nil {
+		log.NewLog().Print("Removing Index credentials email ==> %s", err)
+	}
+
+	err = c.indexEmails()
+	if err != nil {
+		log.NewLog().Print("Indexing credentials emails ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddUniqueIndex("idx_credentials_email_index", "email_index").Error
+	if err != nil {
+		log.NewLog().Print("Unique Index: credentials email_index ==> %s", err)
+	}
+
 	err = c.DB.Model(model).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
 	if err != nil {
 		log.NewLog().Print("Foreign Key Constraints Of credentials ==> %s", err)
 	}
 
 }
+
+// indexEmails fills in the blind index of the credentials created before it existed.
+func (c *CredentialModuleConfig) indexEmails() error {
+
+	credentials := []Credential{}
+	err := c.DB.Unscoped().Where("email_index IS NULL OR email_index = ''").Find(&credentials).Error
+	if err != nil {
+		return err
+	}
+
+	for _, credential := range credentials {
+		emailIndex, err := pii.BlindIndex(string(credential.Email))
+		if err != nil {
+			return err
+		}
+		err = c.DB.Unscoped().Model(&Credential{}).Where("id = ?", credential.ID).
+			UpdateColumn("email_index", emailIndex).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package exposure
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	BreachActionReject = "REJECT"
+	BreachActionQueue  = "QUEUE"
+
+	AlertLevelWarning = "WARNING"
+	AlertLevelBreach  = "BREACH"
+)
+
+// ExposureLimit caps how much BankID may owe CounterpartyBankID in unsettled transfers.
+type ExposureLimit struct {
+	model.Base
+	BankID             uuid.UUID `json:"bankId" gorm:"not null;type:varchar(36)"`
+	CounterpartyBankID uuid.UUID `json:"counterpartyBankId" gorm:"not null;type:varchar(36)"`
+	DebitCap           float32   `json:"debitCap" gorm:"type:float;not null"`
+	AlertThreshold     float32   `json:"alertThreshold" example:"80" gorm:"type:float;DEFAULT:80"`
+	BreachAction       string    `json:"breachAction" example:"REJECT/QUEUE" gorm:"type:varchar(10);not null"`
+	IsActive           *bool     `json:"isActive" gorm:"type:tinyint(1);default:true"`
+}
+
+// LiquidityAlert is raised when a bank pair's exposure reaches a level. It stays open, and no
+// other alert of its level is raised for the pair, until the exposure falls back below it.
+type LiquidityAlert struct {
+	model.Base
+	TimeStamp          time.Time  `json:"timeStamp" gorm:"not null;type:timestamp"`
+	BankID             uuid.UUID  `json:"bankId" gorm:"not null;type:varchar(36)"`
+	CounterpartyBankID uuid.UUID  `json:"counterpartyBankId" gorm:"not null;type:varchar(36)"`
+	Level              string     `json:"level" example:"WARNING/BREACH" gorm:"type:varchar(10);not null"`
+	NetExposure        float32    `json:"netExposure" gorm:"type:float"`
+	DebitCap           float32    `json:"debitCap" gorm:"type:float"`
+	Message            string     `json:"message" gorm:"type:varchar(255)"`
+	ResolvedAt         *time.Time `json:"resolvedAt"`
+}
+
+// BankPosition is the live unsettled position of a bank against one counterparty.
+type BankPosition struct {
+	CounterpartyBankID   uuid.UUID `json:"counterpartyBankId"`
+	CounterpartyBankName string    `json:"counterpartyBankName"`
+	GrossOutgoing        float32   `json:"grossOutgoing"`
+	GrossIncoming        float32   `json:"grossIncoming"`
+	NetPosition          float32   `json:"netPosition"`
+	DebitCap             *float32  `json:"debitCap,omitempty"`
+}
+
+func (limit *ExposureLimit) Validate() error {
+	if limit.BankID == uuid.Nil || limit.CounterpartyBankID == uuid.Nil {
+		return errors.NewValidationError("Bank and counterparty bank must be specified")
+	}
+	if limit.BankID == limit.CounterpartyBankID {
+		return errors.NewValidationError("Bank and counterparty bank must be different")
+	}
+	if limit.DebitCap <= 0 {
+		return errors.NewValidationError("Debit cap must be positive")
+	}
+	if limit.AlertThreshold == 0 {
+		limit.AlertThreshold = 80
+	}
+	if limit.AlertThreshold < 0 || limit.AlertThreshold > 100 {
+		return errors.NewValidationError("Alert threshold must be a percentage between 0 and 100")
+	}
+	if limit.BreachAction == "" {
+		limit.BreachAction = BreachActionReject
+	}
+	if limit.BreachAction != BreachActionReject && limit.BreachAction != BreachActionQueue {
+		return errors.NewValidationError("Breach action must be REJECT or QUEUE")
+	}
+	return nil
+}
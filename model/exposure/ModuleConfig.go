@@ -0,0 +1,48 @@
+package exposure
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ExposureModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewExposureModuleConfig(db *gorm.DB) *ExposureModuleConfig {
+	return &ExposureModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *ExposureModuleConfig) MigrateTables() {
+
+	limitModel := &ExposureLimit{}
+	alertModel := &LiquidityAlert{}
+
+	err := c.DB.AutoMigrate(limitModel, alertModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Exposure ==> %s", err)
+	}
+
+	err = c.DB.Model(limitModel).AddUniqueIndex("idx_exposure_limit_pair", "bank_id", "counterparty_bank_id").Error
+	if err != nil {
+		log.NewLog().Print("Unique Index: ExposureLimit pair ==> %s", err)
+	}
+
+	err = c.DB.Model(alertModel).AddIndex("idx_liquidity_alert_pair_level", "bank_id", "counterparty_bank_id", "level").Error
+	if err != nil {
+		log.NewLog().Print("Index: LiquidityAlert pair ==> %s", err)
+	}
+
+	// Foreign keys: exposure_limits.bank_id / counterparty_bank_id → banks.id
+	err = c.DB.Model(limitModel).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: ExposureLimit -> Bank ==> %s", err)
+	}
+	err = c.DB.Model(limitModel).AddForeignKey("counterparty_bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: ExposureLimit -> CounterpartyBank ==> %s", err)
+	}
+}
@@ -0,0 +1,39 @@
+package holiday
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/util"
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// DateLayout is the format holiday dates are exchanged and stored in.
+const DateLayout = "2006-01-02"
+
+// Holiday closes a day for one bank, or for every bank when BankID is nil.
+type Holiday struct {
+	model.Base
+	BankID *uuid.UUID `json:"bankId" gorm:"type:varchar(36)"`
+	Date   string     `json:"date" example:"2026-01-26" gorm:"column:holiday_date;type:varchar(10);not null"`
+	Name   string     `json:"name" example:"Republic Day" gorm:"type:varchar(100);not null"`
+}
+
+// BusinessDay answers a business-day query for a bank or the system calendar.
+type BusinessDay struct {
+	BankID          *uuid.UUID `json:"bankId,omitempty"`
+	Date            string     `json:"date"`
+	IsBusinessDay   bool       `json:"isBusinessDay"`
+	NextBusinessDay string     `json:"nextBusinessDay"`
+}
+
+func (h *Holiday) Validate() error {
+	if util.IsEmpty(h.Name) {
+		return errors.NewValidationError("Holiday name must be specified")
+	}
+	if _, err := time.Parse(DateLayout, h.Date); err != nil {
+		return errors.NewValidationError("Holiday date must be of the format YYYY-MM-DD")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package holiday
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type HolidayModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewHolidayModuleConfig(db *gorm.DB) *HolidayModuleConfig {
+	return &HolidayModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *HolidayModuleConfig) MigrateTables() {
+
+	model := &Holiday{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Holiday ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddIndex("idx_holiday_date_bank", "holiday_date", "bank_id").Error
+	if err != nil {
+		log.NewLog().Print("Index: Holiday date ==> %s", err)
+	}
+
+	// Foreign key: holidays.bank_id → banks.id
+	err = c.DB.Model(model).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Holiday -> Bank ==> %s", err)
+	}
+}
@@ -0,0 +1,53 @@
+package invitation
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/util"
+	model "banking-app-be/model/general"
+	"banking-app-be/model/role"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Invitation lets a super-admin bring in another administrator. The invitee accepts through a
+// link carrying a one-time token, of which only the hash is stored.
+type Invitation struct {
+	model.Base
+	Email          string     `json:"email" gorm:"not null;type:varchar(36)"`
+	Role           string     `json:"role" example:"BANK_ADMIN" gorm:"not null;type:varchar(20)"`
+	TokenHash      string     `json:"-" gorm:"unique;not null;type:varchar(64)"`
+	ExpiresAt      time.Time  `json:"expiresAt" gorm:"not null;type:timestamp"`
+	AcceptedAt     *time.Time `json:"acceptedAt"`
+	AcceptedUserID *uuid.UUID `json:"acceptedUserId,omitempty" gorm:"type:varchar(36)"`
+	RevokedAt      *time.Time `json:"revokedAt"`
+}
+
+// IssuedInvitation is returned once, when the invitation is created; the link can not be
+// recovered afterwards.
+type IssuedInvitation struct {
+	Invitation
+	Token string `json:"token"`
+	Link  string `json:"link"`
+}
+
+func (i *Invitation) Normalize() {
+	i.Email = strings.ToLower(strings.TrimSpace(i.Email))
+	i.Role = strings.ToUpper(strings.TrimSpace(i.Role))
+}
+
+func (i *Invitation) Validate() error {
+	if util.IsEmpty(i.Email) || !util.ValidateEmail(i.Email) {
+		return errors.NewValidationError("Invitation email must be specified and should be of the type abc@domain.com")
+	}
+	if !role.IsStaff(i.Role) {
+		return errors.NewValidationError("Invitations can only grant a staff role")
+	}
+	return nil
+}
+
+// IsPending reports whether the invitation can still be accepted at now.
+func (i *Invitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.RevokedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,32 @@
+package invitation
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type InvitationModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewInvitationModuleConfig(db *gorm.DB) *InvitationModuleConfig {
+	return &InvitationModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *InvitationModuleConfig) MigrateTables() {
+
+	model := &Invitation{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Invitation ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddIndex("idx_invitations_email", "email").Error
+	if err != nil {
+		log.NewLog().Print("Index: Invitation email ==> %s", err)
+	}
+}
@@ -0,0 +1,94 @@
+package login
+
+import (
+	"banking-app-be/components/pii"
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Outcomes recorded in the login history.
+const (
+	OutcomeSuccess     = "SUCCESS"
+	OutcomeFailed      = "FAILED"
+	OutcomeBlocked     = "BLOCKED"
+	OutcomeMFARequired = "MFA_REQUIRED"
+	OutcomeMFAFailed   = "MFA_FAILED"
+	OutcomeExpired     = "PASSWORD_EXPIRED"
+	OutcomeUnlocked    = "UNLOCKED"
+)
+
+// Attempt is one entry of the login history. UserID is empty for attempts on emails that
+// belong to nobody.
+type Attempt struct {
+	model.Base
+	UserID      *uuid.UUID `json:"-" gorm:"type:varchar(36)"`
+	Email       pii.String `json:"-" gorm:"type:varchar(512)"`
+	AttemptedAt time.Time  `json:"attemptedAt" gorm:"not null;type:timestamp"`
+	IPAddress   string     `json:"ipAddress" gorm:"type:varchar(45)"`
+	UserAgent   string     `json:"userAgent" gorm:"type:varchar(255)"`
+	Outcome     string     `json:"outcome" gorm:"not null;type:varchar(20)"`
+}
+
+func (*Attempt) TableName() string {
+	return "login_history"
+}
+
+// Throttle counts recent failed logins of one subject, an email or a client IP. After a few
+// failures every further attempt has to wait longer, and too many lock the subject for a while.
+// Password reset requests are counted the same way on subjects of their own.
+type Throttle struct {
+	model.Base
+	Subject        string     `json:"subject" gorm:"unique;not null;type:varchar(255)"`
+	FailedAttempts int        `json:"failedAttempts" gorm:"not null;default:0"`
+	LastFailedAt   *time.Time `json:"lastFailedAt"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt"`
+	LockedUntil    *time.Time `json:"lockedUntil"`
+}
+
+func (*Throttle) TableName() string {
+	return "login_throttles"
+}
+
+// BlockedUntil returns when the subject may be tried again, or nil when it may be tried at now.
+func (t *Throttle) BlockedUntil(now time.Time) *time.Time {
+	var until *time.Time
+	for _, candidate := range []*time.Time{t.LockedUntil, t.NextAttemptAt} {
+		if candidate != nil && now.Before(*candidate) && (until == nil || candidate.After(*until)) {
+			until = candidate
+		}
+	}
+	return until
+}
+
+// AccountSubject is the throttle subject of an email. Emails are throttled whether or not an
+// account has them, so throttling does not reveal which ones exist. The subject holds the
+// email's blind index rather than the email itself.
+func AccountSubject(email string) (string, error) {
+	emailIndex, err := pii.BlindIndex(email)
+	if err != nil {
+		return "", err
+	}
+	return "account:" + emailIndex, nil
+}
+
+// IPSubject is the throttle subject of a client address.
+func IPSubject(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+// ResetSubject is the throttle subject of password reset requests for an email, kept apart
+// from AccountSubject so that requesting resets does not lock the account's logins.
+func ResetSubject(email string) (string, error) {
+	emailIndex, err := pii.BlindIndex(email)
+	if err != nil {
+		return "", err
+	}
+	return "reset:" + emailIndex, nil
+}
+
+// ResetIPSubject is the throttle subject of password reset requests from a client address.
+func ResetIPSubject(ipAddress string) string {
+	return "reset-ip:" + ipAddress
+}
@@ -0,0 +1,47 @@
+package login
+
+import (
+	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
+
+	"github.com/jinzhu/gorm"
+)
+
+type LoginModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewLoginModuleConfig(db *gorm.DB) *LoginModuleConfig {
+	return &LoginModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *LoginModuleConfig) MigrateTables() {
+
+	attempt := &Attempt{}
+	throttle := &Throttle{}
+
+	err := c.DB.AutoMigrate(attempt, throttle).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Login ==> %s", err)
+	}
+
+	// Encrypted emails are longer than the plain ones the column was sized for.
+	err = c.DB.Model(attempt).ModifyColumn("email", "varchar(512)").Error
+	if err != nil {
+		log.NewLog().Print("Widening login_history email ==> %s", err)
+	}
+
+	// Attempts recorded before encryption are encrypted as well.
+	_, err = pii.Reencrypt(c.DB, pii.Column{Table: "login_history", Name: "email"}, pii.DefaultBatchSize)
+	if err != nil {
+		log.NewLog().Print("Encrypting login_history email ==> %s", err)
+	}
+
+	// Attempts on unknown emails have no user, so the history has no foreign key to users.
+	err = c.DB.Model(attempt).AddIndex("idx_login_history_user_attempted_at", "user_id", "attempted_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Login History user_id, attempted_at ==> %s", err)
+	}
+}
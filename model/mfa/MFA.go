@@ -0,0 +1,86 @@
+package mfa
+
+import (
+	"banking-app-be/components/pii"
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Factor is the TOTP authenticator of a user. It only protects logins once the user has
+// confirmed it with a first code. The secret is encrypted like personal data, as anyone
+// reading it can produce the user's codes.
+type Factor struct {
+	model.Base
+	UserID          uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	Secret          pii.String `json:"-" gorm:"not null;type:varchar(512)"`
+	ConfirmedAt     *time.Time `json:"confirmedAt"`
+	LastUsedCounter int64      `json:"-"`
+}
+
+func (*Factor) TableName() string {
+	return "mfa_factors"
+}
+
+// IsEnabled reports whether logins of the user need a second factor.
+func (f *Factor) IsEnabled() bool {
+	return f.ConfirmedAt != nil
+}
+
+// BackupCode is a single-use code standing in for the authenticator. Only its hash is stored.
+type BackupCode struct {
+	model.Base
+	UserID   uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	CodeHash string     `json:"-" gorm:"not null;type:varchar(64)"`
+	UsedAt   *time.Time `json:"usedAt"`
+}
+
+func (*BackupCode) TableName() string {
+	return "mfa_backup_codes"
+}
+
+// Challenge is the half-finished login of a user who passed the password check and still owes
+// a second factor. Users that must enroll before logging in do so through the challenge.
+type Challenge struct {
+	model.Base
+	UserID             uuid.UUID  `json:"-" gorm:"not null;type:varchar(36)"`
+	TokenHash          string     `json:"-" gorm:"unique;not null;type:varchar(64)"`
+	ExpiresAt          time.Time  `json:"expiresAt" gorm:"not null;type:timestamp"`
+	Attempts           int        `json:"-" gorm:"not null;default:0"`
+	UsedAt             *time.Time `json:"-"`
+	EnrollmentRequired bool       `json:"enrollmentRequired" gorm:"-"`
+	Token              string     `json:"mfaToken" gorm:"-"`
+}
+
+func (*Challenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// IsOpen reports whether the challenge can still be answered at now.
+func (c *Challenge) IsOpen(now time.Time, maxAttempts int) bool {
+	return c.UsedAt == nil && c.Attempts < maxAttempts && now.Before(c.ExpiresAt)
+}
+
+// Policy holds the deployment-wide MFA rules. There is a single row.
+type Policy struct {
+	model.Base
+	RequireForAdmins *bool `json:"requireForAdmins" gorm:"type:tinyint(1);default:false"`
+}
+
+func (*Policy) TableName() string {
+	return "mfa_policies"
+}
+
+// Enrollment is handed to the user when they start enrolling an authenticator.
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// Status tells a user how their account is protected.
+type Status struct {
+	Enabled              bool `json:"enabled"`
+	Required             bool `json:"required"`
+	RemainingBackupCodes int  `json:"remainingBackupCodes"`
+}
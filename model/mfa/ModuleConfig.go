@@ -0,0 +1,61 @@
+package mfa
+
+import (
+	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
+
+	"github.com/jinzhu/gorm"
+)
+
+type MFAModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewMFAModuleConfig(db *gorm.DB) *MFAModuleConfig {
+	return &MFAModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *MFAModuleConfig) MigrateTables() {
+
+	factor := &Factor{}
+	backupCode := &BackupCode{}
+	challenge := &Challenge{}
+	policy := &Policy{}
+
+	err := c.DB.AutoMigrate(factor, backupCode, challenge, policy).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating MFA ==> %s", err)
+	}
+
+	// Encrypted secrets are longer than the plain ones the column was sized for.
+	err = c.DB.Model(factor).ModifyColumn("secret", "varchar(512) NOT NULL").Error
+	if err != nil {
+		log.NewLog().Print("Widening mfa_factors secret ==> %s", err)
+	}
+
+	// Secrets stored before encryption are encrypted as well.
+	_, err = pii.Reencrypt(c.DB, pii.Column{Table: "mfa_factors", Name: "secret"}, pii.DefaultBatchSize)
+	if err != nil {
+		log.NewLog().Print("Encrypting mfa_factors secret ==> %s", err)
+	}
+
+	// Foreign key: mfa_factors.user_id → users.id
+	err = c.DB.Model(factor).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: MFA Factor -> User ==> %s", err)
+	}
+
+	// Foreign key: mfa_backup_codes.user_id → users.id
+	err = c.DB.Model(backupCode).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: MFA BackupCode -> User ==> %s", err)
+	}
+
+	// Foreign key: mfa_challenges.user_id → users.id
+	err = c.DB.Model(challenge).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: MFA Challenge -> User ==> %s", err)
+	}
+}
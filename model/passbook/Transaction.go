@@ -1,6 +1,7 @@
 package passbook
 
 import (
+	"banking-app-be/components/redact"
 	"time"
 
 	uuid "github.com/satori/go.uuid"
@@ -14,3 +15,8 @@ type Transaction struct {
 	Note           string    `json:"note" gorm:"type:varchar(100)"`
 	AccountID      uuid.UUID `json:"accountId" gorm:"not null;type:varchar(36)"`
 }
+
+// MaskAccountNumbers masks the account numbers the note names.
+func (t *Transaction) MaskAccountNumbers() {
+	t.Note = redact.Numbers(t.Note)
+}
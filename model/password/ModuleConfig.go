@@ -0,0 +1,40 @@
+package password
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PasswordModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewPasswordModuleConfig(db *gorm.DB) *PasswordModuleConfig {
+	return &PasswordModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *PasswordModuleConfig) MigrateTables() {
+
+	history := &History{}
+	resetToken := &ResetToken{}
+
+	err := c.DB.AutoMigrate(history, resetToken).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Password ==> %s", err)
+	}
+
+	// Foreign key: password_history.user_id → users.id
+	err = c.DB.Model(history).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Password History -> User ==> %s", err)
+	}
+
+	// Foreign key: password_reset_tokens.user_id → users.id
+	err = c.DB.Model(resetToken).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Password Reset Token -> User ==> %s", err)
+	}
+}
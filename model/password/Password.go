@@ -0,0 +1,38 @@
+package password
+
+import (
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// History keeps the hashes of passwords a user has set, so recent ones can not be reused.
+type History struct {
+	model.Base
+	UserID       uuid.UUID `json:"-" gorm:"not null;type:varchar(36)"`
+	PasswordHash string    `json:"-" gorm:"not null;type:varchar(255)"`
+}
+
+func (*History) TableName() string {
+	return "password_history"
+}
+
+// ResetToken lets a user who forgot their password set a new one. It is sent to them through
+// the notifier, stored only as a hash and works once before it expires.
+type ResetToken struct {
+	model.Base
+	UserID    uuid.UUID  `json:"-" gorm:"not null;type:varchar(36)"`
+	TokenHash string     `json:"-" gorm:"unique;not null;type:varchar(64)"`
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"not null;type:timestamp"`
+	UsedAt    *time.Time `json:"-"`
+}
+
+func (*ResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// IsUsable reports whether the token can still reset the password at now.
+func (t *ResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PaymentModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewPaymentModuleConfig(db *gorm.DB) *PaymentModuleConfig {
+	return &PaymentModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *PaymentModuleConfig) MigrateTables() {
+
+	paymentModel := &Payment{}
+	eventModel := &PaymentEvent{}
+
+	err := c.DB.AutoMigrate(paymentModel, eventModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Payment ==> %s", err)
+	}
+
+	err = c.DB.Model(paymentModel).AddIndex("idx_payment_status_scheduled_for", "status", "scheduled_for").Error
+	if err != nil {
+		log.NewLog().Print("Index: Payment status ==> %s", err)
+	}
+
+	err = c.DB.Model(paymentModel).AddIndex("idx_payment_status_next_attempt_at", "status", "next_attempt_at").Error
+	if err != nil {
+		log.NewLog().Print("Index: Payment next attempt ==> %s", err)
+	}
+
+	// Foreign key: payments.from_account_id → accounts.id
+	err = c.DB.Model(paymentModel).AddForeignKey("from_account_id", "accounts(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Payment -> Account ==> %s", err)
+	}
+
+	// Foreign key: payment_events.payment_id → payments.id
+	err = c.DB.Model(eventModel).AddForeignKey("payment_id", "payments(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: PaymentEvent -> Payment ==> %s", err)
+	}
+}
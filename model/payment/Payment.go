@@ -0,0 +1,108 @@
+package payment
+
+import (
+	"banking-app-be/components/errors"
+	"banking-app-be/components/redact"
+	"banking-app-be/components/util"
+	"banking-app-be/model/branch"
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	RailInstant   = "INSTANT"
+	RailBatch     = "BATCH"
+	RailHighValue = "HIGH_VALUE"
+
+	StatusInitiated    = "INITIATED"
+	StatusPendingBatch = "PENDING_BATCH"
+	StatusScheduled    = "SCHEDULED"
+	StatusProcessing   = "PROCESSING"
+	StatusQueued       = "QUEUED"
+	StatusCompleted    = "COMPLETED"
+	StatusFailed       = "FAILED"
+)
+
+// transitions lists, per rail, the statuses a payment may move to from each status.
+// QUEUED is reached when an inter-bank exposure limit holds the payment back.
+var transitions = map[string]map[string][]string{
+	RailInstant: {
+		StatusInitiated:  {StatusProcessing, StatusFailed},
+		StatusProcessing: {StatusCompleted, StatusQueued, StatusFailed},
+		StatusQueued:     {StatusProcessing, StatusFailed},
+	},
+	RailBatch: {
+		StatusInitiated:    {StatusPendingBatch, StatusFailed},
+		StatusPendingBatch: {StatusProcessing, StatusFailed},
+		StatusProcessing:   {StatusCompleted, StatusQueued, StatusFailed},
+		StatusQueued:       {StatusProcessing, StatusFailed},
+	},
+	RailHighValue: {
+		StatusInitiated:  {StatusProcessing, StatusScheduled, StatusFailed},
+		StatusScheduled:  {StatusProcessing, StatusFailed},
+		StatusProcessing: {StatusCompleted, StatusQueued, StatusFailed},
+		StatusQueued:     {StatusProcessing, StatusFailed},
+	},
+}
+
+// Payment is a transfer on a rail. Attempts counts how often an exposure limit held it back
+// and NextAttemptAt is when the scheduler retries it while QUEUED.
+type Payment struct {
+	model.Base
+	FromAccountID uuid.UUID      `json:"fromAccountId" gorm:"not null;type:varchar(36)"`
+	ToAccountNo   string         `json:"toAccountNo" gorm:"not null;type:varchar(20)"`
+	ToBranchCode  string         `json:"toBranchCode,omitempty" example:"SBIN0000300" gorm:"type:varchar(11)"`
+	UserID        uuid.UUID      `json:"userId" gorm:"not null;type:varchar(36)"`
+	Amount        float32        `json:"amount" gorm:"type:float"`
+	Rail          string         `json:"rail" example:"INSTANT/BATCH/HIGH_VALUE" gorm:"type:varchar(15);not null"`
+	Status        string         `json:"status" gorm:"type:varchar(15);not null"`
+	ScheduledFor  *time.Time     `json:"scheduledFor"`
+	ProcessedAt   *time.Time     `json:"processedAt"`
+	FailureReason string         `json:"failureReason" gorm:"type:varchar(255)"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt *time.Time     `json:"nextAttemptAt"`
+	Events        []PaymentEvent `json:"events,omitempty" gorm:"foreignKey:PaymentID"`
+}
+
+// PaymentEvent records a single status transition of a payment.
+type PaymentEvent struct {
+	model.Base
+	PaymentID  uuid.UUID `json:"paymentId" gorm:"not null;type:varchar(36)"`
+	TimeStamp  time.Time `json:"timeStamp" gorm:"not null;type:timestamp"`
+	FromStatus string    `json:"fromStatus" gorm:"type:varchar(15)"`
+	ToStatus   string    `json:"toStatus" gorm:"type:varchar(15);not null"`
+	Note       string    `json:"note" gorm:"type:varchar(255)"`
+}
+
+func (p *Payment) Validate() error {
+	if util.IsEmpty(p.ToAccountNo) {
+		return errors.NewValidationError("Receiver account number must be specified")
+	}
+	if p.ToBranchCode != "" && !branch.IsValidIFSC(p.ToBranchCode) && !branch.IsValidBIC(p.ToBranchCode) {
+		return errors.NewValidationError("Receiver branch code must be a valid IFSC or BIC")
+	}
+	if p.Amount <= 0 {
+		return errors.NewValidationError("Transfer amount must be positive")
+	}
+	if p.Rail != "" && p.Rail != RailInstant && p.Rail != RailBatch && p.Rail != RailHighValue {
+		return errors.NewValidationError("Rail must be one of INSTANT, BATCH or HIGH_VALUE")
+	}
+	return nil
+}
+
+// MaskAccountNumbers masks the receiver's account number for a list.
+func (p *Payment) MaskAccountNumbers() {
+	p.ToAccountNo = redact.AccountNumber(p.ToAccountNo)
+}
+
+// CanTransition reports whether the payment's rail allows moving from its current status to status.
+func (p *Payment) CanTransition(status string) bool {
+	for _, allowed := range transitions[p.Rail][p.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
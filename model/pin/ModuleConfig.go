@@ -0,0 +1,33 @@
+package pin
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type PINModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewPINModuleConfig(db *gorm.DB) *PINModuleConfig {
+	return &PINModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *PINModuleConfig) MigrateTables() {
+
+	transactionPIN := &TransactionPIN{}
+
+	err := c.DB.AutoMigrate(transactionPIN).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Transaction PIN ==> %s", err)
+	}
+
+	// Foreign key: transaction_pins.user_id → users.id
+	err = c.DB.Model(transactionPIN).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Transaction PIN -> User ==> %s", err)
+	}
+}
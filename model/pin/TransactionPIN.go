@@ -0,0 +1,54 @@
+package pin
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"time"
+	"unicode"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	MinLength = 4
+	MaxLength = 6
+)
+
+// TransactionPIN confirms high-value operations of a user. Only its bcrypt hash is stored, and
+// repeated wrong entries lock it for a while.
+type TransactionPIN struct {
+	model.Base
+	UserID         uuid.UUID  `json:"userId" gorm:"unique;not null;type:varchar(36)"`
+	PINHash        string     `json:"-" gorm:"not null;type:varchar(255)"`
+	FailedAttempts int        `json:"-" gorm:"not null;default:0"`
+	LockedUntil    *time.Time `json:"lockedUntil"`
+}
+
+func (*TransactionPIN) TableName() string {
+	return "transaction_pins"
+}
+
+// IsLocked reports whether the PIN is refused at now because of earlier wrong entries.
+func (p *TransactionPIN) IsLocked(now time.Time) bool {
+	return p.LockedUntil != nil && now.Before(*p.LockedUntil)
+}
+
+// Status tells a user whether they have a PIN and from which amount it is asked for.
+type Status struct {
+	IsSet           bool       `json:"isSet"`
+	LockedUntil     *time.Time `json:"lockedUntil,omitempty"`
+	ThresholdAmount float32    `json:"thresholdAmount"`
+}
+
+// Validate checks that value is a PIN of MinLength to MaxLength digits.
+func Validate(value string) error {
+	if len(value) < MinLength || len(value) > MaxLength {
+		return errors.NewValidationError("Transaction PIN should consist of 4 to 6 digits")
+	}
+	for _, r := range value {
+		if !unicode.IsDigit(r) {
+			return errors.NewValidationError("Transaction PIN should consist of 4 to 6 digits")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package reserve
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ReserveModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewReserveModuleConfig(db *gorm.DB) *ReserveModuleConfig {
+	return &ReserveModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *ReserveModuleConfig) MigrateTables() {
+
+	accountModel := &ReserveAccount{}
+	entryModel := &ReserveEntry{}
+
+	err := c.DB.AutoMigrate(accountModel, entryModel).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Reserve ==> %s", err)
+	}
+
+	// Foreign key: reserve_accounts.bank_id → banks.id
+	err = c.DB.Model(accountModel).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: ReserveAccount -> Bank ==> %s", err)
+	}
+
+	// Foreign key: reserve_entries.reserve_account_id → reserve_accounts.id
+	err = c.DB.Model(entryModel).AddForeignKey("reserve_account_id", "reserve_accounts(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: ReserveEntry -> ReserveAccount ==> %s", err)
+	}
+}
@@ -0,0 +1,38 @@
+package reserve
+
+import (
+	model "banking-app-be/model/general"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	EntryFunding          = "Funding"
+	EntryTransferOut      = "TransferOut"
+	EntryTransferIn       = "TransferIn"
+	EntrySettlementDebit  = "SettlementDebit"
+	EntrySettlementCredit = "SettlementCredit"
+)
+
+// ReserveAccount is the nostro account a bank holds in the system. Balance holds settled
+// funds while PendingBalance is the net of inter-bank transfers not yet settled.
+type ReserveAccount struct {
+	model.Base
+	BankID         uuid.UUID      `json:"bankId" gorm:"unique;not null;type:varchar(36)"`
+	Balance        float32        `json:"balance" gorm:"type:float;DEFAULT:0"`
+	PendingBalance float32        `json:"pendingBalance" gorm:"type:float;DEFAULT:0"`
+	Entries        []ReserveEntry `json:"entries,omitempty" gorm:"foreignKey:ReserveAccountID"`
+}
+
+type ReserveEntry struct {
+	model.Base
+	ReserveAccountID  uuid.UUID `json:"reserveAccountId" gorm:"not null;type:varchar(36)"`
+	TimeStamp         time.Time `json:"timeStamp" gorm:"not null;type:timestamp"`
+	Type              string    `json:"type" gorm:"not null;type:varchar(36)" example:"TransferOut"`
+	Amount            float32   `json:"amount" gorm:"type:float"`
+	Balance           float32   `json:"balance" gorm:"type:float"`
+	PendingBalance    float32   `json:"pendingBalance" gorm:"type:float"`
+	BankTransactionID uuid.UUID `json:"bankTransactionId,omitempty" gorm:"type:varchar(36)"`
+	Note              string    `json:"note" gorm:"type:varchar(100)"`
+}
@@ -0,0 +1,57 @@
+package role
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type RoleModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewRoleModuleConfig(db *gorm.DB) *RoleModuleConfig {
+	return &RoleModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *RoleModuleConfig) MigrateTables() {
+
+	model := &UserRole{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating UserRole ==> %s", err)
+	}
+
+	err = c.DB.Model(model).AddIndex("idx_user_role_user", "user_id").Error
+	if err != nil {
+		log.NewLog().Print("Index: UserRole user ==> %s", err)
+	}
+
+	// Foreign key: user_roles.user_id → users.id
+	err = c.DB.Model(model).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: UserRole -> User ==> %s", err)
+	}
+
+	userBank := &UserBank{}
+
+	err = c.DB.AutoMigrate(userBank).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating UserBank ==> %s", err)
+	}
+
+	// Foreign key: user_banks.user_id → users.id
+	err = c.DB.Model(userBank).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: UserBank -> User ==> %s", err)
+	}
+
+	// Foreign key: user_banks.bank_id → banks.id
+	err = c.DB.Model(userBank).AddForeignKey("bank_id", "banks(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: UserBank -> Bank ==> %s", err)
+	}
+}
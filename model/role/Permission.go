@@ -0,0 +1,104 @@
+package role
+
+// Permissions are named "<resource>:<action>". Routes declare the permissions they need and
+// roles grant them; nothing checks a role name directly.
+const (
+	UserCreate      = "user:create"
+	UserRead        = "user:read"
+	UserUpdate      = "user:update"
+	UserDelete      = "user:delete"
+	UserRoleAssign  = "user:role:assign"
+	UserUnlock      = "user:unlock"
+	UserImpersonate = "user:impersonate"
+	AdminCreate     = "admin:create"
+
+	BankCreate        = "bank:create"
+	BankUpdate        = "bank:update"
+	BankDelete        = "bank:delete"
+	BranchManage      = "branch:manage"
+	CalendarManage    = "calendar:manage"
+	CalendarRead      = "calendar:read"
+	ReserveRead       = "reserve:read"
+	ReserveFund       = "reserve:fund"
+	ExposureRead      = "exposure:read"
+	ExposureManage    = "exposure:manage"
+	SettlementRead    = "settlement:read"
+	SettlementConfirm = "settlement:confirm"
+
+	PaymentReadAll  = "payment:read-all"
+	PaymentProcess  = "payment:process"
+	AccountReadAll  = "account:read-all"
+	PassbookReadAll = "passbook:read-all"
+
+	AccountManageOwn = "account:manage-own"
+	AccountTransact  = "account:transact"
+	PassbookReadOwn  = "passbook:read-own"
+	PaymentReadOwn   = "payment:read-own"
+
+	SessionManageOwn  = "session:manage-own"
+	ProfileReadOwn    = "profile:read-own"
+	MFAManageOwn      = "mfa:manage-own"
+	PasswordChangeOwn = "password:change-own"
+
+	ServiceAccountManageOwn = "service-account:manage-own"
+
+	ApprovalRead   = "approval:read"
+	ApprovalDecide = "approval:decide"
+
+	AuditRead = "audit:read"
+
+	MFAPolicyManage = "mfa:policy:manage"
+)
+
+var allPermissions = []string{
+	UserCreate, UserRead, UserUpdate, UserDelete, UserRoleAssign, UserUnlock, UserImpersonate, AdminCreate,
+	BankCreate, BankUpdate, BankDelete, BranchManage, CalendarManage, CalendarRead,
+	ReserveRead, ReserveFund, ExposureRead, ExposureManage, SettlementRead, SettlementConfirm,
+	PaymentReadAll, PaymentProcess, AccountReadAll, PassbookReadAll,
+	SessionManageOwn, ProfileReadOwn, MFAManageOwn, PasswordChangeOwn, MFAPolicyManage,
+	ServiceAccountManageOwn, ApprovalRead, ApprovalDecide, AuditRead,
+}
+
+var rolePermissions = map[string][]string{
+	SuperAdmin: allPermissions,
+	BankAdmin: {
+		UserCreate, UserRead, UserUpdate, UserUnlock,
+		BankUpdate, BranchManage, CalendarManage, CalendarRead,
+		ReserveRead, ReserveFund, ExposureRead, ExposureManage, SettlementRead,
+		PaymentReadAll, PaymentProcess, AccountReadAll, PassbookReadAll, ApprovalRead, ApprovalDecide,
+		SessionManageOwn, ProfileReadOwn, MFAManageOwn, PasswordChangeOwn,
+	},
+	Teller: {
+		UserCreate, UserRead, UserUnlock, CalendarRead, PaymentReadAll, AccountReadAll, PassbookReadAll,
+		SessionManageOwn, ProfileReadOwn, MFAManageOwn, PasswordChangeOwn,
+	},
+	Auditor: {
+		UserRead, CalendarRead, ReserveRead, ExposureRead, SettlementRead, PaymentReadAll,
+		AccountReadAll, PassbookReadAll, ApprovalRead, AuditRead,
+		SessionManageOwn, ProfileReadOwn, MFAManageOwn, PasswordChangeOwn,
+	},
+	Customer: {
+		AccountManageOwn, AccountTransact, PassbookReadOwn, PaymentReadOwn,
+		SessionManageOwn, ProfileReadOwn, MFAManageOwn, PasswordChangeOwn, ServiceAccountManageOwn,
+	},
+}
+
+// WithheldWhileImpersonating are never granted to an admin acting as a user, so impersonation
+// can not be used to take over the user's credentials or to move and manage their money. They
+// are withheld even from sessions that are not read-only, rather than relying on every
+// money-moving route to check ReadOnly. Admins read the user's accounts with their own
+// AccountReadAll, outside of impersonation.
+var WithheldWhileImpersonating = []string{
+	PasswordChangeOwn, MFAManageOwn, ServiceAccountManageOwn, AccountTransact, AccountManageOwn,
+}
+
+// PermissionsOf returns the union of the permissions granted by roles.
+func PermissionsOf(roles ...string) map[string]bool {
+	granted := map[string]bool{}
+	for _, name := range roles {
+		for _, permission := range rolePermissions[name] {
+			granted[permission] = true
+		}
+	}
+	return granted
+}
@@ -0,0 +1,76 @@
+package role
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	SuperAdmin = "SUPER_ADMIN"
+	BankAdmin  = "BANK_ADMIN"
+	Teller     = "TELLER"
+	Auditor    = "AUDITOR"
+	Customer   = "CUSTOMER"
+)
+
+// UserRole grants a role to a user.
+type UserRole struct {
+	model.Base
+	UserID uuid.UUID `json:"userId" gorm:"not null;type:varchar(36)"`
+	Role   string    `json:"role" example:"TELLER" gorm:"type:varchar(20);not null"`
+}
+
+// RoleInfo describes a role and the permissions it carries.
+type RoleInfo struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+func IsKnown(name string) bool {
+	_, exists := rolePermissions[name]
+	return exists
+}
+
+// IsStaff reports whether the role is one of the bank's own staff rather than a customer.
+func IsStaff(name string) bool {
+	return IsKnown(name) && name != Customer
+}
+
+// ValidateAssignment normalizes the roles and checks they can be held together.
+// Customers own accounts, so the customer role is never combined with a staff role.
+func ValidateAssignment(roles []string) ([]string, error) {
+
+	if len(roles) == 0 {
+		return nil, errors.NewValidationError("At least one role must be specified")
+	}
+
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, name := range roles {
+		name = strings.ToUpper(strings.TrimSpace(strings.ReplaceAll(name, "-", "_")))
+		if !IsKnown(name) {
+			return nil, errors.NewValidationError("Unknown role " + name)
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	if seen[Customer] && len(normalized) > 1 {
+		return nil, errors.NewValidationError("The customer role can not be combined with staff roles")
+	}
+	return normalized, nil
+}
+
+// Catalog lists every role with its permissions.
+func Catalog() []RoleInfo {
+	catalog := []RoleInfo{}
+	for _, name := range []string{SuperAdmin, BankAdmin, Teller, Auditor, Customer} {
+		catalog = append(catalog, RoleInfo{Role: name, Permissions: rolePermissions[name]})
+	}
+	return catalog
+}
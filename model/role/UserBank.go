@@ -0,0 +1,27 @@
+package role
+
+import (
+	model "banking-app-be/model/general"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// UserBank assigns a bank to a staff member. Staff other than super-admins only manage the
+// banks assigned to them.
+type UserBank struct {
+	model.Base
+	UserID uuid.UUID `json:"userId" gorm:"not null;type:varchar(36)"`
+	BankID uuid.UUID `json:"bankId" gorm:"not null;type:varchar(36)"`
+}
+
+// IsBankScoped reports whether holders of roles are limited to their assigned banks.
+func IsBankScoped(roles []string) bool {
+	scoped := false
+	for _, name := range roles {
+		if name == SuperAdmin {
+			return false
+		}
+		scoped = scoped || IsStaff(name)
+	}
+	return scoped
+}
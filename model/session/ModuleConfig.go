@@ -0,0 +1,33 @@
+package session
+
+import (
+	"banking-app-be/components/log"
+
+	"github.com/jinzhu/gorm"
+)
+
+type SessionModuleConfig struct {
+	DB *gorm.DB
+}
+
+func NewSessionModuleConfig(db *gorm.DB) *SessionModuleConfig {
+	return &SessionModuleConfig{
+		DB: db,
+	}
+}
+
+func (c *SessionModuleConfig) MigrateTables() {
+
+	model := &Session{}
+
+	err := c.DB.AutoMigrate(model).Error
+	if err != nil {
+		log.NewLog().Print("Auto Migrating Session ==> %s", err)
+	}
+
+	// Foreign key: sessions.user_id → users.id
+	err = c.DB.Model(model).AddForeignKey("user_id", "users(id)", "CASCADE", "CASCADE").Error
+	if err != nil {
+		log.NewLog().Print("Foreign Key: Session -> User ==> %s", err)
+	}
+}
@@ -0,0 +1,81 @@
+package session
+
+import (
+	"banking-app-be/components/errors"
+	model "banking-app-be/model/general"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Session is one login of a user. Access tokens carry the session ID, so revoking the session
+// revokes them; the refresh token is stored only as a hash and rotates on every use.
+type Session struct {
+	model.Base
+	UserID           uuid.UUID  `json:"userId" gorm:"not null;type:varchar(36)"`
+	RefreshTokenHash string     `json:"-" gorm:"unique;not null;type:varchar(64)"`
+	ExpiresAt        time.Time  `json:"expiresAt" gorm:"not null;type:timestamp"`
+	LastUsedAt       *time.Time `json:"lastUsedAt"`
+	RevokedAt        *time.Time `json:"revokedAt"`
+	RevokedReason    string     `json:"revokedReason,omitempty" gorm:"type:varchar(100)"`
+	ReplacedByID     *uuid.UUID `json:"replacedById,omitempty" gorm:"type:varchar(36)"`
+	UserAgent        string     `json:"userAgent" gorm:"type:varchar(255)"`
+	IPAddress        string     `json:"ipAddress" gorm:"type:varchar(45)"`
+	// ImpersonatorID is set on sessions an admin opened to act as the user.
+	ImpersonatorID      *uuid.UUID `json:"impersonatorId,omitempty" gorm:"type:varchar(36)"`
+	ReadOnly            bool       `json:"readOnly,omitempty" gorm:"type:tinyint(1);not null;default:false"`
+	ImpersonationReason string     `json:"impersonationReason,omitempty" gorm:"type:varchar(255)"`
+}
+
+// TokenPair is handed to the client on login and on every refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int64  `json:"expiresIn"`
+}
+
+const (
+	RevokedLogout    = "LOGOUT"
+	RevokedLogoutAll = "LOGOUT_ALL"
+	RevokedRotated   = "ROTATED"
+	RevokedReuse     = "REFRESH_TOKEN_REUSE"
+	RevokedUser      = "USER_CHANGED"
+	RevokedPassword  = "PASSWORD_CHANGED"
+	RevokedEnded     = "IMPERSONATION_ENDED"
+)
+
+func (s *Session) IsUsable(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// ImpersonationRequest is what an admin sends to act as a user. Impersonation is read-only
+// unless asked otherwise.
+type ImpersonationRequest struct {
+	Minutes  int64  `json:"minutes" example:"15"`
+	ReadOnly *bool  `json:"readOnly" example:"true"`
+	Reason   string `json:"reason" example:"Ticket 4521: customer can not see their transfer"`
+}
+
+func (r *ImpersonationRequest) Validate(maxMinutes int64) error {
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.Reason == "" || len(r.Reason) > 255 {
+		return errors.NewValidationError("A reason of at most 255 characters must be given for impersonating a user")
+	}
+	if r.Minutes < 0 || r.Minutes > maxMinutes {
+		return errors.NewValidationError(fmt.Sprintf("Impersonation can last at most %d minutes", maxMinutes))
+	}
+	return nil
+}
+
+// Impersonation is handed to the admin. Its access token can not be refreshed; a new
+// impersonation has to be started once it expires.
+type Impersonation struct {
+	SessionID   uuid.UUID `json:"sessionId"`
+	UserID      uuid.UUID `json:"userId"`
+	AccessToken string    `json:"token"`
+	ReadOnly    bool      `json:"readOnly"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	ExpiresIn   int64     `json:"expiresIn"`
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"banking-app-be/components/pii"
+	"banking-app-be/model/account"
+	"banking-app-be/model/audit"
+	"banking-app-be/model/login"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// Export is everything held about a user, handed to them on request. Secrets such as password
+// and PIN hashes are left out; whether they are set is told instead.
+type Export struct {
+	ExportedAt   time.Time         `json:"exportedAt"`
+	Profile      ExportProfile     `json:"profile"`
+	Credential   ExportCredential  `json:"credential"`
+	Roles        []string          `json:"roles"`
+	Accounts     []account.Account `json:"accounts"`
+	LoginHistory []login.Attempt   `json:"loginHistory"`
+	AuditEntries []audit.Entry     `json:"auditEntries"`
+	Actions      []ExportAction    `json:"actions"`
+}
+
+type ExportProfile struct {
+	ID           uuid.UUID  `json:"id"`
+	FirstName    pii.String `json:"firstName"`
+	LastName     pii.String `json:"lastName"`
+	PhoneNo      pii.String `json:"phoneNo"`
+	IsAdmin      *bool      `json:"isAdmin"`
+	IsActive     *bool      `json:"isActive"`
+	TotalBalance float32    `json:"totalBalance"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+type ExportCredential struct {
+	Email             pii.String `json:"email"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	PasswordChangedAt *time.Time `json:"passwordChangedAt"`
+	MFAEnabled        bool       `json:"mfaEnabled"`
+	TransactionPINSet bool       `json:"transactionPinSet"`
+}
+
+// ExportAction is a change the user made to a record that is not theirs, possibly while
+// impersonating someone. The record and its values belong to others, so only when, what kind of
+// change and from where are told.
+type ExportAction struct {
+	OccurredAt    time.Time `json:"occurredAt"`
+	Action        string    `json:"action"`
+	EntityType    string    `json:"entityType"`
+	Impersonating bool      `json:"impersonating"`
+	RequestID     string    `json:"requestId,omitempty"`
+	IPAddress     string    `json:"ipAddress,omitempty"`
+}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"banking-app-be/components/log"
+	"banking-app-be/components/pii"
 
 	"github.com/jinzhu/gorm"
 )
@@ -25,4 +26,26 @@ func (u *UserModuleConfig) MigrateTables() {
 		log.NewLog().Print("Auto Migrating User ==> %s", err)
 	}
 
+	// Names and phone numbers are stored encrypted, which the old column sizes can not hold.
+	// The phone number index is dropped as encrypted values can not be searched anyway.
+	for _, column := range []string{"first_name", "last_name", "phone_no"} {
+		err = u.DB.Model(model).ModifyColumn(column, "varchar(512)").Error
+		if err != nil {
+			log.NewLog().Print("Widening users %s ==> %s", column, err)
+		}
+	}
+
+	// Rows written before encryption are encrypted once their columns can hold it.
+	for _, column := range []string{"first_name", "last_name", "phone_no"} {
+		_, err = pii.Reencrypt(u.DB, pii.Column{Table: "users", Name: column}, pii.DefaultBatchSize)
+		if err != nil {
+			log.NewLog().Print("Encrypting users %s ==> %s", column, err)
+		}
+	}
+
+	err = u.DB.Model(model).RemoveIndex("idx_users_phone_no").Error
+	if err != nil {
+		log.NewLog().Print("Removing Index users phone_no ==> %s", err)
+	}
+
 }
@@ -2,33 +2,39 @@ package user
 
 import (
 	"banking-app-be/components/errors"
+	"banking-app-be/components/pii"
 	"banking-app-be/components/util"
 	"banking-app-be/model/account"
 	"banking-app-be/model/credential"
 	model "banking-app-be/model/general"
+	"banking-app-be/model/role"
+	"time"
 )
 
 type User struct {
 	model.Base
-	FirstName    string                 `json:"firstName" example:"Ravi" gorm:"type:varchar(500)"`
-	LastName     string                 `json:"lastName" example:"Sharma" gorm:"type:varchar(50)"`
-	PhoneNo      string                 `sql:"index" json:"phoneNo" example:"9700795509" gorm:"type:varchar(15)"`
+	FirstName    pii.String             `json:"firstName" example:"Ravi" gorm:"type:varchar(512)"`
+	LastName     pii.String             `json:"lastName" example:"Sharma" gorm:"type:varchar(512)"`
+	PhoneNo      pii.String             `json:"phoneNo" example:"9700795509" gorm:"type:varchar(512)"`
 	IsAdmin      *bool                  `json:"isAdmin" gorm:"type:tinyint(1);default:false"`
 	IsActive     *bool                  `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	TotalBalance float32                `json:"totalBalance" gorm:"type:float;DEFAULT:0"`
 	Credentials  *credential.Credential `json:"credential"`
+	// ErasedAt is set once the user's personal data has been erased.
+	ErasedAt *time.Time `json:"-"`
 }
 
 type UserDTO struct {
 	model.Base
-	FirstName    string                    `json:"firstName" example:"Ravi" gorm:"type:varchar(50)"`
-	LastName     string                    `json:"lastName" example:"Sharma" gorm:"type:varchar(50)"`
-	PhoneNo      string                    `sql:"index" json:"phoneNo" example:"9700795509" gorm:"type:varchar(15)"`
+	FirstName    pii.String                `json:"firstName" example:"Ravi" gorm:"type:varchar(512)"`
+	LastName     pii.String                `json:"lastName" example:"Sharma" gorm:"type:varchar(512)"`
+	PhoneNo      pii.String                `json:"phoneNo" example:"9700795509" gorm:"type:varchar(512)"`
 	IsAdmin      *bool                     `json:"isAdmin" gorm:"type:tinyint(1);default:false"`
 	IsActive     *bool                     `json:"isActive" gorm:"type:tinyint(1);default:true"`
 	TotalBalance float32                   `json:"totalBalance" gorm:"type:float;DEFAULT:0"`
 	Credentials  *credential.CredentialDTO `json:"credential" gorm:"foreignKey:UserId;"`
 	Accounts     []account.AccontBankDTO   `json:"accounts" gorm:"foreignKey:UserId;"`
+	Roles        []role.UserRole           `json:"roles" gorm:"foreignKey:UserID"`
 	// Accounts     []account.AccountDTO   `json:"accounts" gorm:"foreignKey:UserId;references:ID"`
 }
 
@@ -36,15 +42,22 @@ func (*UserDTO) TableName() string {
 	return "users"
 }
 
+// MaskAccountNumbers masks the numbers of the user's accounts for a list.
+func (user *UserDTO) MaskAccountNumbers() {
+	for i := range user.Accounts {
+		user.Accounts[i].MaskAccountNumbers()
+	}
+}
+
 func (user *User) Validate() error {
 
-	if util.IsEmpty(user.FirstName) || !util.ValidateString(user.FirstName) {
+	if util.IsEmpty(string(user.FirstName)) || !util.ValidateString(string(user.FirstName)) {
 		return errors.NewValidationError("User FirstName must be specified and must have characters only")
 	}
-	if util.IsEmpty(user.LastName) || !util.ValidateString(user.LastName) {
+	if util.IsEmpty(string(user.LastName)) || !util.ValidateString(string(user.LastName)) {
 		return errors.NewValidationError("User LastName must be specified and must have characters only")
 	}
-	if util.IsEmpty(user.PhoneNo) || !util.ValidateContact(user.PhoneNo) {
+	if util.IsEmpty(string(user.PhoneNo)) || !util.ValidateContact(string(user.PhoneNo)) {
 		return errors.NewValidationError("User Contact must be specified and have 10 digits")
 	}
 	return nil
@@ -4,17 +4,38 @@ import (
 	"banking-app-be/app"
 	"banking-app-be/components/account/controller"
 	accountService "banking-app-be/components/account/service"
+	bankService "banking-app-be/components/bank/service"
+	exposureService "banking-app-be/components/exposure/service"
+	paymentController "banking-app-be/components/payment/controller"
+	paymentService "banking-app-be/components/payment/service"
+	"banking-app-be/components/security"
 	"banking-app-be/module/repository"
 )
 
 func registerAccountRoutes(appObj *app.App, repository repository.Repository) {
 
 	defer appObj.WG.Done()
-	acountService := accountService.NewAccountService(appObj.DB, repository)
+	reserveService := bankService.NewReserveService(appObj.DB, repository)
+	exposureService := exposureService.NewExposureService(appObj.DB, repository)
+	acountService := accountService.NewAccountService(appObj.DB, repository, reserveService, exposureService)
 
-	accountController := controller.NewAccountController(acountService, appObj.Log)
+	// Account and passbook routes check who holds the addressed account through the account service.
+	security.RegisterAccountOwnerResolver(acountService)
+
+	calendarService := bankService.NewCalendarService(appObj.DB, repository)
+
+	// Transfers are initiated from accounts but executed on payment rails, so both share one PaymentService.
+	payments := paymentService.NewPaymentService(appObj.DB, repository, acountService, calendarService)
+
+	accountController := controller.NewAccountController(acountService, payments, appObj.Log)
+	paymentController := paymentController.NewPaymentController(payments, appObj.Log)
 
 	appObj.RegisterControllerRoutes([]app.Controller{
 		accountController,
+		paymentController,
+	})
+
+	appObj.RegisterJobs([]app.Job{
+		paymentService.NewPaymentScheduler(payments, appObj.Log),
 	})
 }
@@ -0,0 +1,18 @@
+package module
+
+import (
+	"banking-app-be/app"
+
+	"banking-app-be/components/approval/controller"
+	approvalService "banking-app-be/components/approval/service"
+)
+
+func registerApprovalRoutes(appObj *app.App, approvals *approvalService.ApprovalService) {
+
+	defer appObj.WG.Done()
+	approvalController := controller.NewApprovalController(approvals, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		approvalController,
+	})
+}
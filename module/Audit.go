@@ -0,0 +1,25 @@
+package module
+
+import (
+	"banking-app-be/app"
+	"banking-app-be/components/audit"
+	"banking-app-be/module/repository"
+
+	"banking-app-be/components/audit/controller"
+	auditService "banking-app-be/components/audit/service"
+)
+
+func registerAuditRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	audits := auditService.NewAuditService(appObj.DB, repository)
+
+	// Changes are attributed to the request, its caller and address while it is served.
+	appObj.RegisterMiddlewares(audit.MiddlewareRequest)
+	appObj.RegisterControllerRoutes([]app.Controller{
+		controller.NewAuditController(audits, appObj.Log),
+	})
+	appObj.RegisterJobs([]app.Job{
+		auditService.NewAuditSealer(audits, appObj.Log),
+	})
+}
@@ -2,20 +2,55 @@ package module
 
 import (
 	"banking-app-be/app"
+	"banking-app-be/components/security"
+	"banking-app-be/model/approval"
+	"banking-app-be/model/bank"
+	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/role"
 	"banking-app-be/module/repository"
+	"context"
 
+	approvalService "banking-app-be/components/approval/service"
 	"banking-app-be/components/bank/controller"
 	bankService "banking-app-be/components/bank/service"
 )
 
-func registerBankRoutes(appObj *app.App, repository repository.Repository) {
+func registerBankRoutes(appObj *app.App, repository repository.Repository, approvals *approvalService.ApprovalService) {
 
 	defer appObj.WG.Done()
-	bankService := bankService.NewBankService(appObj.DB, repository)
+	reserveService := bankService.NewReserveService(appObj.DB, repository)
+	calendarService := bankService.NewCalendarService(appObj.DB, repository)
+	branchService := bankService.NewBranchService(appObj.DB, repository)
+	bankService := bankService.NewBankService(appObj.DB, repository, reserveService, calendarService)
 
-	bankController := controller.NewBankController(bankService, appObj.Log)
+	// Approved requests run as their proposer; the approver is recorded on the request.
+	approvals.RegisterAction(approval.ActionBankDelete, role.BankDelete, func(ctx context.Context, request *approval.Request) error {
+		bankToDelete := bank.Bank{}
+		bankToDelete.ID = request.TargetID
+		bankToDelete.DeletedBy = request.ProposedBy
+		return bankService.DeleteBank(ctx, &bankToDelete)
+	})
+	approvals.RegisterAction(approval.ActionReserveFund, role.ReserveFund, func(ctx context.Context, request *approval.Request) error {
+		funding := approval.ReserveFunding{}
+		if err := request.DecodePayload(&funding); err != nil {
+			return err
+		}
+		return reserveService.Fund(ctx, request.TargetID, funding.Amount, request.ProposedBy)
+	})
+	approvals.RegisterAction(approval.ActionSettlementConfirm, role.SettlementConfirm, func(ctx context.Context, request *approval.Request) error {
+		ledger := []banktransaction.BankTransactionDTO{}
+		var totalCount int
+		return bankService.ConfirmSettlement(ctx, request.ProposedBy, security.Unrestricted, &ledger, &totalCount)
+	})
+
+	calendarController := controller.NewCalendarController(calendarService, appObj.Log)
+	branchController := controller.NewBranchController(branchService, appObj.Log)
+	bankController := controller.NewBankController(bankService, reserveService, approvals, appObj.Log)
 
+	// Calendar and branch literal paths must be matched before the bank's /{id} routes.
 	appObj.RegisterControllerRoutes([]app.Controller{
+		calendarController,
+		branchController,
 		bankController,
 	})
 
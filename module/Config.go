@@ -3,10 +3,25 @@ package module
 import (
 	"banking-app-be/app"
 	"banking-app-be/model/account"
+	"banking-app-be/model/apikey"
+	"banking-app-be/model/approval"
+	"banking-app-be/model/audit"
 	"banking-app-be/model/bank"
 	banktransaction "banking-app-be/model/bankTransaction"
+	"banking-app-be/model/branch"
 	"banking-app-be/model/credential"
+	"banking-app-be/model/exposure"
+	"banking-app-be/model/holiday"
+	"banking-app-be/model/invitation"
+	"banking-app-be/model/login"
+	"banking-app-be/model/mfa"
 	"banking-app-be/model/passbook"
+	"banking-app-be/model/password"
+	"banking-app-be/model/payment"
+	"banking-app-be/model/pin"
+	"banking-app-be/model/reserve"
+	"banking-app-be/model/role"
+	"banking-app-be/model/session"
 	"banking-app-be/model/user"
 )
 
@@ -15,10 +30,25 @@ func Configure(appObj *app.App) {
 
 	userModule := user.NewUserModuleConfig(appObj.DB)
 	credentialModule := credential.NewCredentialModuleConfig(appObj.DB)
+	roleModule := role.NewRoleModuleConfig(appObj.DB)
+	sessionModule := session.NewSessionModuleConfig(appObj.DB)
+	invitationModule := invitation.NewInvitationModuleConfig(appObj.DB)
+	loginModule := login.NewLoginModuleConfig(appObj.DB)
+	passwordModule := password.NewPasswordModuleConfig(appObj.DB)
+	mfaModule := mfa.NewMFAModuleConfig(appObj.DB)
+	pinModule := pin.NewPINModuleConfig(appObj.DB)
 	bankModule := bank.NewBankModuleConfig(appObj.DB)
+	branchModule := branch.NewBranchModuleConfig(appObj.DB)
+	holidayModule := holiday.NewHolidayModuleConfig(appObj.DB)
+	reserveModule := reserve.NewReserveModuleConfig(appObj.DB)
 	banktransactionModule := banktransaction.NewBankTransactionModuleConfig(appObj.DB)
 	accountModule := account.NewAccountModuleConfig(appObj.DB)
 	passbookModule := passbook.NewPassbookModuleConfig(appObj.DB)
+	exposureModule := exposure.NewExposureModuleConfig(appObj.DB)
+	paymentModule := payment.NewPaymentModuleConfig(appObj.DB)
+	apiKeyModule := apikey.NewAPIKeyModuleConfig(appObj.DB)
+	approvalModule := approval.NewApprovalModuleConfig(appObj.DB)
+	auditModule := audit.NewAuditModuleConfig(appObj.DB)
 
-	appObj.MigrateModuleTables([]app.ModuleConfig{userModule, credentialModule, bankModule, banktransactionModule, accountModule, passbookModule})
+	appObj.MigrateModuleTables([]app.ModuleConfig{userModule, credentialModule, roleModule, sessionModule, invitationModule, loginModule, passwordModule, mfaModule, pinModule, bankModule, branchModule, holidayModule, reserveModule, banktransactionModule, accountModule, passbookModule, exposureModule, paymentModule, apiKeyModule, approvalModule, auditModule})
 }
@@ -0,0 +1,21 @@
+package module
+
+import (
+	"banking-app-be/app"
+	"banking-app-be/module/repository"
+
+	"banking-app-be/components/exposure/controller"
+	exposureService "banking-app-be/components/exposure/service"
+)
+
+func registerExposureRoutes(appObj *app.App, repository repository.Repository) {
+
+	defer appObj.WG.Done()
+	exposureService := exposureService.NewExposureService(appObj.DB, repository)
+
+	exposureController := controller.NewExposureController(exposureService, appObj.Log)
+
+	appObj.RegisterControllerRoutes([]app.Controller{
+		exposureController,
+	})
+}
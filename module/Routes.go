@@ -2,17 +2,33 @@ package module
 
 import (
 	"banking-app-be/app"
+	"banking-app-be/components/audit"
 	"banking-app-be/module/repository"
+
+	approvalService "banking-app-be/components/approval/service"
 )
 
 func RegisterModuleRoutes(app *app.App, repository repository.Repository) {
 	log := app.Log
 	log.Print("============Registering-Module-Routes==============")
 
-	app.WG.Add(5)
-	registerUserRoutes(app, repository)
-	registerBankRoutes(app, repository)
-	registerAccountRoutes(app, repository)
-	registerPassbookRoutes(app, repository)
+	// Every change the user, bank, account, passbook and approval services make is audited.
+	// Tables that are logs themselves are left out.
+	audited := audit.NewRepository(repository,
+		"login_history", "login_throttles", "mfa_challenges", "api_key_nonces", "api_key_usage")
+
+	// Modules register the sensitive actions they hold for a second admin's approval here.
+	approvals := approvalService.NewApprovalService(app.DB, audited)
+
+	app.WG.Add(9)
+	registerSecurityRoutes(app)
+	registerAuditRoutes(app, repository)
+	registerUserRoutes(app, audited, approvals)
+	// Registered ahead of the bank routes so /bank/{id} does not shadow them.
+	registerExposureRoutes(app, repository)
+	registerBankRoutes(app, audited, approvals)
+	registerAccountRoutes(app, audited)
+	registerPassbookRoutes(app, audited)
+	registerApprovalRoutes(app, approvals)
 	app.WG.Done()
 }
@@ -0,0 +1,21 @@
+package module
+
+import (
+	"banking-app-be/app"
+	"banking-app-be/components/security"
+	"banking-app-be/components/security/controller"
+)
+
+func registerSecurityRoutes(appObj *app.App) {
+
+	defer appObj.WG.Done()
+
+	// Signed requests from partner systems are verified before any route authenticates them.
+	appObj.RegisterMiddlewares(security.MiddlewareSignature)
+	appObj.RegisterRootControllerRoutes([]app.Controller{
+		controller.NewJWKSController(appObj.Log),
+	})
+	appObj.RegisterJobs([]app.Job{
+		security.NewKeyReloader(appObj.Log),
+	})
+}
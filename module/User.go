@@ -2,19 +2,56 @@ package module
 
 import (
 	"banking-app-be/app"
+	"banking-app-be/components/notification"
+	"banking-app-be/components/security"
 	"banking-app-be/components/user/controller"
 	userService "banking-app-be/components/user/service"
+	"banking-app-be/model/approval"
+	"banking-app-be/model/role"
 	"banking-app-be/module/repository"
+	"context"
+
+	approvalService "banking-app-be/components/approval/service"
 )
 
-func registerUserRoutes(appObj *app.App, repository repository.Repository) {
+func registerUserRoutes(appObj *app.App, repository repository.Repository, approvals *approvalService.ApprovalService) {
 
 	defer appObj.WG.Done()
-	userService := userService.NewUserService(appObj.DB, repository)
+	sessionService := userService.NewSessionService(appObj.DB, repository)
+	loginGuard := userService.NewLoginGuardService(appObj.DB, repository)
+	mfaService := userService.NewMFAService(appObj.DB, repository, sessionService, loginGuard, nil)
+	stepUpService := userService.NewStepUpService(appObj.DB, repository, mfaService, loginGuard, nil)
+	passwordService := userService.NewPasswordService(appObj.DB, repository, sessionService, loginGuard, notification.NewNotifier(appObj.Log))
+	serviceAccountService := userService.NewServiceAccountService(appObj.DB, repository)
+	noncePurger := userService.NewAPIKeyNoncePurger(serviceAccountService, appObj.Log)
+	userService := userService.NewUserService(appObj.DB, repository, sessionService, mfaService, loginGuard)
+
+	// Access tokens are only honoured while the session they were issued for is live, and
+	// the middleware decides from the user as stored rather than from the token's claims.
+	security.RegisterSessionChecker(sessionService)
+	security.RegisterPrincipalResolver(userService)
+	// High-value withdrawals and transfers are confirmed with a PIN or MFA code checked here.
+	security.RegisterStepUpVerifier(stepUpService)
+	// Service accounts call the API with keys, limited to the scopes and accounts of each key.
+	security.RegisterAPIKeyAuthenticator(serviceAccountService)
 
-	userController := controller.NewUserController(userService, appObj.Log)
+	// Admin rights follow roles, so granting them is held for a second super-admin.
+	approvals.RegisterAction(approval.ActionRoleAssign, role.UserRoleAssign, func(ctx context.Context, request *approval.Request) error {
+		assignment := approval.RoleAssignment{}
+		if err := request.DecodePayload(&assignment); err != nil {
+			return err
+		}
+		userRoles := []role.UserRole{}
+		return userService.AssignRoles(ctx, request.TargetID, assignment.Roles, request.ProposedBy, &userRoles)
+	})
+
+	userController := controller.NewUserController(userService, sessionService, mfaService, stepUpService, loginGuard, passwordService,
+		serviceAccountService, approvals, appObj.Log)
 
 	appObj.RegisterControllerRoutes([]app.Controller{
 		userController,
 	})
+	appObj.RegisterJobs([]app.Job{
+		noncePurger,
+	})
 }
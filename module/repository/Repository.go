@@ -2,11 +2,17 @@ package repository
 
 import (
 	"banking-app-be/components/errors"
+	"context"
+	stderrors "errors"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
+// mysqlDuplicateEntry is the MySQL error number of an insert violating a unique key.
+const mysqlDuplicateEntry = 1062
+
 type Repository interface {
 	Add(uow *UnitOfWork, out interface{}) error
 	GetAll(uow *UnitOfWork, out interface{}, queryProcessor ...QueryProcessor) error
@@ -24,19 +30,29 @@ func NewGormRepository() *GormRepository {
 	return &GormRepository{}
 }
 
+// UnitOfWork is a transaction, or a plain connection when readonly. Context carries what the work
+// is done for, such as the HTTP request, to the repositories.
 type UnitOfWork struct {
 	DB        *gorm.DB
 	Committed bool
 	Readonly  bool
+	Context   context.Context
 }
 
 func NewUnitOfWork(db *gorm.DB, readonly bool) *UnitOfWork {
+	return NewUnitOfWorkWithContext(context.Background(), db, readonly)
+}
+
+// NewUnitOfWorkWithContext is NewUnitOfWork for work done on behalf of ctx, typically the context
+// of the request being served.
+func NewUnitOfWorkWithContext(ctx context.Context, db *gorm.DB, readonly bool) *UnitOfWork {
 	commit := false
 	if readonly {
 		return &UnitOfWork{
 			DB:        db.New(),
 			Committed: commit,
 			Readonly:  readonly,
+			Context:   ctx,
 		}
 	}
 
@@ -44,6 +60,7 @@ func NewUnitOfWork(db *gorm.DB, readonly bool) *UnitOfWork {
 		DB:        db.New().Begin(),
 		Committed: commit,
 		Readonly:  readonly,
+		Context:   ctx,
 	}
 }
 
@@ -166,8 +183,24 @@ func Filter(condition string, args ...interface{}) QueryProcessor {
 	}
 }
 
-func DoesEmailExist(db *gorm.DB, email string, out interface{}, queryProcessors ...QueryProcessor) (bool, error) {
-	if email == "" {
+func OrderBy(order string) QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Order(order)
+		return db, nil
+	}
+}
+
+// ForUpdate locks the rows read until the unit of work commits or rolls back.
+func ForUpdate() QueryProcessor {
+	return func(db *gorm.DB, out interface{}) (*gorm.DB, error) {
+		db = db.Set("gorm:query_option", "FOR UPDATE")
+		return db, nil
+	}
+}
+
+// DoesEmailExist looks an email up by its blind index, as emails are stored encrypted.
+func DoesEmailExist(db *gorm.DB, emailIndex string, out interface{}, queryProcessors ...QueryProcessor) (bool, error) {
+	if emailIndex == "" {
 		return false, errors.NewNotFoundError("email not present")
 	}
 	count := 0
@@ -177,7 +210,7 @@ func DoesEmailExist(db *gorm.DB, email string, out interface{}, queryProcessors
 	if err != nil {
 		return false, err
 	}
-	if err := db.Debug().Model(out).Where("email = ?", email).Count(&count).Error; err != nil {
+	if err := db.Debug().Model(out).Where("email_index = ?", emailIndex).Count(&count).Error; err != nil {
 		return false, err
 	}
 	if count > 0 {
@@ -214,3 +247,9 @@ func (repository *GormRepository) Update(uow *UnitOfWork, out interface{}, query
 	}
 	return db.Model(out).Update(out).Error
 }
+
+// IsDuplicateKey reports whether err is MySQL refusing a row because its unique key is taken.
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return stderrors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}